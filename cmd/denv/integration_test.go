@@ -46,11 +46,11 @@ func TestFullWorkflow(t *testing.T) {
 	assert.Equal(t, "test-env", runtime.Environment)
 
 	// Test: List environments
-	err = commands.List()
+	err = commands.List(false)
 	assert.NoError(t, err)
 
 	// Test: Remove environment (should succeed in test mode as no real session exists)
-	err = commands.Rm("test-env", false)
+	err = commands.Rm("test-env", false, true)
 	assert.NoError(t, err)
 	assert.NoDirExists(t, envPath)
 }
@@ -4,12 +4,18 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/caoer/denv/internal/commands"
 )
 
+// version is set at build time via -ldflags
+var version = "dev"
+
 func main() {
+	commands.Version = version
+
 	if len(os.Args) < 2 {
 		printUsage()
 		os.Exit(0)
@@ -43,6 +49,7 @@ func main() {
 		// Parse flags for rm command
 		fs := flag.NewFlagSet("rm", flag.ExitOnError)
 		all := fs.Bool("all", false, "Remove all inactive environments")
+		yes := fs.Bool("yes", false, "Do not ask for confirmation")
 		_ = fs.Parse(os.Args[2:])
 
 		envName := ""
@@ -54,11 +61,236 @@ func main() {
 			envName = fs.Arg(0)
 		}
 		
-		if err := commands.Rm(envName, *all); err != nil {
+		if err := commands.Rm(envName, *all, *yes); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "restore-trash":
+		if len(os.Args) < 3 {
+			fmt.Fprintf(os.Stderr, "Error: environment name required\n")
+			os.Exit(1)
+		}
+		if err := commands.RestoreTrash(os.Args[2]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "clone":
+		// Parse flags for clone command
+		fs := flag.NewFlagSet("clone", flag.ExitOnError)
+		force := fs.Bool("force", false, "Clone even if the source has active sessions")
+		_ = fs.Parse(os.Args[2:])
+
+		if fs.NArg() < 2 {
+			fmt.Fprintf(os.Stderr, "Error: source and destination environment names required\n")
+			os.Exit(1)
+		}
+
+		if err := commands.Clone(fs.Arg(0), fs.Arg(1), *force); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "snapshot":
+		if len(os.Args) < 3 {
+			fmt.Fprintf(os.Stderr, "Error: environment name required (or use 'snapshot ls')\n")
+			os.Exit(1)
+		}
+		var err error
+		if os.Args[2] == "ls" || os.Args[2] == "list" {
+			err = commands.SnapshotList(os.Stdout)
+		} else {
+			name := ""
+			if len(os.Args) > 3 {
+				name = os.Args[3]
+			}
+			err = commands.Snapshot(os.Args[2], name)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "restore":
+		if len(os.Args) < 4 {
+			fmt.Fprintf(os.Stderr, "Error: environment and snapshot names required\n")
+			os.Exit(1)
+		}
+		if err := commands.Restore(os.Args[2], os.Args[3]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "mv":
+		if len(os.Args) < 4 {
+			fmt.Fprintf(os.Stderr, "Error: old and new environment names required\n")
+			os.Exit(1)
+		}
+		if err := commands.Mv(os.Args[2], os.Args[3]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "gc":
+		// Parse flags for gc command
+		fs := flag.NewFlagSet("gc", flag.ExitOnError)
+		olderThan := fs.String("older-than", "14d", "Remove environments not entered for this long (e.g. 14d, 2w, 36h)")
+		projectFilter := fs.String("project", "", "Only collect environments of this project")
+		dryRun := fs.Bool("dry-run", false, "Show what would be removed without removing anything")
+		_ = fs.Parse(os.Args[2:])
+
+		age, err := commands.ParseAge(*olderThan)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := commands.GC(age, *projectFilter, *dryRun, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "up", "down":
+		fs := flag.NewFlagSet(command, flag.ExitOnError)
+		envName := fs.String("env", "", "Environment to manage (default: current or 'default')")
+		_ = fs.Parse(os.Args[2:])
+
+		run := commands.Up
+		if command == "down" {
+			run = commands.Down
+		}
+		if err := run(*envName, fs.Args(), os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "wait":
+		fs := flag.NewFlagSet("wait", flag.ExitOnError)
+		envName := fs.String("env", "", "Environment to resolve ports in (default: current or 'default')")
+		timeout := fs.Duration("timeout", commands.DefaultWaitTimeout, "Give up after this long")
+		httpPath := fs.String("http", "", "Wait for an HTTP path to answer instead of a TCP connection")
+
+		// Flags may come before or after the targets
+		var targets []string
+		args := os.Args[2:]
+		for {
+			_ = fs.Parse(args)
+			if fs.NArg() == 0 {
+				break
+			}
+			targets = append(targets, fs.Arg(0))
+			args = fs.Args()[1:]
+		}
+		if err := commands.Wait(*envName, targets, *timeout, *httpPath, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "logs":
+		fs := flag.NewFlagSet("logs", flag.ExitOnError)
+		envName := fs.String("env", "", "Environment of the service (default: current or 'default')")
+		follow := fs.Bool("follow", false, "Keep printing new log output")
+		fs.BoolVar(follow, "f", false, "Shorthand for --follow")
+		_ = fs.Parse(os.Args[2:])
+
+		if err := commands.Logs(*envName, fs.Arg(0), *follow, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "run":
+		fs := flag.NewFlagSet("run", flag.ExitOnError)
+		envName := fs.String("env", "", "Environment to run in (default: current or 'default')")
+		basePort := fs.Int("port", commands.DefaultProcfileBasePort, "Original port of the first process; each next one adds 100")
+		_ = fs.Parse(os.Args[2:])
+
+		if err := commands.RunProcfile(fs.Arg(0), *envName, *basePort, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "compose":
+		fs := flag.NewFlagSet("compose", flag.ExitOnError)
+		envName := fs.String("env", "", "Environment to run the stack in (default: current or 'default')")
+		_ = fs.Parse(os.Args[2:])
+
+		code, err := commands.Compose(*envName, fs.Args())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		os.Exit(code)
+
+	case "proxy":
+		var err error
+		if len(os.Args) > 2 && os.Args[2] == "switch" {
+			envName := ""
+			if len(os.Args) > 3 {
+				envName = os.Args[3]
+			}
+			err = commands.ProxySwitch(envName, os.Stdout)
+		} else {
+			fs := flag.NewFlagSet("proxy", flag.ExitOnError)
+			useTLS := fs.Bool("tls", false, "Terminate HTTPS with a certificate from the local CA")
+			_ = fs.Parse(os.Args[2:])
+			err = commands.Proxy(fs.Arg(0), *useTLS, os.Stdout)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "router":
+		fs := flag.NewFlagSet("router", flag.ExitOnError)
+		portList := fs.String("ports", "", "Comma-separated original ports to route (default: all)")
+		useTLS := fs.Bool("tls", false, "Serve HTTPS with certificates from the local CA")
+		_ = fs.Parse(os.Args[2:])
+
+		var routePorts []int
+		for _, p := range strings.Split(*portList, ",") {
+			if p = strings.TrimSpace(p); p == "" {
+				continue
+			}
+			port, err := strconv.Atoi(p)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid port: %s\n", p)
+				os.Exit(1)
+			}
+			routePorts = append(routePorts, port)
+		}
+		if err := commands.Router(routePorts, *useTLS, os.Stdout); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
 
+	case "ca":
+		if len(os.Args) < 3 || os.Args[2] != "export" {
+			fmt.Fprintf(os.Stderr, "Usage: denv ca export\n")
+			os.Exit(1)
+		}
+		if err := commands.CAExport(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	// Runs a service in the background for denv up
+	case "supervise":
+		if len(os.Args) < 3 {
+			fmt.Fprintf(os.Stderr, "Error: service spec required\n")
+			os.Exit(1)
+		}
+		if err := commands.Supervise(os.Args[2]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	// Runs the entered shell inside its network namespace
+	case "netns-exec":
+		code, err := commands.NetnsExec(os.Args[2:])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		os.Exit(code)
+
 	case "ps":
 		envName := ""
 		if len(os.Args) > 2 {
@@ -69,6 +301,15 @@ func main() {
 			os.Exit(1)
 		}
 
+	case "ports":
+		fs := flag.NewFlagSet("ports", flag.ExitOnError)
+		all := fs.Bool("all", false, "Inspect every environment and report conflicts")
+		_ = fs.Parse(os.Args[2:])
+		if err := commands.Ports(fs.Arg(0), *all, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
 	case "sessions":
 		// Parse flags
 		fs := flag.NewFlagSet("sessions", flag.ExitOnError)
@@ -82,11 +323,24 @@ func main() {
 		}
 
 	case "export":
-		envName := ""
-		if len(os.Args) > 2 {
-			envName = os.Args[2]
+		fs := flag.NewFlagSet("export", flag.ExitOnError)
+		var opts commands.ExportOptions
+		fs.StringVar(&opts.Format, "format", "sh", "Output format: "+strings.Join(commands.ExportFormats, ", ")+", or a shell (fish, pwsh, nu, ...)")
+		fs.BoolVar(&opts.NoCore, "no-core", false, "Leave out the DENV_* variables")
+		fs.BoolVar(&opts.NoPorts, "no-ports", false, "Leave out the PORT_* mappings")
+		_ = fs.Parse(os.Args[2:])
+
+		if err := commands.Export(fs.Arg(0), opts, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
 		}
-		if err := commands.Export(envName, os.Stdout); err != nil {
+
+	case "prompt":
+		fs := flag.NewFlagSet("prompt", flag.ExitOnError)
+		format := fs.String("format", commands.DefaultPromptFormat, "Go template with .Project, .Env, .Sessions, .Ports, .Color and .Colored")
+		_ = fs.Parse(os.Args[2:])
+
+		if err := commands.Prompt(*format, os.Stdout); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
@@ -102,6 +356,20 @@ func main() {
 			fmt.Println("Updates the config file with new default patterns while preserving project overrides")
 		}
 
+	case "allow", "deny":
+		file := ""
+		if len(os.Args) > 2 {
+			file = os.Args[2]
+		}
+		run := commands.Allow
+		if command == "deny" {
+			run = commands.Deny
+		}
+		if err := run(file); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
 	case "project":
 		action := ""
 		if len(os.Args) > 2 {
@@ -114,6 +382,77 @@ func main() {
 		}
 
 	// Commands for bash wrapper integration
+	case "hook":
+		if len(os.Args) < 3 {
+			fmt.Fprintf(os.Stderr, "Usage: denv hook bash|zsh|fish\n")
+			os.Exit(1)
+		}
+		if err := commands.Hook(os.Args[2], os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	// Called by the shell hook before every prompt
+	case "direnvrc":
+		if err := commands.Direnvrc(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "hook-env":
+		if len(os.Args) < 3 {
+			fmt.Fprintf(os.Stderr, "Error: shell name required\n")
+			os.Exit(1)
+		}
+		pid := os.Getppid()
+		if len(os.Args) > 3 {
+			if p, err := strconv.Atoi(os.Args[3]); err == nil {
+				pid = p
+			}
+		}
+		if err := commands.HookEnv(os.Args[2], pid, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "hook-exit":
+		pid := os.Getppid()
+		if len(os.Args) > 2 {
+			if p, err := strconv.Atoi(os.Args[2]); err == nil {
+				pid = p
+			}
+		}
+		commands.HookExit(pid)
+
+	// Print commands for the shell to evaluate; the shell hook wraps these
+	case "switch", "push", "pop":
+		fs := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+		shellName := fs.String("shell", "", "Shell to print commands for (default: from $SHELL)")
+		pid := fs.Int("pid", os.Getppid(), "PID of the shell")
+		_ = fs.Parse(os.Args[2:])
+
+		var err error
+		switch os.Args[1] {
+		case "switch":
+			if fs.NArg() < 1 {
+				fmt.Fprintf(os.Stderr, "Usage: denv switch <env>\n")
+				os.Exit(1)
+			}
+			err = commands.Switch(*shellName, fs.Arg(0), *pid, os.Stdout)
+		case "push":
+			if fs.NArg() < 1 {
+				fmt.Fprintf(os.Stderr, "Usage: denv push <env>\n")
+				os.Exit(1)
+			}
+			err = commands.Push(*shellName, fs.Arg(0), *pid, os.Stdout)
+		case "pop":
+			err = commands.Pop(*shellName, *pid, os.Stdout)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
 	case "prepare-env":
 		envName := ""
 		if len(os.Args) > 2 {
@@ -161,16 +500,43 @@ Usage:
   denv enter [name]      Enter environment (default: "default")
   denv ls [--plain]      List all environments (--plain for pipe-friendly output)
   denv ps                Show current environment status
-  denv rm <name>         Remove environment
-  denv rm --all          Remove all inactive environments
+  denv ports [name]      Show what listens on an environment's ports (--all for every environment)
+  denv up [service...]   Start the environment's services (--env name)
+  denv down [service...] Stop the environment's services (--env name)
+  denv logs [service]    Show service logs (-f to follow, --env name)
+  denv wait <port|VAR>... Wait until ports accept connections (--timeout, --http path)
+  denv run [Procfile]    Run every Procfile process with its own PORT (--env name)
+  denv compose [args...] Run docker compose with the environment's ports (--env name first)
+  denv proxy [--tls] [name] Serve an environment on its original ports
+  denv proxy switch <name> Point the running proxy at another environment
+  denv router [--ports] [--tls] Route http://<env>.<project>.localhost:<port> to environments
+  denv ca export         Print the local CA root certificate
+  denv rm <name>         Move environment to trash (--yes skips confirmation)
+  denv rm --all          Move all inactive environments to trash
+  denv restore-trash <name> Restore a removed environment from trash
+  denv mv <old> <new>    Rename an environment, keeping its state
+  denv gc [--older-than 14d] [--project p] [--dry-run]
+                         Remove environments not used recently
+  denv clone <src> <dst> Copy an environment with fresh ports (--force if active)
+  denv snapshot <name> [snapshot] Archive an environment's state
+  denv snapshot ls       List snapshots for the current project
+  denv restore <name> <snapshot> Restore an environment from a snapshot
   denv sessions          Show active sessions
   denv sessions --cleanup Clean orphaned sessions
   denv sessions --kill   Terminate all sessions
-  denv export [name]     Export environment variables (for direnv)
+  denv export [name]     Export environment variables (--format dotenv|json|systemd|docker|<shell>)
+  denv direnvrc          Print the direnv library providing 'use denv [name]'
+  denv prompt [--format tmpl] Print a prompt segment for starship, p10k, ...
+  denv hook <shell>      Print the hook activating environments on cd (bash, zsh, fish)
+  denv switch <env>      Change the current shell's environment (needs the hook)
+  denv push <env>        Switch, keeping the current environment to pop back to
+  denv pop               Return to the environment before the last push
   denv project           Show current project name
   denv project rename <name> Rename current project
   denv project unset     Remove project override
   denv config update     Update config with new default patterns
+  denv allow [path]      Trust the repository's .denv.yaml (patterns and hooks)
+  denv deny [path]       Revoke trust in the repository's .denv.yaml
   denv help             Show this help
 
 Environment Variables:
@@ -0,0 +1,249 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caoer/denv/internal/router"
+)
+
+const (
+	caCertFile = "ca.pem"
+	caKeyFile  = "ca-key.pem"
+
+	caValidity   = 10 * 365 * 24 * time.Hour
+	leafValidity = 397 * 24 * time.Hour
+)
+
+// CA is the local certificate authority denv issues certificates from
+type CA struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+
+	mu    sync.Mutex
+	cache map[string]*tls.Certificate
+}
+
+// LoadOrCreateCA loads the CA stored in dir, generating it on first use
+func LoadOrCreateCA(dir string) (*CA, error) {
+	certPath := filepath.Join(dir, caCertFile)
+	keyPath := filepath.Join(dir, caKeyFile)
+
+	certPEM, certErr := os.ReadFile(certPath)
+	keyPEM, keyErr := os.ReadFile(keyPath)
+	if os.IsNotExist(certErr) && os.IsNotExist(keyErr) {
+		return createCA(dir)
+	}
+	if certErr != nil {
+		return nil, certErr
+	}
+	if keyErr != nil {
+		return nil, keyErr
+	}
+	return parseCA(certPEM, keyPEM)
+}
+
+func createCA(dir string) (*CA, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	hostname, _ := os.Hostname()
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: randomSerial(),
+		Subject: pkix.Name{
+			Organization: []string{"denv"},
+			CommonName:   fmt.Sprintf("denv local CA (%s)", hostname),
+		},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+		// Its key sits in DENV_HOME, so the CA may only vouch for local
+		// names: localhost, routed hosts and loopback addresses
+		PermittedDNSDomainsCritical: true,
+		PermittedDNSDomains:         permittedDomains(),
+		PermittedIPRanges:           loopbackRanges(),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, caKeyFile), keyPEM, 0600); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, caCertFile), certPEM, 0644); err != nil {
+		return nil, err
+	}
+	return parseCA(certPEM, keyPEM)
+}
+
+// permittedDomains returns the domains the CA may issue certificates for
+func permittedDomains() []string {
+	domains := []string{"localhost"}
+	if suffix := strings.TrimPrefix(router.Suffix, "."); suffix != "localhost" {
+		domains = append(domains, suffix)
+	}
+	return domains
+}
+
+// loopbackRanges returns the addresses the CA may issue certificates for
+func loopbackRanges() []*net.IPNet {
+	var ranges []*net.IPNet
+	for _, cidr := range []string{"127.0.0.0/8", "::1/128"} {
+		_, ipNet, _ := net.ParseCIDR(cidr)
+		ranges = append(ranges, ipNet)
+	}
+	return ranges
+}
+
+func parseCA(certPEM, keyPEM []byte) (*CA, error) {
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil {
+		return nil, errors.New("invalid CA certificate")
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		return nil, errors.New("invalid CA key")
+	}
+	key, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CA{cert: cert, key: key, certPEM: certPEM, cache: make(map[string]*tls.Certificate)}, nil
+}
+
+// CertPEM returns the PEM-encoded root certificate
+func (ca *CA) CertPEM() []byte {
+	return ca.certPEM
+}
+
+// Certificate returns the root certificate
+func (ca *CA) Certificate() *x509.Certificate {
+	return ca.cert
+}
+
+// Issue returns a certificate signed by the CA for the given DNS names and
+// IP addresses
+func (ca *CA) Issue(hosts ...string) (*tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: randomSerial(),
+		Subject:      pkix.Name{Organization: []string{"denv"}, CommonName: hosts[0]},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(leafValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, h)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Certificate{
+		Certificate: [][]byte{der, ca.cert.Raw},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}, nil
+}
+
+// HostsFor returns the names a certificate for server name should cover:
+// routed hosts like feature.myapp.localhost get a wildcard for the whole
+// project (*.myapp.localhost); anything else is treated as plain localhost
+func HostsFor(serverName string) []string {
+	name := strings.TrimSuffix(strings.ToLower(serverName), ".")
+	labels := strings.Split(name, ".")
+	if len(labels) >= 3 && labels[len(labels)-1] == "localhost" {
+		parent := strings.Join(labels[1:], ".")
+		return []string{"*." + parent, parent}
+	}
+	return []string{"localhost", "127.0.0.1", "::1"}
+}
+
+// GetCertificate issues (and caches) a certificate for the client's server
+// name; use it as tls.Config.GetCertificate
+func (ca *CA) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	hosts := HostsFor(hello.ServerName)
+	key := hosts[0]
+
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+	if cert, ok := ca.cache[key]; ok && time.Now().Before(cert.Leaf.NotAfter) {
+		return cert, nil
+	}
+	cert, err := ca.Issue(hosts...)
+	if err != nil {
+		return nil, err
+	}
+	ca.cache[key] = cert
+	return cert, nil
+}
+
+// TLSConfig returns a server configuration issuing certificates on demand
+func (ca *CA) TLSConfig() *tls.Config {
+	return &tls.Config{
+		GetCertificate: ca.GetCertificate,
+		MinVersion:     tls.VersionTLS12,
+		NextProtos:     []string{"http/1.1"},
+	}
+}
+
+func randomSerial() *big.Int {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return big.NewInt(time.Now().UnixNano())
+	}
+	return serial
+}
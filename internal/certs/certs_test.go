@@ -0,0 +1,93 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadOrCreateCA(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "ca")
+
+	ca, err := LoadOrCreateCA(dir)
+	require.NoError(t, err)
+	assert.True(t, ca.Certificate().IsCA)
+
+	info, err := os.Stat(filepath.Join(dir, "ca-key.pem"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// Test: The same CA is loaded again
+	again, err := LoadOrCreateCA(dir)
+	require.NoError(t, err)
+	assert.Equal(t, ca.CertPEM(), again.CertPEM())
+}
+
+func TestHostsFor(t *testing.T) {
+	assert.Equal(t, []string{"*.myapp.localhost", "myapp.localhost"}, HostsFor("feature.myapp.localhost"))
+	assert.Equal(t, []string{"*.myapp.localhost", "myapp.localhost"}, HostsFor("Main.MyApp.localhost."))
+	assert.Equal(t, []string{"localhost", "127.0.0.1", "::1"}, HostsFor(""))
+	assert.Equal(t, []string{"localhost", "127.0.0.1", "::1"}, HostsFor("localhost"))
+}
+
+func TestIssuedCertificatesVerify(t *testing.T) {
+	ca, err := LoadOrCreateCA(t.TempDir())
+	require.NoError(t, err)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Certificate())
+
+	cases := []struct {
+		serverName string
+		verifyName string
+	}{
+		{"feature.myapp.localhost", "feature.myapp.localhost"},
+		{"feature.myapp.localhost", "main.myapp.localhost"},
+		{"", "localhost"},
+		{"", "127.0.0.1"},
+	}
+	for _, c := range cases {
+		cert, err := ca.GetCertificate(&tls.ClientHelloInfo{ServerName: c.serverName})
+		require.NoError(t, err)
+		_, err = cert.Leaf.Verify(x509.VerifyOptions{DNSName: c.verifyName, Roots: roots})
+		assert.NoError(t, err, c.verifyName)
+	}
+
+	// Test: A project's certificate does not cover other projects
+	cert, err := ca.GetCertificate(&tls.ClientHelloInfo{ServerName: "feature.myapp.localhost"})
+	require.NoError(t, err)
+	_, err = cert.Leaf.Verify(x509.VerifyOptions{DNSName: "feature.other.localhost", Roots: roots})
+	assert.Error(t, err)
+
+	// Test: Certificates are cached per project
+	again, err := ca.GetCertificate(&tls.ClientHelloInfo{ServerName: "main.myapp.localhost"})
+	require.NoError(t, err)
+	assert.Same(t, cert, again)
+}
+
+func TestCAIsNameConstrained(t *testing.T) {
+	ca, err := LoadOrCreateCA(t.TempDir())
+	require.NoError(t, err)
+	assert.True(t, ca.Certificate().PermittedDNSDomainsCritical)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Certificate())
+
+	// Test: Certificates for names outside localhost and loopback don't verify
+	for _, host := range []string{"example.com", "10.0.0.1"} {
+		cert, err := ca.Issue(host)
+		require.NoError(t, err)
+		_, err = cert.Leaf.Verify(x509.VerifyOptions{DNSName: host, Roots: roots})
+		assert.ErrorContains(t, err, "not authorized", host)
+	}
+
+	cert, err := ca.Issue("::1")
+	require.NoError(t, err)
+	_, err = cert.Leaf.Verify(x509.VerifyOptions{DNSName: "::1", Roots: roots})
+	assert.NoError(t, err)
+}
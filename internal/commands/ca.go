@@ -0,0 +1,31 @@
+package commands
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+
+	"github.com/caoer/denv/internal/certs"
+	"github.com/caoer/denv/internal/paths"
+)
+
+// CAExport writes the root certificate of the local CA, creating the CA on
+// first use, so it can be installed into a trust store
+func CAExport(w io.Writer) error {
+	ca, err := certs.LoadOrCreateCA(paths.CAPath())
+	if err != nil {
+		return fmt.Errorf("failed to load CA: %w", err)
+	}
+	_, err = w.Write(ca.CertPEM())
+	return err
+}
+
+// serverTLSConfig returns a TLS configuration issuing certificates from the
+// local CA
+func serverTLSConfig() (*tls.Config, error) {
+	ca, err := certs.LoadOrCreateCA(paths.CAPath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load CA: %w", err)
+	}
+	return ca.TLSConfig(), nil
+}
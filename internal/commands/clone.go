@@ -0,0 +1,201 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/caoer/denv/internal/config"
+	"github.com/caoer/denv/internal/environment"
+	"github.com/caoer/denv/internal/hooks"
+	"github.com/caoer/denv/internal/override"
+	"github.com/caoer/denv/internal/paths"
+	"github.com/caoer/denv/internal/ports"
+	"github.com/caoer/denv/internal/project"
+)
+
+// Clone copies an environment, including its isolated data, into a new
+// environment with freshly allocated ports
+func Clone(srcName, dstName string, force bool) error {
+	if srcName == "" || dstName == "" {
+		return fmt.Errorf("source and destination environment names required")
+	}
+	if srcName == dstName {
+		return fmt.Errorf("source and destination must be different")
+	}
+	if err := checkEnvironmentName(dstName); err != nil {
+		return err
+	}
+
+	// Detect project (respecting config overrides like Enter does)
+	cwd, _ := os.Getwd()
+	cfg, _ := config.LoadConfig(filepath.Join(paths.DenvHome(), "config.yaml"))
+	projectName := project.RegisterProjectWithConfig(cwd, cfg)
+
+	srcPath := paths.EnvironmentPath(projectName, srcName)
+	dstPath := paths.EnvironmentPath(projectName, dstName)
+
+	if _, err := os.Stat(srcPath); os.IsNotExist(err) {
+		return fmt.Errorf("environment '%s' does not exist", srcName)
+	}
+	if _, err := os.Stat(dstPath); err == nil {
+		return fmt.Errorf("environment '%s' already exists", dstName)
+	}
+
+	srcRuntime, err := environment.LoadRuntime(srcPath)
+	if err != nil {
+		return fmt.Errorf("failed to load environment '%s': %w", srcName, err)
+	}
+	if srcRuntime == nil {
+		srcRuntime = environment.NewRuntime(projectName, srcName)
+	}
+
+	// Copying files that are being written to gives an inconsistent clone
+	if active := countActiveSessions(srcRuntime); active > 0 && !force {
+		return fmt.Errorf("cannot clone environment with %d active session(s) (use --force to clone anyway)", active)
+	}
+
+	// Copy everything except session locks, port reservations and service
+	// state, which belong to the source environment
+	err = copyDir(srcPath, dstPath, func(rel string) bool {
+		return rel == "sessions" || rel == "ports.json" || rel == "services" || rel == "logs"
+	})
+	if err != nil {
+		_ = os.RemoveAll(dstPath)
+		return fmt.Errorf("failed to copy environment: %w", err)
+	}
+
+	// Re-allocate every port, keeping clear of the other environments
+	pm := ports.NewPortManager(dstPath)
+	for mapped := range getAllProjectEnvironmentPorts(projectName, dstName) {
+		pm.Reserve(mapped)
+	}
+	newPorts := make(map[int]int)
+	for orig := range srcRuntime.Ports {
+		newPorts[orig] = pm.GetPort(orig)
+	}
+
+	runtime := environment.NewRuntime(projectName, dstName)
+	runtime.Ports = newPorts
+	runtime.Overrides = relocateOverrides(srcRuntime.Overrides, srcPath, dstPath, newPorts)
+
+	if err := environment.SaveRuntime(dstPath, runtime); err != nil {
+		_ = os.RemoveAll(dstPath)
+		return fmt.Errorf("failed to save runtime: %w", err)
+	}
+
+	runHooks(hooks.OnCreate, runtime, dstPath, "", os.Stdout)
+
+	fmt.Printf("Cloned environment '%s' to '%s' for project %s\n", srcName, dstName, projectName)
+	printPortChanges(os.Stdout, srcRuntime.Ports, newPorts)
+	return nil
+}
+
+// relocateOverrides rewrites overrides computed for oldEnvPath so they point
+// at newEnvPath and use the given port mappings
+func relocateOverrides(overrides map[string]environment.Override, oldEnvPath, newEnvPath string, portMap map[int]int) map[string]environment.Override {
+	result := make(map[string]environment.Override)
+
+	for key, o := range overrides {
+		switch o.Rule {
+		case "random_port":
+			if orig, err := strconv.Atoi(o.Original); err == nil {
+				if mapped, ok := portMap[orig]; ok {
+					o.Current = strconv.Itoa(mapped)
+				}
+			}
+		case "rewrite_ports":
+			o.Current = override.RewriteURL(o.Original, portMap)
+		default:
+			o.Current = relocatePath(o.Current, oldEnvPath, newEnvPath)
+		}
+		result[key] = o
+	}
+
+	return result
+}
+
+// relocatePath replaces the oldBase prefix of path with newBase
+func relocatePath(path, oldBase, newBase string) string {
+	if path == oldBase {
+		return newBase
+	}
+	if strings.HasPrefix(path, oldBase+string(filepath.Separator)) {
+		return newBase + path[len(oldBase):]
+	}
+	return path
+}
+
+// copyDir recursively copies src to dst, preserving file modes and symlinks.
+// skip is called with the slash-separated path relative to src.
+func copyDir(src, dst string, skip func(rel string) bool) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if rel != "." && skip != nil && skip(filepath.ToSlash(rel)) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		target := filepath.Join(dst, rel)
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		default:
+			// Sockets, pipes and devices can't be meaningfully copied
+			return nil
+		}
+	})
+}
+
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// printPortChanges prints old → new mapped ports, sorted by original port
+func printPortChanges(w io.Writer, oldPorts, newPorts map[int]int) {
+	var origs []int
+	for orig := range newPorts {
+		origs = append(origs, orig)
+	}
+	sort.Ints(origs)
+
+	for _, orig := range origs {
+		fmt.Fprintf(w, "  %d: %d → %d\n", orig, oldPorts[orig], newPorts[orig])
+	}
+}
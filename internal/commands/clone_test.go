@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/caoer/denv/internal/environment"
+	"github.com/caoer/denv/internal/testutil"
+)
+
+func TestClone_CopiesStateWithFreshPorts(t *testing.T) {
+	tmpDir, _ := testutil.SetupProject(t, "clonetest")
+
+	os.Setenv("API_PORT", "3000")
+	os.Setenv("DATABASE_URL", "postgres://localhost:5432/db")
+	os.Setenv("CACHE_DIR", "/tmp/cache")
+	defer os.Unsetenv("API_PORT")
+	defer os.Unsetenv("DATABASE_URL")
+	defer os.Unsetenv("CACHE_DIR")
+
+	require.NoError(t, Enter("default"))
+
+	srcPath := filepath.Join(tmpDir, "clonetest-default")
+	require.NoError(t, os.MkdirAll(filepath.Join(srcPath, "cache"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(srcPath, "cache", "data.db"), []byte("state"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(srcPath, "sessions"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(srcPath, "sessions", "old.lock"), nil, 0644))
+
+	// Test: Clone into a new environment
+	err := Clone("default", "feature", false)
+	require.NoError(t, err)
+
+	dstPath := filepath.Join(tmpDir, "clonetest-feature")
+	data, err := os.ReadFile(filepath.Join(dstPath, "cache", "data.db"))
+	require.NoError(t, err)
+	assert.Equal(t, "state", string(data))
+	assert.NoDirExists(t, filepath.Join(dstPath, "sessions"))
+
+	src, err := environment.LoadRuntime(srcPath)
+	require.NoError(t, err)
+	dst, err := environment.LoadRuntime(dstPath)
+	require.NoError(t, err)
+
+	assert.Equal(t, "feature", dst.Environment)
+	assert.Empty(t, dst.Sessions)
+
+	// Every original port is re-allocated to a different mapped port
+	require.Len(t, dst.Ports, len(src.Ports))
+	for orig, mapped := range src.Ports {
+		assert.NotZero(t, dst.Ports[orig])
+		assert.NotEqual(t, mapped, dst.Ports[orig])
+	}
+
+	// Overrides follow the new environment
+	assert.Equal(t, strconv.Itoa(dst.Ports[3000]), dst.Overrides["API_PORT"].Current)
+	assert.Contains(t, dst.Overrides["DATABASE_URL"].Current, ":"+strconv.Itoa(dst.Ports[5432])+"/")
+	assert.Equal(t, filepath.Join(dstPath, "cache"), dst.Overrides["CACHE_DIR"].Current)
+}
+
+func TestClone_RefusesActiveSource(t *testing.T) {
+	tmpDir, _ := testutil.SetupProject(t, "clonetest")
+
+	require.NoError(t, Enter("default"))
+
+	srcPath := filepath.Join(tmpDir, "clonetest-default")
+	runtime, err := environment.LoadRuntime(srcPath)
+	require.NoError(t, err)
+	runtime.Sessions["active-session"] = environment.Session{
+		ID:  "active-session",
+		PID: os.Getpid(),
+	}
+	require.NoError(t, environment.SaveRuntime(srcPath, runtime))
+
+	// Test: Should refuse without --force
+	err = Clone("default", "feature", false)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "active session")
+	assert.NoDirExists(t, filepath.Join(tmpDir, "clonetest-feature"))
+
+	// Test: --force clones anyway
+	err = Clone("default", "feature", true)
+	assert.NoError(t, err)
+	assert.DirExists(t, filepath.Join(tmpDir, "clonetest-feature"))
+}
+
+func TestClone_Errors(t *testing.T) {
+	testutil.SetupProject(t, "clonetest")
+
+	err := Clone("missing", "feature", false)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "does not exist")
+
+	require.NoError(t, Enter("default"))
+	require.NoError(t, Enter("feature"))
+
+	err = Clone("default", "feature", false)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "already exists")
+}
+
+func TestRelocatePath(t *testing.T) {
+	assert.Equal(t, "/h/p-new/cache", relocatePath("/h/p-old/cache", "/h/p-old", "/h/p-new"))
+	assert.Equal(t, "/h/p-new", relocatePath("/h/p-old", "/h/p-old", "/h/p-new"))
+	assert.Equal(t, "/h/p-older/cache", relocatePath("/h/p-older/cache", "/h/p-old", "/h/p-new"))
+	assert.Equal(t, "/elsewhere", relocatePath("/elsewhere", "/h/p-old", "/h/p-new"))
+}
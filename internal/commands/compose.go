@@ -0,0 +1,136 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/caoer/denv/internal/compose"
+	"github.com/caoer/denv/internal/environment"
+	"github.com/caoer/denv/internal/ports"
+)
+
+// composeOverrideName is the override file written to the environment
+const composeOverrideName = "compose.override.yml"
+
+// Compose runs docker compose with args for an environment: the compose
+// project is named <project>-<env> and published host ports are remapped
+// through an override file, so stacks of several environments don't
+// collide. It returns docker's exit code.
+func Compose(envName string, args []string) (int, error) {
+	cfg, runtime, envPath, err := loadTargetEnvironment(envName)
+	if err != nil {
+		return 1, err
+	}
+
+	cwd, _ := os.Getwd()
+	composePath := compose.Find(cwd)
+	if composePath == "" {
+		return 1, fmt.Errorf("no compose file found (%s)", strings.Join(compose.FileNames, ", "))
+	}
+	overridePath, err := writeComposeOverride(composePath, runtime, envPath)
+	if err != nil {
+		return 1, err
+	}
+
+	env, _ := environmentVariables(cfg, runtime, envPath, "")
+	var environ []string
+	for k, v := range env {
+		environ = append(environ, k+"="+v)
+	}
+	environ = append(environ, "COMPOSE_PROJECT_NAME="+composeProjectName(runtime))
+
+	cmd := exec.Command("docker", append([]string{"compose", "-f", composePath, "-f", overridePath}, args...)...)
+	cmd.Env = environ
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	// docker compose stops the stack itself on Ctrl+C; stay around for it
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	defer signal.Stop(signals)
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode(), nil
+		}
+		return 1, fmt.Errorf("failed to run docker compose: %w", err)
+	}
+	return 0, nil
+}
+
+// writeComposeOverride maps the ports published by the compose file that
+// the environment doesn't have yet and writes the override remapping them,
+// returning its path
+func writeComposeOverride(composePath string, runtime *environment.Runtime, envPath string) (string, error) {
+	file, err := compose.Load(composePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", composePath, err)
+	}
+
+	if allocatePorts(runtime, envPath, file.PublishedPorts()) {
+		if err := environment.SaveRuntime(envPath, runtime); err != nil {
+			return "", fmt.Errorf("failed to save runtime: %w", err)
+		}
+	}
+
+	data, err := file.Override(runtime.Ports, composeProjectName(runtime))
+	if err != nil {
+		return "", err
+	}
+	overridePath := filepath.Join(envPath, composeOverrideName)
+	if err := os.WriteFile(overridePath, data, 0644); err != nil {
+		return "", err
+	}
+	return overridePath, nil
+}
+
+// allocatePorts maps the given original ports that have no mapping yet,
+// reporting whether any was added
+func allocatePorts(runtime *environment.Runtime, envPath string, wanted map[int]bool) bool {
+	pm := ports.NewPortManager(envPath)
+	if len(runtime.Ports) > 0 {
+		pm.InitializeWithPorts(runtime.Ports)
+	}
+
+	added := false
+	for port := range wanted {
+		if _, exists := runtime.Ports[port]; !exists {
+			runtime.Ports[port] = pm.GetPort(port)
+			added = true
+		}
+	}
+	return added
+}
+
+// composeProjectNameInvalid matches what compose project names can't hold
+var composeProjectNameInvalid = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// composeProjectName returns the compose project of an environment,
+// <project>-<env> lowercased to the characters compose allows
+func composeProjectName(runtime *environment.Runtime) string {
+	name := strings.ToLower(runtime.Project + "-" + runtime.Environment)
+	return strings.TrimLeft(composeProjectNameInvalid.ReplaceAllString(name, "-"), "-_")
+}
+
+// composePorts returns the host ports published by the compose file of the
+// project in dir, so Enter maps them even without a *_PORT variable
+func composePorts(dir string) map[int]bool {
+	path := compose.Find(dir)
+	if path == "" {
+		return nil
+	}
+	file, err := compose.Load(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring %s: %v\n", path, err)
+		return nil
+	}
+	return file.PublishedPorts()
+}
@@ -0,0 +1,118 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/caoer/denv/internal/environment"
+	"github.com/caoer/denv/internal/testutil"
+)
+
+const composeFile = `services:
+  web:
+    image: nginx
+    ports:
+      - "3000:80"
+  db:
+    image: postgres
+    container_name: db
+    ports:
+      - "5432:5432"
+`
+
+func setupComposeProject(t *testing.T) (string, string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script standing in for docker")
+	}
+
+	tmpDir, tmpProject := testutil.SetupProject(t, "composetest")
+	require.NoError(t, os.WriteFile(filepath.Join(tmpProject, "compose.yaml"), []byte(composeFile), 0644))
+	unsetenv(t, "DENV_ENV_NAME")
+	return tmpDir, tmpProject
+}
+
+func TestEnter_MapsComposePorts(t *testing.T) {
+	tmpDir, _ := setupComposeProject(t)
+	require.NoError(t, Enter("dev"))
+
+	// Test: Ports published by the compose file are mapped without a *_PORT variable
+	rt, err := environment.LoadRuntime(filepath.Join(tmpDir, "composetest-dev"))
+	require.NoError(t, err)
+	assert.Contains(t, rt.Ports, 3000)
+	assert.Contains(t, rt.Ports, 5432)
+}
+
+func TestPrepareEnv_MapsComposePorts(t *testing.T) {
+	tmpDir, _ := setupComposeProject(t)
+	require.NoError(t, PrepareEnv("dev"))
+
+	// Test: The wrapper's path maps the same ports as denv enter
+	rt, err := environment.LoadRuntime(filepath.Join(tmpDir, "composetest-dev"))
+	require.NoError(t, err)
+	assert.Contains(t, rt.Ports, 3000)
+	assert.Contains(t, rt.Ports, 5432)
+}
+
+func TestCompose(t *testing.T) {
+	tmpDir, tmpProject := setupComposeProject(t)
+	envPath := filepath.Join(tmpDir, "composetest-dev")
+	require.NoError(t, os.MkdirAll(envPath, 0755))
+	require.NoError(t, environment.SaveRuntime(envPath, &environment.Runtime{
+		Project:     "composetest",
+		Environment: "dev",
+		Ports:       map[int]int{3000: 33000},
+	}))
+
+	// A fake docker recording how it was called
+	bin := t.TempDir()
+	record := filepath.Join(bin, "record")
+	script := "#!/bin/sh\necho \"$* $COMPOSE_PROJECT_NAME $PORT_3000\" > " + record + "\nexit 7\n"
+	require.NoError(t, os.WriteFile(filepath.Join(bin, "docker"), []byte(script), 0755))
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	code, err := Compose("dev", []string{"up", "-d"})
+	require.NoError(t, err)
+
+	// Test: docker's exit code is passed on
+	assert.Equal(t, 7, code)
+
+	// Test: docker compose gets both files, the project name and the variables
+	overridePath := filepath.Join(envPath, "compose.override.yml")
+	data, err := os.ReadFile(record)
+	require.NoError(t, err)
+	assert.Equal(t, "compose -f "+filepath.Join(tmpProject, "compose.yaml")+" -f "+overridePath+" up -d composetest-dev 33000\n", string(data))
+
+	// Test: The compose file's other ports are mapped and saved
+	rt, err := environment.LoadRuntime(envPath)
+	require.NoError(t, err)
+	dbPort, ok := rt.Ports[5432]
+	require.True(t, ok)
+
+	override, err := os.ReadFile(overridePath)
+	require.NoError(t, err)
+	assert.Contains(t, string(override), "- 33000:80\n")
+	assert.Contains(t, string(override), "- "+strconv.Itoa(dbPort)+":5432\n")
+	assert.Contains(t, string(override), "container_name: db-composetest-dev\n")
+}
+
+func TestCompose_Errors(t *testing.T) {
+	_, tmpProject := setupComposeProject(t)
+
+	_, err := Compose("dev", nil)
+	assert.ErrorContains(t, err, "does not exist")
+
+	require.NoError(t, Enter("dev"))
+	require.NoError(t, os.Remove(filepath.Join(tmpProject, "compose.yaml")))
+	_, err = Compose("dev", nil)
+	assert.ErrorContains(t, err, "no compose file")
+}
+
+func TestComposeProjectName(t *testing.T) {
+	assert.Equal(t, "myapp-feature-x", composeProjectName(&environment.Runtime{Project: "MyApp", Environment: "feature.x"}))
+	assert.Equal(t, "app-dev", composeProjectName(&environment.Runtime{Project: "_app", Environment: "dev"}))
+}
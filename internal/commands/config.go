@@ -18,14 +18,13 @@ func ConfigUpdate() error {
 		return fmt.Errorf("failed to load existing config: %w", err)
 	}
 
-	// Create new config with default patterns
-	newCfg := &config.Config{
-		Projects: existingCfg.Projects, // Preserve existing project overrides
-		Patterns: config.GetDefaultPatterns(), // Use new default patterns
-	}
+	// Replace patterns with the new defaults, preserving everything else
+	// (project overrides, pinned environments, ...)
+	newCfg := *existingCfg
+	newCfg.Patterns = config.GetDefaultPatterns()
 
 	// Save the updated config
-	if err := config.SaveConfig(configPath, newCfg); err != nil {
+	if err := config.SaveConfig(configPath, &newCfg); err != nil {
 		return fmt.Errorf("failed to save updated config: %w", err)
 	}
 
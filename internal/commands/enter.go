@@ -3,7 +3,6 @@ package commands
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
 	"sort"
@@ -14,9 +13,9 @@ import (
 	"github.com/caoer/denv/internal/ui"
 	"github.com/caoer/denv/internal/config"
 	"github.com/caoer/denv/internal/environment"
+	"github.com/caoer/denv/internal/hooks"
 	"github.com/caoer/denv/internal/override"
 	"github.com/caoer/denv/internal/paths"
-	"github.com/caoer/denv/internal/ports"
 	"github.com/caoer/denv/internal/project"
 	"github.com/caoer/denv/internal/session"
 	"github.com/caoer/denv/internal/shell"
@@ -25,12 +24,15 @@ import (
 func Enter(envName string) error {
 	// Check if we're already in a denv environment
 	if existingEnv := os.Getenv("DENV_ENV_NAME"); existingEnv != "" {
-		return fmt.Errorf("already in denv environment '%s'. Please exit the current environment before entering a new one", existingEnv)
+		return fmt.Errorf("already in denv environment '%s'. Please exit the current environment before entering a new one, or use 'denv switch' with the shell hook", existingEnv)
 	}
 	
 	if envName == "" {
 		envName = "default"
 	}
+	if err := checkEnvironmentName(envName); err != nil {
+		return err
+	}
 
 	// Detect project
 	cwd, _ := os.Getwd()
@@ -41,7 +43,18 @@ func Enter(envName string) error {
 
 	// Check for project override
 	cfg, _ := config.LoadConfig(filepath.Join(paths.DenvHome(), "config.yaml"))
-	projectName := project.DetectProjectWithConfig(cwd, cfg)
+	projectName := project.RegisterProjectWithConfig(cwd, cfg)
+
+	// Patterns and services from an approved .denv.yaml
+	cfg = withRepoConfig(cfg, cwd)
+
+	// In a network namespace the original ports are free to use
+	useNetns := netnsIsolation(cfg)
+	if useNetns {
+		if err := checkNetnsServices(cfg); err != nil {
+			return err
+		}
+	}
 
 	// Create environment path
 	envPath := paths.EnvironmentPath(projectName, envName)
@@ -60,31 +73,15 @@ func Enter(envName string) error {
 
 	// Load or create runtime
 	runtime, _ := environment.LoadRuntime(envPath)
-	if runtime == nil {
+	isNew := runtime == nil
+	if isNew {
 		runtime = environment.NewRuntime(projectName, envName)
 	}
+	recordSubproject(runtime, cwd, cfg)
+	firstSession := countActiveSessions(runtime) == 0
 
-	// Setup port manager and initialize with existing runtime ports
-	pm := ports.NewPortManager(envPath)
-	
-	// Initialize port manager with existing runtime ports to respect them
-	if len(runtime.Ports) > 0 {
-		pm.InitializeWithPorts(runtime.Ports)
-	}
-	
-	// Collect ports that are actually used by environment variables
-	usedPorts := collectUsedPorts(os.Environ(), cfg)
-	for port := range usedPorts {
-		// Check if we already have a mapping in runtime
-		if existingPort, exists := runtime.Ports[port]; exists {
-			// Use existing mapping
-			runtime.Ports[port] = existingPort
-		} else {
-			// Get new mapping from port manager
-			mappedPort := pm.GetPort(port)
-			runtime.Ports[port] = mappedPort
-		}
-	}
+	// Map the ports the environment uses, keeping existing mappings
+	mapEnvironmentPorts(cfg, runtime, envPath, cwd)
 
 	// Create session (skip in test mode)
 	var sessionHandle *session.SessionHandle
@@ -103,44 +100,41 @@ func Enter(envName string) error {
 		}
 	}
 
+	// Record usage for age-based garbage collection
+	runtime.MarkUsed()
+
 	// Save runtime
 	_ = environment.SaveRuntime(envPath, runtime)
 
-	// Prepare environment variables
-	env := make(map[string]string)
-	
-	// Core denv variables
-	env["DENV_HOME"] = paths.DenvHome()
-	env["DENV_ENV"] = envPath
-	env["DENV_PROJECT"] = projectPath
-	env["DENV_ENV_NAME"] = envName
-	env["DENV_PROJECT_NAME"] = projectName
-	env["DENV_SESSION"] = sessionHandle.ID
-
-	// Port mappings
-	for orig, mapped := range runtime.Ports {
-		env[fmt.Sprintf("PORT_%d", orig)] = strconv.Itoa(mapped)
-		env[fmt.Sprintf("ORIGINAL_PORT_%d", orig)] = strconv.Itoa(orig)
+	envRuntime := runtime
+	if useNetns {
+		envRuntime = namespacedRuntime(runtime)
 	}
 
-	// Apply override rules
-	currentEnv := os.Environ()
-	envMap := make(map[string]string)
-	for _, e := range currentEnv {
-		if kv := splitEnv(e); len(kv) == 2 {
-			envMap[kv[0]] = kv[1]
-		}
-	}
-	
-	overridden, overrides := override.ApplyRules(envMap, cfg, runtime.Ports, envPath)
-	for k, v := range overridden {
-		env[k] = v
+	// Prepare environment variables
+	env, overrides := environmentVariables(cfg, envRuntime, envPath, sessionHandle.ID)
+	if useNetns {
+		env["DENV_ISOLATION"] = config.IsolationNetns
 	}
-	
+
 	// Store overrides in runtime for persistence
 	runtime.Overrides = overrides
 	_ = environment.SaveRuntime(envPath, runtime)
 
+	// Run lifecycle hooks now that ports and overrides are settled
+	if isNew {
+		runHooks(hooks.OnCreate, runtime, envPath, sessionHandle.ID, os.Stdout)
+	}
+	if firstSession {
+		runHooks(hooks.OnFirstEnter, runtime, envPath, sessionHandle.ID, os.Stdout)
+	}
+	runHooks(hooks.OnEnter, runtime, envPath, sessionHandle.ID, os.Stdout)
+	if firstSession {
+		if err := startServices(cfg, runtime, envPath, nil, true, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+
 	// Check for test mode
 	if os.Getenv("DENV_TEST_MODE") == "1" {
 		allEnvPorts := getAllProjectEnvironmentPorts(projectName, envName)
@@ -153,36 +147,41 @@ func Enter(envName string) error {
 	if shellPath == "" {
 		shellPath = "/bin/bash"
 	}
-	shellType, _ := shell.DetectShell(shellPath)
+	shellType, shellName := shell.DetectShell(shellPath)
+	if shellName != shellType.String() {
+		fmt.Fprintf(os.Stderr, "Warning: unsupported shell '%s', starting bash\n", shellName)
+	}
 	
 	// Generate shell-specific wrapper script
 	wrapperScript := shell.GenerateShellWrapper(shellType, env)
 	
-	// Write wrapper to temp file
-	tmpFile, err := os.CreateTemp("", "denv-wrapper-*.sh")
+	// Write wrapper and the shell's startup files to a temp directory
+	startupDir, err := os.MkdirTemp("", "denv-shell-*")
 	if err != nil {
 		return err
 	}
-	defer os.Remove(tmpFile.Name())
-	
-	_, _ = tmpFile.WriteString(wrapperScript)
-	tmpFile.Close()
+	defer os.RemoveAll(startupDir)
+
+	wrapperPath := filepath.Join(startupDir, "wrapper"+shell.ScriptExtension(shellType))
+	if err := os.WriteFile(wrapperPath, []byte(wrapperScript), 0644); err != nil {
+		return err
+	}
 
 	// Print entry message with all project environments
 	allEnvPorts := getAllProjectEnvironmentPorts(projectName, envName)
 	printEnterMessage(envName, projectName, runtime.Ports, overrides, allEnvPorts)
+	if useNetns {
+		fmt.Println("🔒 Network namespace: original ports are used as-is and reachable on the mapped ports from the host")
+	}
 
 	// Get shell-specific command
-	shellArgs := shell.GetShellCommand(shellType, tmpFile.Name())
-	
-	// Start new shell with appropriate method
-	cmd := exec.Command(shellArgs[0], shellArgs[1:]...)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	shellArgs, shellEnv, err := shell.StartupCommand(shellType, wrapperPath, startupDir)
+	if err != nil {
+		return err
+	}
 
 	// Run the shell and wait for it to exit
-	err = cmd.Run()
+	err = runShell(shellArgs, shellEnv, runtime, useNetns)
 	
 	// Clean up the session after shell exits
 	cleanupSession(envPath, sessionHandle)
@@ -210,6 +209,12 @@ func cleanupSession(envPath string, sessionHandle *session.SessionHandle) {
 	
 	// Save the updated runtime
 	_ = environment.SaveRuntime(envPath, runtime)
+
+	runHooks(hooks.OnExit, runtime, envPath, sessionHandle.ID, os.Stdout)
+	if countActiveSessions(runtime) == 0 {
+		runHooks(hooks.OnLastExit, runtime, envPath, sessionHandle.ID, os.Stdout)
+		_ = stopServices(envPath, nil, true, os.Stdout)
+	}
 	
 	// Remove the lock file
 	lockPath := filepath.Join(envPath, "sessions", sessionHandle.ID+".lock")
@@ -231,17 +236,89 @@ func cleanupProjectSymlinks(projectName, envName string) {
 	envLink := filepath.Join(denvDir, envLinkName)
 	os.Remove(envLink)
 	
-	// Check if there are any other active environments for this project
+	// Check if there are any other active environments for this project;
+	// the pattern also matches the links of projects whose name extends
+	// this one's, and their project links (the star is a wildcard too)
 	pattern := filepath.Join(denvDir, fmt.Sprintf("*%s-*", projectName))
 	matches, _ := filepath.Glob(pattern)
+	others := 0
+	for _, match := range matches {
+		linkName, ok := strings.CutPrefix(filepath.Base(match), "*")
+		if !ok {
+			continue
+		}
+		runtime, _ := environment.LoadRuntime(match)
+		if name, _, ok := environmentDir(linkName, runtime); ok && name == projectName {
+			others++
+		}
+	}
 	
 	// If no other environments, remove the project symlink too
-	if len(matches) == 0 {
+	if others == 0 {
 		projectLink := filepath.Join(denvDir, projectName)
 		os.Remove(projectLink)
 	}
 }
 
+// recordSubproject notes in runtime which monorepo sub-project in dir the
+// environment belongs to, so denv ls can group it under the repository
+func recordSubproject(runtime *environment.Runtime, dir string, cfg *config.Config) {
+	if mode, _ := cfg.SubprojectMode(); mode != config.SubprojectsSeparate {
+		return
+	}
+	sub := project.DetectSubproject(dir, cfg)
+	if sub == nil || sub.Alias() != runtime.Project {
+		return
+	}
+	runtime.Parent = sub.Parent
+	runtime.Subproject = sub.Path
+}
+
+// environmentVariables returns the variables a process in the environment
+// sees: the current variables with the override rules applied, plus denv's
+// own variables and the port mappings
+func environmentVariables(cfg *config.Config, runtime *environment.Runtime, envPath, sessionID string) (map[string]string, map[string]environment.Override) {
+	env := denvVariables(runtime, envPath, sessionID)
+
+	// Apply override rules
+	envMap := make(map[string]string)
+	for _, e := range os.Environ() {
+		if kv := splitEnv(e); len(kv) == 2 {
+			envMap[kv[0]] = kv[1]
+		}
+	}
+
+	overridden, overrides := override.ApplyRules(envMap, cfg, runtime.Ports, envPath)
+	for k, v := range overridden {
+		env[k] = v
+	}
+
+	return env, overrides
+}
+
+// denvVariables returns denv's own variables and the port mappings
+func denvVariables(runtime *environment.Runtime, envPath, sessionID string) map[string]string {
+	env := make(map[string]string)
+
+	// Core denv variables
+	env["DENV_HOME"] = paths.DenvHome()
+	env["DENV_ENV"] = envPath
+	env["DENV_PROJECT"] = paths.ProjectPath(runtime.Project)
+	env["DENV_ENV_NAME"] = runtime.Environment
+	env["DENV_PROJECT_NAME"] = runtime.Project
+	if sessionID != "" {
+		env["DENV_SESSION"] = sessionID
+	}
+
+	// Port mappings
+	for orig, mapped := range runtime.Ports {
+		env[fmt.Sprintf("PORT_%d", orig)] = strconv.Itoa(mapped)
+		env[fmt.Sprintf("ORIGINAL_PORT_%d", orig)] = strconv.Itoa(orig)
+	}
+
+	return env
+}
+
 func splitEnv(env string) []string {
 	if idx := strings.Index(env, "="); idx >= 0 {
 		return []string{env[:idx], env[idx+1:]}
@@ -249,6 +326,24 @@ func splitEnv(env string) []string {
 	return []string{env}
 }
 
+// mapEnvironmentPorts gives every port the environment uses a mapping in
+// runtime, keeping existing ones, and returns those ports: the ones
+// referenced by variables, listened on by services and published by the
+// compose file of the project in dir
+func mapEnvironmentPorts(cfg *config.Config, runtime *environment.Runtime, envPath, dir string) map[int]bool {
+	usedPorts := collectUsedPorts(os.Environ(), cfg)
+	// Services listen on their mapped ports, so map those up front too
+	for port := range servicePorts(cfg) {
+		usedPorts[port] = true
+	}
+	// So do the ports published by the project's compose file
+	for port := range composePorts(dir) {
+		usedPorts[port] = true
+	}
+	allocatePorts(runtime, envPath, usedPorts)
+	return usedPorts
+}
+
 // collectUsedPorts analyzes environment variables to find which ports are actually referenced
 func collectUsedPorts(environ []string, cfg *config.Config) map[int]bool {
 	ports := make(map[int]bool)
@@ -361,16 +456,18 @@ func getAllProjectEnvironmentPorts(projectName, currentEnv string) map[int]strin
 	}
 	
 	for _, envPath := range matches {
-		// Extract environment name from path
-		envDir := filepath.Base(envPath)
-		envName := strings.TrimPrefix(envDir, projectName+"-")
-		
 		// Load runtime for this environment
 		runtime, err := environment.LoadRuntime(envPath)
 		if err != nil || runtime == nil {
 			continue
 		}
 		
+		// The pattern also matches projects whose name extends this one's
+		name, envName, ok := environmentDir(filepath.Base(envPath), runtime)
+		if !ok || name != projectName {
+			continue
+		}
+		
 		// Record which environment owns each mapped port
 		for _, mappedPort := range runtime.Ports {
 			portOwners[mappedPort] = envName
@@ -199,4 +199,39 @@ func TestPortsJsonAndRuntimeJsonConsistency(t *testing.T) {
 
 	// Verify consistency between runtime.json and ports.json
 	assert.Equal(t, runtime.Ports[8080], portMappings[8080])
-}
\ No newline at end of file
+}
+func TestEnterRecordsSubproject(t *testing.T) {
+	tmpDir, tmpProject := setupHookProject(t)
+	billing := filepath.Join(tmpProject, "services", "billing")
+	require.NoError(t, os.MkdirAll(billing, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(billing, "go.mod"), []byte("module billing\n"), 0644))
+
+	oldCwd, _ := os.Getwd()
+	require.NoError(t, os.Chdir(billing))
+	defer func() { _ = os.Chdir(oldCwd) }()
+
+	// By default the sub-project uses the repository's environments
+	require.NoError(t, Enter("dev"))
+	runtime, err := environment.LoadRuntime(filepath.Join(tmpDir, "hooktest-dev"))
+	require.NoError(t, err)
+	require.NotNil(t, runtime)
+	assert.Empty(t, runtime.Parent)
+
+	// Separate sub-projects get their own, recorded as the parent's
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "config.yaml"), []byte("subprojects:\n  mode: separate\n"), 0644))
+	require.NoError(t, Enter("dev"))
+	runtime, err = environment.LoadRuntime(filepath.Join(tmpDir, "hooktest.services.billing-dev"))
+	require.NoError(t, err)
+	require.NotNil(t, runtime)
+	assert.Equal(t, "hooktest.services.billing", runtime.Project)
+	assert.Equal(t, "hooktest", runtime.Parent)
+	assert.Equal(t, "services/billing", runtime.Subproject)
+
+	// So does the wrapper's prepare-env
+	require.NoError(t, PrepareEnv("local"))
+	runtime, err = environment.LoadRuntime(filepath.Join(tmpDir, "hooktest.services.billing-local"))
+	require.NoError(t, err)
+	require.NotNil(t, runtime)
+	assert.Equal(t, "hooktest", runtime.Parent)
+	assert.Equal(t, "services/billing", runtime.Subproject)
+}
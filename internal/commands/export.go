@@ -1,84 +1,182 @@
 package commands
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
-	"os"
+	"regexp"
 	"sort"
-	"strconv"
+	"strings"
 
 	"github.com/caoer/denv/internal/environment"
-	"github.com/caoer/denv/internal/paths"
-	"github.com/caoer/denv/internal/project"
+	"github.com/caoer/denv/internal/shell"
 )
 
-// Export outputs environment variables for direnv integration
-func Export(envName string, w io.Writer) error {
+// ExportFormats lists the formats denv export writes besides shell names
+var ExportFormats = []string{"sh", "dotenv", "json", "systemd", "docker"}
+
+// ExportOptions selects what denv export writes and how
+type ExportOptions struct {
+	// Format is one of ExportFormats or a shell name; empty means sh
+	Format string
+	// NoCore leaves out the DENV_* variables
+	NoCore bool
+	// NoPorts leaves out the PORT_* and ORIGINAL_PORT_* mappings
+	NoPorts bool
+}
+
+// exportVar is one variable written by denv export
+type exportVar struct {
+	key, value string
+}
+
+// Export outputs an environment's variables for direnv and other tools, in
+// the format selected by opts
+func Export(envName string, opts ExportOptions, w io.Writer) error {
 	if envName == "" {
 		envName = "default"
 	}
 
-	// Detect current project
-	cwd, _ := os.Getwd()
-	projectName, err := project.DetectProject(cwd)
+	assign, err := exportAssignment(opts.Format)
 	if err != nil {
-		return fmt.Errorf("failed to detect project: %w", err)
+		return err
 	}
 
-	// Load runtime
-	envPath := paths.EnvironmentPath(projectName, envName)
-	runtime, err := environment.LoadRuntime(envPath)
+	// Resolve the project and configuration the way Enter does
+	cfg, runtime, envPath, err := loadTargetEnvironment(envName)
 	if err != nil {
-		return fmt.Errorf("failed to load environment: %w", err)
-	}
-	if runtime == nil {
-		return fmt.Errorf("environment '%s' does not exist for project %s", envName, projectName)
+		return err
 	}
 
-	// Output environment variables
-	fmt.Fprintf(w, "# denv environment: %s/%s\n", projectName, envName)
-	
-	// Core variables
-	fmt.Fprintf(w, "export DENV_HOME=\"%s\"\n", paths.DenvHome())
-	fmt.Fprintf(w, "export DENV_ENV=\"%s\"\n", envPath)
-	fmt.Fprintf(w, "export DENV_PROJECT=\"%s\"\n", paths.ProjectPath(projectName))
-	fmt.Fprintf(w, "export DENV_ENV_NAME=\"%s\"\n", envName)
-	fmt.Fprintf(w, "export DENV_PROJECT_NAME=\"%s\"\n", projectName)
-
-	// Port mappings (sorted for consistency)
-	var ports []int
-	for p := range runtime.Ports {
-		ports = append(ports, p)
+	// Overrides are computed from the caller's variables rather than read
+	// from the runtime, which holds those of the last shell that entered
+	vars := exportVariables(runtime, envPath, opts)
+	_, applied := environmentVariables(cfg, runtime, envPath, "")
+	overrides := make([]exportVar, 0, len(applied))
+	for key, override := range applied {
+		overrides = append(overrides, exportVar{key, override.Current})
 	}
-	sort.Ints(ports)
+	sort.Slice(overrides, func(i, j int) bool {
+		return overrides[i].key < overrides[j].key
+	})
+	all := append(vars, overrides...)
 
-	for _, orig := range ports {
-		mapped := runtime.Ports[orig]
-		fmt.Fprintf(w, "export PORT_%d=\"%d\"\n", orig, mapped)
-		fmt.Fprintf(w, "export ORIGINAL_PORT_%d=\"%d\"\n", orig, orig)
+	if assign == nil {
+		// JSON has no comments, so everything goes into one object
+		object := make(map[string]string)
+		for _, v := range all {
+			object[v.key] = v.value
+		}
+		data, err := json.MarshalIndent(object, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
 	}
 
-	// Apply overrides if any
-	if len(runtime.Overrides) > 0 {
-		fmt.Fprintln(w, "\n# Variable overrides")
-		
-		// Sort keys for consistency
-		var keys []string
-		for k := range runtime.Overrides {
-			keys = append(keys, k)
+	fmt.Fprintf(w, "# denv environment: %s/%s\n", runtime.Project, runtime.Environment)
+	for i, v := range all {
+		if i == len(vars) {
+			fmt.Fprintln(w, "\n# Variable overrides")
 		}
-		sort.Strings(keys)
-		
-		for _, key := range keys {
-			override := runtime.Overrides[key]
-			fmt.Fprintf(w, "export %s=\"%s\"\n", key, escapeForShell(override.Current))
+		line, err := assign(v.key, v.value)
+		if err != nil {
+			return err
 		}
+		fmt.Fprintln(w, line)
 	}
 
 	return nil
 }
 
-func escapeForShell(value string) string {
-	// Basic escaping for shell export
-	return strconv.Quote(value)[1:len(strconv.Quote(value))-1]
-}
\ No newline at end of file
+// exportVariables returns denv's own variables for the environment, as set
+// by Enter, without the ones opts leaves out. They are sorted, with the
+// ports in numeric order.
+func exportVariables(runtime *environment.Runtime, envPath string, opts ExportOptions) []exportVar {
+	var vars []exportVar
+	for key, value := range denvVariables(runtime, envPath, "") {
+		isPort := strings.HasPrefix(key, "PORT_") || strings.HasPrefix(key, "ORIGINAL_PORT_")
+		if (isPort && opts.NoPorts) || (!isPort && opts.NoCore) {
+			continue
+		}
+		vars = append(vars, exportVar{key, value})
+	}
+
+	sortKey := func(key string) string {
+		// Pad port numbers so PORT_8080 sorts before PORT_10000, and keep
+		// each ORIGINAL_PORT_ after its PORT_
+		name := strings.TrimPrefix(key, "ORIGINAL_")
+		if port := strings.TrimPrefix(name, "PORT_"); port != name {
+			return fmt.Sprintf("PORT_%05s_%t", port, name != key)
+		}
+		return key
+	}
+	sort.Slice(vars, func(i, j int) bool {
+		return sortKey(vars[i].key) < sortKey(vars[j].key)
+	})
+	return vars
+}
+
+// exportAssignment returns the function writing one variable in format,
+// or nil for JSON
+func exportAssignment(format string) (func(key, value string) (string, error), error) {
+	switch format {
+	case "", "sh":
+		format = "bash"
+	case "json":
+		return nil, nil
+	case "dotenv":
+		return func(key, value string) (string, error) {
+			return key + "=" + quoteDotenv(value), nil
+		}, nil
+	case "systemd":
+		return func(key, value string) (string, error) {
+			return key + "=" + quoteSystemd(value), nil
+		}, nil
+	case "docker":
+		return func(key, value string) (string, error) {
+			// Docker env files take the rest of the line as is
+			if strings.ContainsAny(value, "\r\n") {
+				return "", fmt.Errorf("%s contains a line break, which docker env files can't hold", key)
+			}
+			return key + "=" + value, nil
+		}, nil
+	}
+
+	shellType, err := shell.ParseShellName(format)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported format '%s' (supported: %s, or a shell: bash, zsh, fish, nu, pwsh, xonsh)",
+			format, strings.Join(ExportFormats, ", "))
+	}
+	return func(key, value string) (string, error) {
+		return shell.ExportCommand(shellType, key, value), nil
+	}, nil
+}
+
+// plainValue matches values that need no quoting in dotenv and systemd files
+var plainValue = regexp.MustCompile(`^[A-Za-z0-9_./:@%+,=-]+$`)
+
+// quoteDotenv quotes a value for .env files. Single quotes are literal in
+// every dotenv dialect, so they're used unless the value holds one or a
+// line break; double quotes then take backslash escapes.
+func quoteDotenv(value string) string {
+	if plainValue.MatchString(value) {
+		return value
+	}
+	if !strings.ContainsAny(value, "'\r\n") {
+		return "'" + value + "'"
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
+	return `"` + r.Replace(value) + `"`
+}
+
+// quoteSystemd quotes a value for a systemd EnvironmentFile, which takes
+// shell-like double quotes that may span lines
+func quoteSystemd(value string) string {
+	if plainValue.MatchString(value) {
+		return value
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`")
+	return `"` + r.Replace(value) + `"`
+}
@@ -2,12 +2,15 @@ package commands
 
 import (
 	"bytes"
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/caoer/denv/internal/config"
 	"github.com/caoer/denv/internal/environment"
 	"github.com/caoer/denv/internal/paths"
 	"github.com/caoer/denv/internal/testutil"
@@ -41,7 +44,7 @@ func TestExportCommand(t *testing.T) {
 
 	// Test: Export should output environment variables
 	var output bytes.Buffer
-	err := Export("test", &output)
+	err := Export("test", ExportOptions{}, &output)
 	assert.NoError(t, err)
 
 	result := output.String()
@@ -78,7 +81,7 @@ func TestExportForDirenv(t *testing.T) {
 
 	// Test: Export without environment name should use default
 	var output bytes.Buffer
-	err := Export("", &output)
+	err := Export("", ExportOptions{}, &output)
 	assert.NoError(t, err)
 
 	result := output.String()
@@ -91,4 +94,133 @@ func TestExportForDirenv(t *testing.T) {
 				"Line should start with 'export ': %s", line)
 		}
 	}
-}
\ No newline at end of file
+}
+
+func TestExport_Formats(t *testing.T) {
+	testutil.SetupProject(t, "formattest")
+
+	envPath := paths.EnvironmentPath("formattest", "default")
+	_ = os.MkdirAll(envPath, 0755)
+	runtime := &environment.Runtime{
+		Project:     "formattest",
+		Environment: "default",
+		Ports:       map[int]int{3000: 33000, 10000: 40000},
+	}
+	_ = environment.SaveRuntime(envPath, runtime)
+	t.Setenv("APP_URL", "http://localhost:3000/it's $HOME")
+
+	export := func(opts ExportOptions) string {
+		var output bytes.Buffer
+		require.NoError(t, Export("", opts, &output))
+		return output.String()
+	}
+
+	// Test: Shell formats use the shell's quoting
+	assert.Contains(t, export(ExportOptions{}), "export APP_URL=\"http://localhost:33000/it's \\$HOME\";\n")
+	assert.Contains(t, export(ExportOptions{Format: "pwsh"}), "$env:APP_URL = 'http://localhost:33000/it''s $HOME'\n")
+	assert.Contains(t, export(ExportOptions{Format: "nu"}), "$env.PORT_3000 = \"33000\"\n")
+	assert.Contains(t, export(ExportOptions{Format: "fish"}), "set -gx PORT_3000 \"33000\";\n")
+
+	// Test: File formats
+	dotenv := export(ExportOptions{Format: "dotenv"})
+	assert.Contains(t, dotenv, "PORT_3000=33000\n")
+	assert.Contains(t, dotenv, "APP_URL=\"http://localhost:33000/it's $HOME\"\n")
+	assert.Contains(t, export(ExportOptions{Format: "systemd"}), "APP_URL=\"http://localhost:33000/it's \\$HOME\"\n")
+	assert.Contains(t, export(ExportOptions{Format: "docker"}), "APP_URL=http://localhost:33000/it's $HOME\n")
+
+	var object map[string]string
+	require.NoError(t, json.Unmarshal([]byte(export(ExportOptions{Format: "json"})), &object))
+	assert.Equal(t, "40000", object["PORT_10000"])
+	assert.Equal(t, "http://localhost:33000/it's $HOME", object["APP_URL"])
+
+	// Test: Ports are in numeric order
+	sh := export(ExportOptions{})
+	assert.Less(t, strings.Index(sh, "PORT_3000="), strings.Index(sh, "PORT_10000="))
+
+	// Test: Core variables and port mappings can be left out
+	only := export(ExportOptions{Format: "dotenv", NoCore: true, NoPorts: true})
+	assert.NotContains(t, only, "DENV_")
+	assert.NotContains(t, only, "PORT_")
+	assert.Contains(t, only, "APP_URL=")
+	noCore := export(ExportOptions{Format: "dotenv", NoCore: true})
+	assert.NotContains(t, noCore, "DENV_ENV=")
+	assert.Contains(t, noCore, "ORIGINAL_PORT_3000=3000\n")
+
+	// Test: Unknown formats are rejected
+	assert.Error(t, Export("", ExportOptions{Format: "tcsh"}, &bytes.Buffer{}))
+}
+
+func TestExport_DockerRejectsLineBreaks(t *testing.T) {
+	assign, err := exportAssignment("docker")
+	require.NoError(t, err)
+	_, err = assign("MOTD", "two\nlines")
+	assert.ErrorContains(t, err, "MOTD")
+}
+
+func TestQuoteDotenv(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"33000", "33000"},
+		{"postgres://db:35432/app", "postgres://db:35432/app"},
+		{"", "''"},
+		{"with spaces $HOME", "'with spaces $HOME'"},
+		{"it's", `"it's"`},
+		{"multi\nline \"x\" \\", `"multi\nline \"x\" \\"`},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, quoteDotenv(tt.value), tt.value)
+	}
+}
+
+func TestQuoteSystemd(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"33000", "33000"},
+		{"", `""`},
+		{"it's $HOME `id`", "\"it's \\$HOME \\`id\\`\""},
+		{"multi\nline", "\"multi\nline\""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, quoteSystemd(tt.value), tt.value)
+	}
+}
+
+func TestExport_ResolvesLikeEnter(t *testing.T) {
+	tmpDir, _ := testutil.SetupProject(t, "resolvetest")
+
+	// A project override in config.yaml renames the project
+	cwd, _ := os.Getwd()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	cfg, err := config.LoadConfig(configPath)
+	require.NoError(t, err)
+	cfg.Projects = map[string]string{cwd: "renamed"}
+	require.NoError(t, config.SaveConfig(configPath, cfg))
+
+	envPath := paths.EnvironmentPath("renamed", "default")
+	_ = os.MkdirAll(envPath, 0755)
+	runtime := &environment.Runtime{
+		Project:     "renamed",
+		Environment: "default",
+		Ports:       map[int]int{5432: 35432},
+		Overrides: map[string]environment.Override{
+			// Left by another shell that entered
+			"DATABASE_URL": {Original: "postgres://localhost:5432/other", Current: "postgres://localhost:35432/other"},
+		},
+	}
+	_ = environment.SaveRuntime(envPath, runtime)
+	t.Setenv("DATABASE_URL", "postgres://localhost:5432/mine")
+
+	var output bytes.Buffer
+	require.NoError(t, Export("", ExportOptions{Format: "dotenv"}, &output))
+
+	// Test: The renamed project's environment is exported
+	assert.Contains(t, output.String(), "DENV_PROJECT_NAME=renamed\n")
+
+	// Test: Overrides come from the caller's variables, not the runtime
+	assert.Contains(t, output.String(), "DATABASE_URL=postgres://localhost:35432/mine\n")
+	assert.NotContains(t, output.String(), "other")
+}
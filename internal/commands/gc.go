@@ -0,0 +1,180 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/caoer/denv/internal/config"
+	"github.com/caoer/denv/internal/environment"
+	"github.com/caoer/denv/internal/hooks"
+	"github.com/caoer/denv/internal/paths"
+)
+
+// GC removes environments that have not been entered for longer than
+// olderThan. Pinned environments and environments with active sessions are
+// always kept.
+func GC(olderThan time.Duration, projectFilter string, dryRun bool, w io.Writer) error {
+	denvHome := paths.DenvHome()
+	// Without the config there's no telling which environments are pinned
+	cfg, err := config.LoadConfig(filepath.Join(denvHome, "config.yaml"))
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	purgeExpiredTrash(cfg, w)
+
+	entries, err := os.ReadDir(denvHome)
+	if err != nil {
+		if os.IsNotExist(err) {
+			fmt.Fprintln(w, "No stale environments found")
+			return nil
+		}
+		return fmt.Errorf("failed to read denv home: %w", err)
+	}
+
+	type staleEnv struct {
+		path     string
+		project  string
+		env      string
+		lastUsed time.Time
+		ports    int
+		runtime  *environment.Runtime
+	}
+	var stale []staleEnv
+	cutoff := time.Now().Add(-olderThan)
+
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		// Only directories with a runtime are environments; this keeps
+		// shared project directories safe
+		envPath := filepath.Join(denvHome, entry.Name())
+		runtime, err := environment.LoadRuntime(envPath)
+		if err != nil || runtime == nil {
+			continue
+		}
+
+		if projectFilter != "" && runtime.Project != projectFilter {
+			continue
+		}
+		if cfg.IsPinned(runtime.Project, runtime.Environment) {
+			continue
+		}
+		if countActiveSessions(runtime) > 0 {
+			continue
+		}
+		if runtime.LastActivity().After(cutoff) {
+			continue
+		}
+
+		stale = append(stale, staleEnv{
+			path:     envPath,
+			project:  runtime.Project,
+			env:      runtime.Environment,
+			lastUsed: runtime.LastActivity(),
+			ports:    len(runtime.Ports),
+			runtime:  runtime,
+		})
+	}
+
+	if len(stale) == 0 {
+		fmt.Fprintln(w, "No stale environments found")
+		return nil
+	}
+
+	sort.Slice(stale, func(i, j int) bool {
+		return stale[i].lastUsed.Before(stale[j].lastUsed)
+	})
+
+	verb := "Removed"
+	if dryRun {
+		verb = "Would remove"
+	}
+	fmt.Fprintf(w, "%s %d stale environment(s):\n", verb, len(stale))
+
+	for _, s := range stale {
+		if !dryRun {
+			runHooks(hooks.OnDestroy, s.runtime, s.path, "", w)
+			_ = stopServices(s.path, nil, false, io.Discard)
+			// The port reservations (ports.json) live inside the
+			// environment directory and go with it
+			if err := os.RemoveAll(s.path); err != nil {
+				return fmt.Errorf("failed to remove environment %s:%s: %w", s.project, s.env, err)
+			}
+		}
+		fmt.Fprintf(w, "  • %s:%s (last used %s ago, %d ports)\n",
+			s.project, s.env, formatAge(time.Since(s.lastUsed)), s.ports)
+	}
+
+	if !dryRun {
+		cwd, _ := os.Getwd()
+		if removed := cleanDanglingSymlinks(filepath.Join(cwd, ".denv")); removed > 0 {
+			fmt.Fprintf(w, "Removed %d dangling symlink(s) from .denv/\n", removed)
+		}
+	}
+
+	return nil
+}
+
+// cleanDanglingSymlinks removes symlinks in dir whose target no longer exists
+func cleanDanglingSymlinks(dir string) int {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		info, err := os.Lstat(path)
+		if err != nil || info.Mode()&os.ModeSymlink == 0 {
+			continue
+		}
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			if os.Remove(path) == nil {
+				removed++
+			}
+		}
+	}
+	return removed
+}
+
+// ParseAge parses a duration that may also use day ("14d") and week ("2w")
+// units
+func ParseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if strings.HasSuffix(s, suffix) {
+			n, err := strconv.Atoi(strings.TrimSuffix(s, suffix))
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid age: %s", s)
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age: %s", s)
+	}
+	return d, nil
+}
+
+// formatAge formats a duration in the largest whole unit
+func formatAge(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+}
@@ -0,0 +1,120 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/caoer/denv/internal/environment"
+	"github.com/caoer/denv/internal/testutil"
+)
+
+// ageEnvironment pretends the environment was last entered d ago
+func ageEnvironment(t *testing.T, envPath string, d time.Duration) {
+	runtime, err := environment.LoadRuntime(envPath)
+	require.NoError(t, err)
+	runtime.LastUsed = time.Now().Add(-d)
+	require.NoError(t, environment.SaveRuntime(envPath, runtime))
+}
+
+func TestEnterStampsLastUsed(t *testing.T) {
+	tmpDir, _ := testutil.SetupProject(t, "gctest")
+
+	require.NoError(t, Enter("feature"))
+	require.NoError(t, Enter("feature"))
+
+	runtime, err := environment.LoadRuntime(filepath.Join(tmpDir, "gctest-feature"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, runtime.EnterCount)
+	assert.WithinDuration(t, time.Now(), runtime.LastUsed, time.Minute)
+}
+
+func TestGC_RemovesOnlyStaleEnvironments(t *testing.T) {
+	tmpDir, tmpProject := testutil.SetupProject(t, "gctest")
+
+	for _, name := range []string{"default", "old", "fresh", "busy"} {
+		require.NoError(t, Enter(name))
+	}
+	ageEnvironment(t, filepath.Join(tmpDir, "gctest-default"), 30*24*time.Hour)
+	ageEnvironment(t, filepath.Join(tmpDir, "gctest-old"), 30*24*time.Hour)
+	ageEnvironment(t, filepath.Join(tmpDir, "gctest-busy"), 30*24*time.Hour)
+
+	busyPath := filepath.Join(tmpDir, "gctest-busy")
+	runtime, err := environment.LoadRuntime(busyPath)
+	require.NoError(t, err)
+	runtime.Sessions["active"] = environment.Session{ID: "active", PID: os.Getpid()}
+	require.NoError(t, environment.SaveRuntime(busyPath, runtime))
+
+	// Test: Dry run reports without removing
+	var out bytes.Buffer
+	require.NoError(t, GC(14*24*time.Hour, "", true, &out))
+	assert.Contains(t, out.String(), "Would remove 1")
+	assert.Contains(t, out.String(), "gctest:old")
+	assert.DirExists(t, filepath.Join(tmpDir, "gctest-old"))
+
+	// Test: Real run removes the stale environment and its symlink
+	out.Reset()
+	require.NoError(t, GC(14*24*time.Hour, "", false, &out))
+	assert.NoDirExists(t, filepath.Join(tmpDir, "gctest-old"))
+	assert.DirExists(t, filepath.Join(tmpDir, "gctest-default"), "pinned environment must be kept")
+	assert.DirExists(t, filepath.Join(tmpDir, "gctest-fresh"))
+	assert.DirExists(t, busyPath, "environment with active sessions must be kept")
+	assert.DirExists(t, filepath.Join(tmpDir, "gctest"), "shared project directory must be kept")
+
+	_, err = os.Lstat(filepath.Join(tmpProject, ".denv", "*gctest-old"))
+	assert.True(t, os.IsNotExist(err), "dangling symlink should be removed")
+	_, err = os.Lstat(filepath.Join(tmpProject, ".denv", "*gctest-fresh"))
+	assert.NoError(t, err)
+}
+
+func TestGC_ProjectFilter(t *testing.T) {
+	tmpDir, _ := testutil.SetupProject(t, "gctest")
+
+	require.NoError(t, Enter("old"))
+	ageEnvironment(t, filepath.Join(tmpDir, "gctest-old"), 30*24*time.Hour)
+
+	var out bytes.Buffer
+	require.NoError(t, GC(14*24*time.Hour, "otherproject", false, &out))
+	assert.DirExists(t, filepath.Join(tmpDir, "gctest-old"))
+	assert.Contains(t, out.String(), "No stale environments")
+}
+
+func TestGC_MalformedConfig(t *testing.T) {
+	tmpDir, _ := testutil.SetupProject(t, "gctest")
+
+	require.NoError(t, Enter("default"))
+	ageEnvironment(t, filepath.Join(tmpDir, "gctest-default"), 30*24*time.Hour)
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "config.yaml"), []byte("pinned: [\n"), 0644))
+
+	// Test: Without the pinned list nothing is removed
+	var out bytes.Buffer
+	assert.Error(t, GC(14*24*time.Hour, "", false, &out))
+	assert.DirExists(t, filepath.Join(tmpDir, "gctest-default"))
+}
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected time.Duration
+	}{
+		{"14d", 14 * 24 * time.Hour},
+		{"2w", 14 * 24 * time.Hour},
+		{"36h", 36 * time.Hour},
+		{"90m", 90 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseAge(tt.input)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expected, got)
+	}
+
+	_, err := ParseAge("soon")
+	assert.Error(t, err)
+	_, err = ParseAge("-3d")
+	assert.Error(t, err)
+}
@@ -0,0 +1,323 @@
+package commands
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/caoer/denv/internal/config"
+	"github.com/caoer/denv/internal/environment"
+	"github.com/caoer/denv/internal/hooks"
+	"github.com/caoer/denv/internal/paths"
+	"github.com/caoer/denv/internal/project"
+	"github.com/caoer/denv/internal/session"
+	"github.com/caoer/denv/internal/shell"
+)
+
+// activationVar holds the encoded activation of a shell using denv hook
+const activationVar = "DENV_ACTIVATION"
+
+// activation records what denv changed in a shell so it can be undone
+type activation struct {
+	Project string `json:"project"`
+	Env     string `json:"env"`
+	EnvPath string `json:"env_path"`
+	// Root is the directory that activated the environment; leaving it
+	// deactivates
+	Root    string `json:"root"`
+	Session string `json:"session"`
+	// PID is the shell owning the session; shells inheriting the
+	// activation don't release it
+	PID int `json:"pid"`
+	// Saved holds the previous value of every variable denv set, nil if it
+	// was unset, and Values what it set them to
+	Saved  map[string]*string `json:"saved"`
+	Values map[string]string  `json:"values"`
+}
+
+func (a *activation) encode() string {
+	data, _ := json.Marshal(a)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeActivation(s string) *activation {
+	if s == "" {
+		return nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil
+	}
+	var a activation
+	if json.Unmarshal(data, &a) != nil {
+		return nil
+	}
+	return &a
+}
+
+// Hook prints the shell code activating environments on directory change
+func Hook(shellName string, w io.Writer) error {
+	shellType, err := shell.ParseShellName(shellName)
+	if err != nil {
+		return err
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	script, err := shell.HookScript(shellType, self)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprint(w, script)
+	return err
+}
+
+// Direnvrc prints the direnv library defining use_denv
+func Direnvrc(w io.Writer) error {
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprint(w, shell.DirenvScript(self))
+	return err
+}
+
+// HookEnv prints the commands moving the calling shell (pid) from its
+// current activation to the one for the current directory. It prints
+// nothing when nothing changes, so it is cheap to run at every prompt.
+func HookEnv(shellName string, pid int, w io.Writer) error {
+	shellType, err := shell.ParseShellName(shellName)
+	if err != nil {
+		return err
+	}
+	cwd, _ := os.Getwd()
+
+	changes, err := hookChanges(cwd, pid)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "denv: %v\n", err)
+	}
+	writeChanges(shellType, changes, w)
+	return nil
+}
+
+// HookExit releases the sessions held by an exiting shell (pid): its
+// current activation's and those suspended under it by denv push
+func HookExit(pid int) {
+	current := decodeActivation(os.Getenv(activationVar))
+	if current == nil {
+		return
+	}
+	for _, a := range append([]*activation{current}, loadStack()...) {
+		if a.PID == pid && a.Session != "" {
+			releaseSession(a.EnvPath, a.Session, os.Stderr)
+		}
+	}
+}
+
+// hookChanges returns the variables to set (or unset, when nil) to move
+// from the current activation to the one for dir
+func hookChanges(dir string, pid int) (map[string]*string, error) {
+	current := decodeActivation(os.Getenv(activationVar))
+	if current == nil && os.Getenv("DENV_ENV_NAME") != "" {
+		// Inside a shell started by denv enter
+		return nil, nil
+	}
+
+	root := activationRoot(dir)
+	if current == nil && root == "" {
+		return nil, nil
+	}
+	if current != nil && current.Root == root {
+		return nil, nil
+	}
+
+	changes := make(map[string]*string)
+	if current != nil {
+		deactivateAll(current, pid, changes)
+	}
+	if root == "" {
+		return changes, nil
+	}
+	return changes, activate(root, "default", pid, changes)
+}
+
+// activationRoot returns the nearest directory at or above dir that has a
+// .denv.yaml or a .denv directory from denv enter, or ""
+func activationRoot(dir string) string {
+	home, _ := os.Stat(paths.DenvHome())
+	for {
+		if _, err := os.Stat(filepath.Join(dir, config.RepoConfigName)); err == nil {
+			return dir
+		}
+		if info, err := os.Stat(filepath.Join(dir, ".denv")); err == nil && info.IsDir() && (home == nil || !os.SameFile(info, home)) {
+			return dir
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// deactivate restores the variables saved by an activation and releases
+// its session
+func deactivate(a *activation, pid int, changes map[string]*string) {
+	a.unwind(changes)
+	os.Unsetenv(activationVar)
+	changes[activationVar] = nil
+
+	if a.PID == pid && a.Session != "" {
+		releaseSession(a.EnvPath, a.Session, os.Stderr)
+	}
+	fmt.Fprintf(os.Stderr, "denv: left %s:%s\n", a.Project, a.Env)
+}
+
+// unwind restores the variables saved by the activation
+func (a *activation) unwind(changes map[string]*string) {
+	for key, previous := range a.Saved {
+		if previous == nil {
+			os.Unsetenv(key)
+		} else {
+			os.Setenv(key, *previous)
+		}
+		changes[key] = previous
+	}
+}
+
+// reapply sets the variables of the activation again and makes it current
+func (a *activation) reapply(changes map[string]*string) {
+	for key, value := range a.Values {
+		value := value
+		os.Setenv(key, value)
+		changes[key] = &value
+	}
+	encoded := a.encode()
+	os.Setenv(activationVar, encoded)
+	changes[activationVar] = &encoded
+}
+
+// activate opens a session of an environment for the shell and records the
+// variables it sets
+func activate(root, envName string, pid int, changes map[string]*string) error {
+	if err := checkEnvironmentName(envName); err != nil {
+		return err
+	}
+	cfg, _ := config.LoadConfig(filepath.Join(paths.DenvHome(), "config.yaml"))
+	projectName := project.RegisterProjectWithConfig(root, cfg)
+	cfg = withRepoConfig(cfg, root)
+
+	env, runtime, envPath, sessionID, err := openSession(cfg, root, projectName, envName, pid)
+	if err != nil {
+		return err
+	}
+
+	a := &activation{
+		Project: runtime.Project,
+		Env:     runtime.Environment,
+		EnvPath: envPath,
+		Root:    root,
+		Session: sessionID,
+		PID:     pid,
+		Saved:   make(map[string]*string),
+		Values:  make(map[string]string),
+	}
+	for key, value := range env {
+		previous, ok := os.LookupEnv(key)
+		if ok && previous == value {
+			continue
+		}
+		if ok {
+			a.Saved[key] = &previous
+		} else {
+			a.Saved[key] = nil
+		}
+		a.Values[key] = value
+		value := value
+		os.Setenv(key, value)
+		changes[key] = &value
+	}
+
+	fmt.Fprintf(os.Stderr, "denv: entered %s:%s (%d ports)\n", a.Project, a.Env, len(runtime.Ports))
+
+	encoded := a.encode()
+	os.Setenv(activationVar, encoded)
+	changes[activationVar] = &encoded
+	return nil
+}
+
+// openSession prepares an environment like denv enter and registers a
+// session owned by pid, returning the variables of the environment
+func openSession(cfg *config.Config, root, projectName, envName string, pid int) (map[string]string, *environment.Runtime, string, string, error) {
+	envPath := paths.EnvironmentPath(projectName, envName)
+	_ = os.MkdirAll(envPath, 0755)
+	projectPath := paths.ProjectPath(projectName)
+	_ = os.MkdirAll(filepath.Join(projectPath, "hooks"), 0755)
+	_ = createProjectSymlinks(root, envPath, projectPath, projectName, envName)
+
+	runtime, _ := environment.LoadRuntime(envPath)
+	isNew := runtime == nil
+	if isNew {
+		runtime = environment.NewRuntime(projectName, envName)
+	}
+	recordSubproject(runtime, root, cfg)
+	firstSession := countActiveSessions(runtime) == 0
+
+	mapEnvironmentPorts(cfg, runtime, envPath, root)
+
+	sessionHandle := session.CreateSession(envPath, "")
+	if sessionHandle == nil {
+		return nil, nil, "", "", fmt.Errorf("failed to create a session for '%s'", envName)
+	}
+	sessionHandle.Release()
+	runtime.Sessions[sessionHandle.ID] = environment.Session{
+		ID:      sessionHandle.ID,
+		PID:     pid,
+		Started: time.Now(),
+	}
+	runtime.MarkUsed()
+
+	env, overrides := environmentVariables(cfg, runtime, envPath, sessionHandle.ID)
+	runtime.Overrides = overrides
+	_ = environment.SaveRuntime(envPath, runtime)
+
+	// Hook output must not end up in the commands the shell evaluates
+	if isNew {
+		runHooks(hooks.OnCreate, runtime, envPath, sessionHandle.ID, os.Stderr)
+	}
+	if firstSession {
+		runHooks(hooks.OnFirstEnter, runtime, envPath, sessionHandle.ID, os.Stderr)
+	}
+	runHooks(hooks.OnEnter, runtime, envPath, sessionHandle.ID, os.Stderr)
+	if firstSession {
+		if err := startServices(cfg, runtime, envPath, nil, true, os.Stderr); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+
+	return env, runtime, envPath, sessionHandle.ID, nil
+}
+
+// writeChanges prints shell commands applying changes, sorted by name
+func writeChanges(shellType shell.ShellType, changes map[string]*string, w io.Writer) {
+	keys := make([]string, 0, len(changes))
+	for key := range changes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if value := changes[key]; value != nil {
+			fmt.Fprintln(w, shell.ExportCommand(shellType, key, *value))
+		} else {
+			fmt.Fprintln(w, shell.UnsetCommand(shellType, key))
+		}
+	}
+}
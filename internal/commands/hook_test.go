@@ -0,0 +1,149 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/caoer/denv/internal/environment"
+	"github.com/caoer/denv/internal/testutil"
+)
+
+func setupHookProject(t *testing.T) (string, string) {
+	tmpDir, tmpProject := testutil.SetupProject(t, "hooktest")
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpProject, "src", "app"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpProject, ".denv"), 0755))
+
+	unsetenv(t, "DENV_ENV_NAME")
+	unsetenv(t, activationVar)
+	t.Setenv("PORT", "3000")
+	return tmpDir, tmpProject
+}
+
+// unsetenv removes a variable for the duration of a test
+func unsetenv(t *testing.T, key string) {
+	if value, ok := os.LookupEnv(key); ok {
+		t.Cleanup(func() { os.Setenv(key, value) })
+	} else {
+		t.Cleanup(func() { os.Unsetenv(key) })
+	}
+	os.Unsetenv(key)
+}
+
+func TestHookChanges_ActivatesAndRestores(t *testing.T) {
+	tmpDir, tmpProject := setupHookProject(t)
+	pid := os.Getpid()
+	envPath := filepath.Join(tmpDir, "hooktest-default")
+
+	// Test: Entering the project activates its default environment
+	changes, err := hookChanges(filepath.Join(tmpProject, "src"), pid)
+	require.NoError(t, err)
+	require.NotNil(t, changes["DENV_ENV_NAME"])
+	assert.Equal(t, "default", *changes["DENV_ENV_NAME"])
+	require.NotNil(t, changes["PORT"])
+	assert.NotEqual(t, "3000", *changes["PORT"])
+	assert.Equal(t, os.Getenv("PORT"), *changes["PORT"])
+	require.NotNil(t, changes[activationVar])
+
+	// Test: The session belongs to the shell
+	rt, err := environment.LoadRuntime(envPath)
+	require.NoError(t, err)
+	require.Len(t, rt.Sessions, 1)
+	for _, s := range rt.Sessions {
+		assert.Equal(t, pid, s.PID)
+		assert.Equal(t, os.Getenv("DENV_SESSION"), s.ID)
+	}
+
+	// Test: Moving within the project changes nothing
+	changes, err = hookChanges(filepath.Join(tmpProject, "src", "app"), pid)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+
+	// Test: Leaving restores the previous values and releases the session
+	changes, err = hookChanges(t.TempDir(), pid)
+	require.NoError(t, err)
+	require.NotNil(t, changes["PORT"])
+	assert.Equal(t, "3000", *changes["PORT"])
+	assert.Nil(t, changes["DENV_SESSION"])
+	assert.Contains(t, changes, activationVar)
+	assert.Nil(t, changes[activationVar])
+	assert.Equal(t, "3000", os.Getenv("PORT"))
+
+	rt, err = environment.LoadRuntime(envPath)
+	require.NoError(t, err)
+	assert.Empty(t, rt.Sessions)
+}
+
+func TestHookChanges_InheritedActivation(t *testing.T) {
+	tmpDir, tmpProject := setupHookProject(t)
+
+	_, err := hookChanges(tmpProject, os.Getpid())
+	require.NoError(t, err)
+
+	// Test: A child shell leaving the project keeps the parent's session
+	_, err = hookChanges(t.TempDir(), os.Getpid()+100000)
+	require.NoError(t, err)
+	rt, err := environment.LoadRuntime(filepath.Join(tmpDir, "hooktest-default"))
+	require.NoError(t, err)
+	assert.Len(t, rt.Sessions, 1)
+}
+
+func TestHookChanges_InsideEnter(t *testing.T) {
+	_, tmpProject := setupHookProject(t)
+	t.Setenv("DENV_ENV_NAME", "feature")
+
+	// Test: Shells started by denv enter are left alone
+	changes, err := hookChanges(tmpProject, os.Getpid())
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestHookEnv_Output(t *testing.T) {
+	_, tmpProject := setupHookProject(t)
+	_ = os.Chdir(tmpProject)
+
+	var out bytes.Buffer
+	require.NoError(t, HookEnv("fish", os.Getpid(), &out))
+	assert.Contains(t, out.String(), "set -gx DENV_ENV_NAME \"default\";")
+
+	_ = os.Chdir(t.TempDir())
+	out.Reset()
+	require.NoError(t, HookEnv("bash", os.Getpid(), &out))
+	assert.Contains(t, out.String(), "unset DENV_ENV_NAME;")
+	assert.Contains(t, out.String(), "export PORT=\"3000\";")
+	assert.NotContains(t, out.String(), "export HOME=")
+
+	assert.Error(t, HookEnv("tcsh", os.Getpid(), &out))
+}
+
+func TestHookExit_ReleasesPushedSessions(t *testing.T) {
+	tmpDir, tmpProject := setupHookProject(t)
+	unsetenv(t, stackVar)
+	pid := os.Getpid()
+
+	_, err := hookChanges(tmpProject, pid)
+	require.NoError(t, err)
+	_, err = pushChanges(tmpProject, "staging", pid)
+	require.NoError(t, err)
+
+	// Test: Another shell inheriting the activation releases nothing
+	HookExit(pid + 1)
+	rt, err := environment.LoadRuntime(filepath.Join(tmpDir, "hooktest-default"))
+	require.NoError(t, err)
+	assert.Len(t, rt.Sessions, 1)
+
+	// Test: The owning shell releases the current and the suspended session
+	HookExit(pid)
+	for _, env := range []string{"default", "staging"} {
+		rt, err := environment.LoadRuntime(filepath.Join(tmpDir, "hooktest-"+env))
+		require.NoError(t, err)
+		assert.Empty(t, rt.Sessions, env)
+	}
+
+	// HookExit leaves the variables to the exiting shell; restore them
+	_, err = hookChanges(t.TempDir(), pid)
+	require.NoError(t, err)
+}
@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/caoer/denv/internal/config"
+	"github.com/caoer/denv/internal/environment"
+	"github.com/caoer/denv/internal/hooks"
+	"github.com/caoer/denv/internal/paths"
+	"github.com/caoer/denv/internal/project"
+	"github.com/caoer/denv/internal/trust"
+)
+
+// runHooks runs the lifecycle hooks for event from the project's hooks/
+// directory and then the environment's. Failures are reported as warnings.
+func runHooks(event hooks.Event, runtime *environment.Runtime, envPath, sessionID string, stdout io.Writer) {
+	if runtime == nil {
+		return
+	}
+
+	projectPath := paths.ProjectPath(runtime.Project)
+	runner := &hooks.Runner{
+		Dirs:     []string{filepath.Join(projectPath, "hooks"), filepath.Join(envPath, "hooks")},
+		Commands: repoHooks(event, runtime.Project),
+		Timeout:  hookTimeout(),
+		Stdout:   stdout,
+		Stderr:   os.Stderr,
+	}
+
+	errs := runner.Run(hooks.Context{
+		Event:       event,
+		Project:     runtime.Project,
+		Environment: runtime.Environment,
+		EnvPath:     envPath,
+		ProjectPath: projectPath,
+		Session:     sessionID,
+		Ports:       runtime.Ports,
+	})
+	for _, err := range errs {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// repoHooks returns the approved hook commands for event from the current
+// repository's config, if that repository is the environment's project
+func repoHooks(event hooks.Event, projectName string) map[hooks.Event][]string {
+	cwd, _ := os.Getwd()
+	repoCfg, path, status := loadRepoConfig(cwd)
+	if repoCfg == nil || len(repoCfg.Hooks[string(event)]) == 0 {
+		return nil
+	}
+
+	cfg, _ := config.LoadConfig(filepath.Join(paths.DenvHome(), "config.yaml"))
+	if project.DetectProjectWithConfig(cwd, cfg) != projectName {
+		return nil
+	}
+
+	if status != trust.Allowed {
+		fmt.Fprintf(os.Stderr, "Warning: skipping %s hooks: %s\n", event, blockedMessage(path, status))
+		return nil
+	}
+	return map[hooks.Event][]string{event: repoCfg.Hooks[string(event)]}
+}
+
+// hookTimeout returns the configured hook timeout
+func hookTimeout() time.Duration {
+	cfg, _ := config.LoadConfig(filepath.Join(paths.DenvHome(), "config.yaml"))
+	if cfg == nil || cfg.HookTimeout == "" {
+		return hooks.DefaultTimeout
+	}
+	d, err := time.ParseDuration(cfg.HookTimeout)
+	if err != nil || d <= 0 {
+		fmt.Fprintf(os.Stderr, "Warning: invalid hook_timeout %q, using %s\n", cfg.HookTimeout, hooks.DefaultTimeout)
+		return hooks.DefaultTimeout
+	}
+	return d
+}
@@ -0,0 +1,90 @@
+package commands
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/caoer/denv/internal/config"
+	"github.com/caoer/denv/internal/testutil"
+)
+
+func setupHooksProject(t *testing.T) (string, string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell hooks")
+	}
+
+	tmpDir, _ := testutil.SetupProject(t, "hooktest")
+
+	// Every hook appends its event and environment to a shared log
+	logPath := filepath.Join(tmpDir, "hooks.log")
+	hooksDir := filepath.Join(tmpDir, "hooktest", "hooks")
+	require.NoError(t, os.MkdirAll(hooksDir, 0755))
+	for _, event := range []string{"on-create", "on-first-enter", "on-enter", "on-destroy"} {
+		script := "#!/bin/sh\necho \"$DENV_HOOK_EVENT $DENV_ENV_NAME\" >> " + logPath + "\n"
+		require.NoError(t, os.WriteFile(filepath.Join(hooksDir, event), []byte(script), 0755))
+	}
+	return tmpDir, logPath
+}
+
+func readHookLog(t *testing.T, logPath string) []string {
+	data, err := os.ReadFile(logPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	require.NoError(t, err)
+	_ = os.Remove(logPath)
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestHooks_Lifecycle(t *testing.T) {
+	tmpDir, logPath := setupHooksProject(t)
+
+	// Test: Creating an environment runs on-create once
+	require.NoError(t, Enter("dev"))
+	assert.Equal(t, []string{"on-create dev", "on-first-enter dev", "on-enter dev"}, readHookLog(t, logPath))
+
+	require.NoError(t, Enter("dev"))
+	assert.Equal(t, []string{"on-first-enter dev", "on-enter dev"}, readHookLog(t, logPath))
+
+	// Test: A clone is a new environment
+	require.NoError(t, Clone("dev", "copy", false))
+	assert.Equal(t, []string{"on-create copy"}, readHookLog(t, logPath))
+
+	// Test: Removing keeps the environment intact in the trash
+	require.NoError(t, Rm("copy", false, true))
+	assert.Nil(t, readHookLog(t, logPath))
+
+	// Test: Purging expired trash runs on-destroy
+	trash := filepath.Join(tmpDir, ".trash")
+	entries, err := os.ReadDir(trash)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	expired := time.Now().Add(-30 * 24 * time.Hour).Format(trashTimeFormat)
+	require.NoError(t, os.Rename(filepath.Join(trash, entries[0].Name()), filepath.Join(trash, expired)))
+	purgeExpiredTrash(&config.Config{}, io.Discard)
+	assert.Equal(t, []string{"on-destroy copy"}, readHookLog(t, logPath))
+	assert.NoDirExists(t, filepath.Join(trash, expired))
+}
+
+func TestHooks_EnvironmentHooksAndFailures(t *testing.T) {
+	tmpDir, logPath := setupHooksProject(t)
+
+	require.NoError(t, Enter("dev"))
+	readHookLog(t, logPath)
+
+	// Environment hooks run after project hooks; failures don't abort
+	envHooks := filepath.Join(tmpDir, "hooktest-dev", "hooks")
+	require.NoError(t, os.MkdirAll(envHooks, 0755))
+	script := "#!/bin/sh\necho \"env $DENV_HOOK_EVENT\" >> " + logPath + "\nexit 1\n"
+	require.NoError(t, os.WriteFile(filepath.Join(envHooks, "on-enter"), []byte(script), 0755))
+
+	require.NoError(t, Enter("dev"))
+	assert.Equal(t, []string{"on-first-enter dev", "on-enter dev", "env on-enter"}, readHookLog(t, logPath))
+}
@@ -40,15 +40,9 @@ func ListEnvironments() ([]EnvironmentInfo, error) {
 		}
 		
 		name := entry.Name()
-		// Parse project-environment pattern
-		parts := strings.SplitN(name, "-", 2)
-		if len(parts) == 2 {
-			project := parts[0]
-			env := parts[1]
-			
-			envPath := filepath.Join(denvHome, name)
-			runtime, _ := environment.LoadRuntime(envPath)
-			
+		envPath := filepath.Join(denvHome, name)
+		runtime, _ := environment.LoadRuntime(envPath)
+		if project, env, ok := environmentDir(name, runtime); ok {
 			sessionCount := 0
 			portCount := 0
 			if runtime != nil {
@@ -107,16 +101,10 @@ func ListPlain(w io.Writer) error {
 		}
 
 		name := entry.Name()
-		// Parse project-environment pattern
-		parts := strings.SplitN(name, "-", 2)
-		if len(parts) == 2 {
-			project := parts[0]
-			envName := parts[1]
-
-			// Load runtime to get session and port info
-			envPath := filepath.Join(denvHome, name)
-			runtime, _ := environment.LoadRuntime(envPath)
-
+		// Load runtime to get the names, session and port info
+		envPath := filepath.Join(denvHome, name)
+		runtime, _ := environment.LoadRuntime(envPath)
+		if project, envName, ok := environmentDir(name, runtime); ok {
 			sessionCount := 0
 			portCount := 0
 			status := "inactive"
@@ -171,22 +159,17 @@ func ListPretty() error {
 
 	// Group environments by project
 	projectEnvs := make(map[string][]ui.EnvInfo)
+	subprojects := make(map[string]bool)
 	for _, entry := range entries {
 		if !entry.IsDir() {
 			continue
 		}
 		
 		name := entry.Name()
-		// Parse project-environment pattern
-		parts := strings.SplitN(name, "-", 2)
-		if len(parts) == 2 {
-			project := parts[0]
-			envName := parts[1]
-			
-			// Load runtime to get session and port info
-			envPath := filepath.Join(denvHome, name)
-			runtime, _ := environment.LoadRuntime(envPath)
-			
+		// Load runtime to get the names, session and port info
+		envPath := filepath.Join(denvHome, name)
+		runtime, _ := environment.LoadRuntime(envPath)
+		if project, envName, ok := environmentDir(name, runtime); ok {
 			sessionCount := 0
 			portCount := 0
 			active := false
@@ -208,14 +191,28 @@ func ListPretty() error {
 				Sessions: sessionCount,
 				Ports:    portCount,
 			}
+
+			// Sub-projects are listed under their repository's project
+			if runtime != nil && runtime.Parent != "" {
+				envInfo.Subproject = runtime.Subproject
+				subprojects[project] = true
+				project = runtime.Parent
+			}
 			
 			projectEnvs[project] = append(projectEnvs[project], envInfo)
-		} else if name != "" && !strings.HasPrefix(name, ".") {
+		} else if _, seen := projectEnvs[name]; !seen && name != "" && !strings.HasPrefix(name, ".") {
 			// Standalone project directory
 			projectEnvs[name] = []ui.EnvInfo{}
 		}
 	}
 
+	// A sub-project's own directory is shown with its parent
+	for alias := range subprojects {
+		if len(projectEnvs[alias]) == 0 {
+			delete(projectEnvs, alias)
+		}
+	}
+
 	if len(projectEnvs) == 0 {
 		fmt.Println("No denv environments found")
 		return nil
@@ -226,6 +223,20 @@ func ListPretty() error {
 	return nil
 }
 
+// environmentDir returns the project and environment of the directory
+// name in DENV_HOME, preferring those recorded in its runtime: splitting
+// the name is ambiguous for project names with a dash
+func environmentDir(name string, runtime *environment.Runtime) (project, env string, ok bool) {
+	if runtime != nil && runtime.Project != "" && runtime.Environment != "" {
+		return runtime.Project, runtime.Environment, true
+	}
+	parts := strings.SplitN(name, "-", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
 func sessionExists(pid int) bool {
 	process, err := os.FindProcess(pid)
 	if err != nil {
@@ -153,4 +153,32 @@ func TestListPlainFormat(t *testing.T) {
 		output := strings.TrimSpace(buf.String())
 		assert.Empty(t, output, "Plain format should output nothing when no environments exist")
 	})
-}
\ No newline at end of file
+}
+
+func TestListPlainUsesRuntimeNames(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("DENV_HOME", tempDir)
+
+	// Project names may hold dashes; the runtime knows where they end
+	runtimes := map[string]*environment.Runtime{
+		"my-app-dev":                      environment.NewRuntime("my-app", "dev"),
+		"monorepo.services.billing-local": environment.NewRuntime("monorepo.services.billing", "local"),
+	}
+	runtimes["monorepo.services.billing-local"].Parent = "monorepo"
+	runtimes["monorepo.services.billing-local"].Subproject = "services/billing"
+	for name, runtime := range runtimes {
+		envDir := filepath.Join(tempDir, name)
+		require.NoError(t, os.MkdirAll(envDir, 0755))
+		require.NoError(t, environment.SaveRuntime(envDir, runtime))
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, ListPlain(&buf))
+	assert.Equal(t, "monorepo.services.billing\tlocal\tinactive\t0\t0\nmy-app\tdev\tinactive\t0\t0\n", buf.String())
+
+	envs, err := ListEnvironments()
+	require.NoError(t, err)
+	require.Len(t, envs, 2)
+	projects := []string{envs[0].Project, envs[1].Project}
+	assert.ElementsMatch(t, []string{"my-app", "monorepo.services.billing"}, projects)
+}
@@ -0,0 +1,79 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/caoer/denv/internal/config"
+	"github.com/caoer/denv/internal/environment"
+	"github.com/caoer/denv/internal/paths"
+	"github.com/caoer/denv/internal/project"
+)
+
+// Mv renames an environment, keeping its state, ports and symlinks
+func Mv(oldName, newName string) error {
+	if oldName == "" || newName == "" {
+		return fmt.Errorf("old and new environment names required")
+	}
+	if oldName == newName {
+		return fmt.Errorf("old and new names must be different")
+	}
+	if err := checkEnvironmentName(newName); err != nil {
+		return err
+	}
+
+	// Detect project (respecting config overrides like Enter does)
+	cwd, _ := os.Getwd()
+	cfg, _ := config.LoadConfig(filepath.Join(paths.DenvHome(), "config.yaml"))
+	projectName := project.DetectProjectWithConfig(cwd, cfg)
+
+	oldPath := paths.EnvironmentPath(projectName, oldName)
+	newPath := paths.EnvironmentPath(projectName, newName)
+
+	if _, err := os.Stat(oldPath); os.IsNotExist(err) {
+		return fmt.Errorf("environment '%s' does not exist", oldName)
+	}
+	if _, err := os.Stat(newPath); err == nil {
+		return fmt.Errorf("environment '%s' already exists", newName)
+	}
+
+	runtime, err := environment.LoadRuntime(oldPath)
+	if err != nil {
+		return fmt.Errorf("failed to load environment '%s': %w", oldName, err)
+	}
+	if active := countActiveSessions(runtime); active > 0 {
+		return fmt.Errorf("cannot rename environment with %d active session(s)", active)
+	}
+	if running := runningServices(oldPath); len(running) > 0 {
+		return fmt.Errorf("cannot rename environment with running services: %s (run 'denv down' first)", strings.Join(running, ", "))
+	}
+
+	// Port reservations (ports.json) live in the directory and move with it
+	if err := os.Rename(oldPath, newPath); err != nil {
+		return fmt.Errorf("failed to rename environment: %w", err)
+	}
+
+	if runtime != nil {
+		runtime.Environment = newName
+		runtime.Overrides = relocateOverrides(runtime.Overrides, oldPath, newPath, runtime.Ports)
+		if err := environment.SaveRuntime(newPath, runtime); err != nil {
+			return fmt.Errorf("failed to save runtime: %w", err)
+		}
+	}
+
+	// Point the project's .denv symlink at the new location
+	denvDir := filepath.Join(cwd, ".denv")
+	oldLink := filepath.Join(denvDir, fmt.Sprintf("*%s-%s", projectName, oldName))
+	if info, err := os.Lstat(oldLink); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		_ = os.Remove(oldLink)
+		newLink := filepath.Join(denvDir, fmt.Sprintf("*%s-%s", projectName, newName))
+		if err := os.Symlink(newPath, newLink); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to update symlink: %v\n", err)
+		}
+	}
+
+	fmt.Printf("Renamed environment '%s' to '%s' for project %s\n", oldName, newName, projectName)
+	return nil
+}
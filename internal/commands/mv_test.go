@@ -0,0 +1,77 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/caoer/denv/internal/environment"
+	"github.com/caoer/denv/internal/testutil"
+)
+
+func TestMv_RenamesEnvironment(t *testing.T) {
+	tmpDir, tmpProject := testutil.SetupProject(t, "mvtest")
+
+	os.Setenv("API_PORT", "3000")
+	os.Setenv("CACHE_DIR", "/tmp/cache")
+	defer os.Unsetenv("API_PORT")
+	defer os.Unsetenv("CACHE_DIR")
+
+	require.NoError(t, Enter("tmp"))
+	oldPath := filepath.Join(tmpDir, "mvtest-tmp")
+	require.NoError(t, os.WriteFile(filepath.Join(oldPath, "state"), []byte("useful"), 0644))
+	before, err := environment.LoadRuntime(oldPath)
+	require.NoError(t, err)
+
+	// Test: Rename keeps state and ports
+	require.NoError(t, Mv("tmp", "payments"))
+
+	newPath := filepath.Join(tmpDir, "mvtest-payments")
+	assert.NoDirExists(t, oldPath)
+	data, err := os.ReadFile(filepath.Join(newPath, "state"))
+	require.NoError(t, err)
+	assert.Equal(t, "useful", string(data))
+
+	after, err := environment.LoadRuntime(newPath)
+	require.NoError(t, err)
+	assert.Equal(t, "payments", after.Environment)
+	assert.Equal(t, before.Ports, after.Ports)
+	assert.Equal(t, filepath.Join(newPath, "cache"), after.Overrides["CACHE_DIR"].Current)
+	assert.FileExists(t, filepath.Join(newPath, "ports.json"))
+
+	// Test: The project symlink follows the rename
+	_, err = os.Lstat(filepath.Join(tmpProject, ".denv", "*mvtest-tmp"))
+	assert.True(t, os.IsNotExist(err))
+	target, err := os.Readlink(filepath.Join(tmpProject, ".denv", "*mvtest-payments"))
+	require.NoError(t, err)
+	assert.Equal(t, newPath, target)
+}
+
+func TestMv_RefusesActiveSessions(t *testing.T) {
+	tmpDir, _ := testutil.SetupProject(t, "mvtest")
+
+	require.NoError(t, Enter("tmp"))
+	envPath := filepath.Join(tmpDir, "mvtest-tmp")
+	runtime, err := environment.LoadRuntime(envPath)
+	require.NoError(t, err)
+	runtime.Sessions["active"] = environment.Session{ID: "active", PID: os.Getpid()}
+	require.NoError(t, environment.SaveRuntime(envPath, runtime))
+
+	err = Mv("tmp", "payments")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "active session")
+	assert.DirExists(t, envPath)
+}
+
+func TestMv_TargetExists(t *testing.T) {
+	testutil.SetupProject(t, "mvtest")
+
+	require.NoError(t, Enter("tmp"))
+	require.NoError(t, Enter("payments"))
+
+	err := Mv("tmp", "payments")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "already exists")
+}
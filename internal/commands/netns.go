@@ -0,0 +1,90 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/caoer/denv/internal/config"
+	"github.com/caoer/denv/internal/environment"
+	"github.com/caoer/denv/internal/netns"
+)
+
+// netnsIsolation reports whether denv enter should run the shell in its own
+// network namespace, warning when the configured mode can't be used
+func netnsIsolation(cfg *config.Config) bool {
+	if cfg == nil {
+		return false
+	}
+	switch cfg.Isolation {
+	case "", config.IsolationRemap:
+		return false
+	case config.IsolationNetns:
+	default:
+		fmt.Fprintf(os.Stderr, "Warning: unknown isolation mode '%s', using port remapping\n", cfg.Isolation)
+		return false
+	}
+
+	if err := netns.Available(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: netns isolation unavailable (%v); falling back to port remapping\n", err)
+		return false
+	}
+	return true
+}
+
+// checkNetnsServices refuses autostarted services under netns isolation:
+// they run on the host, out of reach of the namespaced shell
+func checkNetnsServices(cfg *config.Config) error {
+	var names []string
+	for name, svc := range cfg.Services {
+		if svc.Autostart {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+	return fmt.Errorf("autostarted services (%s) run on the host and can't be reached from a netns shell; "+
+		"disable their autostart or use isolation: %s", strings.Join(names, ", "), config.IsolationRemap)
+}
+
+// namespacedRuntime returns a copy of runtime whose ports map to
+// themselves: inside the namespace the original ports are used as-is
+func namespacedRuntime(runtime *environment.Runtime) *environment.Runtime {
+	rt := *runtime
+	rt.Ports = make(map[int]int, len(runtime.Ports))
+	for orig := range runtime.Ports {
+		rt.Ports[orig] = orig
+	}
+	return &rt
+}
+
+// runShell runs the entered shell with shellEnv added to its environment,
+// inside a network namespace whose original ports are reachable on the
+// host's mapped ports if useNetns is set
+func runShell(shellArgs, shellEnv []string, runtime *environment.Runtime, useNetns bool) error {
+	env := append(os.Environ(), shellEnv...)
+	if useNetns {
+		sess, err := netns.Start(shellArgs, env, runtime.Ports, os.Stdin, os.Stdout, os.Stderr)
+		if err != nil {
+			return fmt.Errorf("failed to start network namespace: %w", err)
+		}
+		return sess.Wait()
+	}
+
+	cmd := exec.Command(shellArgs[0], shellArgs[1:]...)
+	cmd.Env = env
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// NetnsExec runs a command inside the network namespace created by
+// denv enter and returns its exit code
+func NetnsExec(args []string) (int, error) {
+	return netns.Helper(args)
+}
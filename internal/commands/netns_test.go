@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/caoer/denv/internal/config"
+	"github.com/caoer/denv/internal/environment"
+)
+
+func TestNamespacedRuntime(t *testing.T) {
+	rt := environment.NewRuntime("myapp", "feature")
+	rt.Ports[3000] = 33000
+	rt.Ports[5432] = 35432
+
+	ns := namespacedRuntime(rt)
+	assert.Equal(t, map[int]int{3000: 3000, 5432: 5432}, ns.Ports)
+	assert.Equal(t, "feature", ns.Environment)
+
+	// Test: The host mapping is untouched
+	assert.Equal(t, 33000, rt.Ports[3000])
+
+	// Test: Port variables keep their original values inside the namespace
+	t.Setenv("PORT", "3000")
+	t.Setenv("DATABASE_URL", "postgres://localhost:5432/app")
+	env, _ := environmentVariables(&config.Config{Patterns: config.GetDefaultPatterns()}, ns, t.TempDir(), "")
+	assert.Equal(t, "3000", env["PORT"])
+	assert.Equal(t, "postgres://localhost:5432/app", env["DATABASE_URL"])
+	assert.Equal(t, "3000", env["PORT_3000"])
+}
+
+func TestNetnsIsolation_Remap(t *testing.T) {
+	assert.False(t, netnsIsolation(nil))
+	assert.False(t, netnsIsolation(&config.Config{}))
+	assert.False(t, netnsIsolation(&config.Config{Isolation: config.IsolationRemap}))
+	assert.False(t, netnsIsolation(&config.Config{Isolation: "bogus"}))
+}
+
+func TestCheckNetnsServices(t *testing.T) {
+	cfg := &config.Config{Services: map[string]config.Service{
+		"worker": {Command: "worker"},
+	}}
+	assert.NoError(t, checkNetnsServices(cfg))
+
+	// Test: Autostarted services would be out of the shell's reach
+	cfg.Services["redis"] = config.Service{Command: "redis-server", Autostart: true}
+	cfg.Services["db"] = config.Service{Command: "postgres", Autostart: true}
+	err := checkNetnsServices(cfg)
+	assert.ErrorContains(t, err, "(db, redis)")
+}
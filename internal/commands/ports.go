@@ -0,0 +1,231 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/caoer/denv/internal/environment"
+	"github.com/caoer/denv/internal/netstat"
+	"github.com/caoer/denv/internal/paths"
+)
+
+// envRuntime is an environment found in DENV_HOME
+type envRuntime struct {
+	path    string
+	runtime *environment.Runtime
+}
+
+func (e envRuntime) label() string {
+	return e.runtime.Project + ":" + e.runtime.Environment
+}
+
+// allEnvironments returns every environment with a runtime, sorted by
+// project and name
+func allEnvironments() []envRuntime {
+	var envs []envRuntime
+
+	entries, _ := os.ReadDir(paths.DenvHome())
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		envPath := filepath.Join(paths.DenvHome(), entry.Name())
+		runtime, err := environment.LoadRuntime(envPath)
+		if err != nil || runtime == nil {
+			continue
+		}
+		envs = append(envs, envRuntime{path: envPath, runtime: runtime})
+	}
+
+	sort.Slice(envs, func(i, j int) bool {
+		return envs[i].label() < envs[j].label()
+	})
+	return envs
+}
+
+// Ports shows what is actually listening on the ports of an environment
+// (or of every environment with all) and flags processes that ignore the
+// remap or collide with another environment
+func Ports(envName string, all bool, w io.Writer) error {
+	var envs []envRuntime
+	if all {
+		envs = allEnvironments()
+		if len(envs) == 0 {
+			fmt.Fprintln(w, "No environments found")
+			return nil
+		}
+	} else {
+		_, runtime, envPath, err := loadTargetEnvironment(envName)
+		if err != nil {
+			return err
+		}
+		envs = []envRuntime{{path: envPath, runtime: runtime}}
+	}
+
+	listeners, err := netstat.Listening()
+	if err != nil {
+		return err
+	}
+	inspector := newPortInspector(listeners)
+
+	for i, env := range envs {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		if err := inspector.show(env, w); err != nil {
+			return err
+		}
+	}
+
+	if all {
+		if conflicts := mappingConflicts(envs); len(conflicts) > 0 {
+			fmt.Fprintln(w, "\nConflicts:")
+			for _, c := range conflicts {
+				fmt.Fprintf(w, "  ⚠️  %s\n", c)
+			}
+		}
+	}
+	return nil
+}
+
+// portInspector answers which process, and which environment, listens on
+// a port
+type portInspector struct {
+	byPort map[int][]netstat.Listener
+	envs   map[int]string
+}
+
+func newPortInspector(listeners []netstat.Listener) *portInspector {
+	p := &portInspector{
+		byPort: make(map[int][]netstat.Listener),
+		envs:   make(map[int]string),
+	}
+	for _, l := range listeners {
+		// The same process often listens on both IPv4 and IPv6
+		dup := false
+		for _, seen := range p.byPort[l.Port] {
+			if seen.PID == l.PID {
+				dup = true
+			}
+		}
+		if !dup {
+			p.byPort[l.Port] = append(p.byPort[l.Port], l)
+		}
+	}
+	return p
+}
+
+// environmentOf returns the project:environment a process runs in, or ""
+func (p *portInspector) environmentOf(pid int) string {
+	if pid == 0 {
+		return ""
+	}
+	if label, ok := p.envs[pid]; ok {
+		return label
+	}
+	label := ""
+	env := netstat.ProcessEnv(pid)
+	if env["DENV_PROJECT_NAME"] != "" && env["DENV_ENV_NAME"] != "" {
+		label = env["DENV_PROJECT_NAME"] + ":" + env["DENV_ENV_NAME"]
+	}
+	p.envs[pid] = label
+	return label
+}
+
+func (p *portInspector) show(env envRuntime, w io.Writer) error {
+	label := env.label()
+	fmt.Fprintf(w, "📡 %s\n", label)
+	if len(env.runtime.Ports) == 0 {
+		fmt.Fprintln(w, "  No ports mapped")
+		return nil
+	}
+
+	var origs []int
+	for orig := range env.runtime.Ports {
+		origs = append(origs, orig)
+	}
+	sort.Ints(origs)
+
+	var warnings []string
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  ORIGINAL\tMAPPED\tSTATE\tPROCESS\tENVIRONMENT")
+	for _, orig := range origs {
+		mapped := env.runtime.Ports[orig]
+
+		listeners := p.byPort[mapped]
+		if len(listeners) == 0 {
+			fmt.Fprintf(tw, "  %d\t%d\t○ idle\t-\t-\n", orig, mapped)
+		}
+		for _, l := range listeners {
+			owner := p.environmentOf(l.PID)
+			fmt.Fprintf(tw, "  %d\t%d\t● listening\t%s\t%s\n", orig, mapped, describeProcess(l), orDash(owner))
+			if owner != "" && owner != label {
+				warnings = append(warnings, fmt.Sprintf("port %d is held by %s from %s", mapped, describeProcess(l), owner))
+			}
+		}
+
+		for _, l := range p.byPort[orig] {
+			if p.environmentOf(l.PID) == label {
+				warnings = append(warnings, fmt.Sprintf("%s listens on original port %d instead of %d", describeProcess(l), orig, mapped))
+			}
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, warning := range warnings {
+		fmt.Fprintf(w, "  ⚠️  %s\n", warning)
+	}
+	return nil
+}
+
+// mappingConflicts reports mapped ports assigned to more than one
+// environment
+func mappingConflicts(envs []envRuntime) []string {
+	owners := make(map[int][]string)
+	for _, env := range envs {
+		for orig, mapped := range env.runtime.Ports {
+			owners[mapped] = append(owners[mapped], fmt.Sprintf("%s (%d)", env.label(), orig))
+		}
+	}
+
+	var ports []int
+	for port, names := range owners {
+		if len(names) > 1 {
+			ports = append(ports, port)
+		}
+	}
+	sort.Ints(ports)
+
+	var conflicts []string
+	for _, port := range ports {
+		names := owners[port]
+		sort.Strings(names)
+		conflicts = append(conflicts, fmt.Sprintf("port %d is mapped by %s", port, strings.Join(names, ", ")))
+	}
+	return conflicts
+}
+
+func describeProcess(l netstat.Listener) string {
+	if l.PID == 0 {
+		return "unknown process"
+	}
+	if l.Command == "" {
+		return "pid " + strconv.Itoa(l.PID)
+	}
+	return l.Command + " (" + strconv.Itoa(l.PID) + ")"
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,93 @@
+//go:build linux
+
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/caoer/denv/internal/environment"
+)
+
+// TestPortsHelperProcess listens on a port as a process of an environment
+func TestPortsHelperProcess(t *testing.T) {
+	port := os.Getenv("DENV_PORTS_LISTEN")
+	if port == "" {
+		return
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:"+port)
+	if err != nil {
+		os.Exit(1)
+	}
+	defer ln.Close()
+	os.Stdout.WriteString("ready\n")
+	// Listen until the test closes stdin
+	_, _ = bufio.NewReader(os.Stdin).ReadString('\n')
+	os.Exit(0)
+}
+
+func addPortsEnvironment(t *testing.T, tmpDir, env string, ports map[int]int) {
+	rt := environment.NewRuntime("portstest", env)
+	rt.Ports = ports
+	envPath := filepath.Join(tmpDir, "portstest-"+env)
+	require.NoError(t, os.MkdirAll(envPath, 0755))
+	require.NoError(t, environment.SaveRuntime(envPath, rt))
+}
+
+func TestPorts_All(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.Setenv("DENV_HOME", tmpDir)
+
+	// A mapped port this test listens on
+	mapped, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer mapped.Close()
+	mappedPort := mapped.Addr().(*net.TCPAddr).Port
+
+	orig := freePort(t)
+	idle := freePort(t)
+	addPortsEnvironment(t, tmpDir, "feature", map[int]int{orig: mappedPort})
+	addPortsEnvironment(t, tmpDir, "other", map[int]int{orig + 1: mappedPort, 5432: idle})
+
+	// A process of the feature environment listening on the original port
+	cmd := exec.Command(os.Args[0], "-test.run=^TestPortsHelperProcess$")
+	cmd.Env = append(os.Environ(),
+		"DENV_PORTS_LISTEN="+strconv.Itoa(orig),
+		"DENV_PROJECT_NAME=portstest",
+		"DENV_ENV_NAME=feature")
+	stdin, err := cmd.StdinPipe()
+	require.NoError(t, err)
+	stdout, err := cmd.StdoutPipe()
+	require.NoError(t, err)
+	require.NoError(t, cmd.Start())
+	defer func() {
+		stdin.Close()
+		_ = cmd.Wait()
+	}()
+	line, _ := bufio.NewReader(stdout).ReadString('\n')
+	require.Equal(t, "ready\n", line)
+
+	var out bytes.Buffer
+	require.NoError(t, Ports("", true, &out))
+	output := out.String()
+
+	assert.Contains(t, output, "📡 portstest:feature")
+	assert.Contains(t, output, "📡 portstest:other")
+	assert.Contains(t, output, "● listening")
+	assert.Contains(t, output, "○ idle")
+	assert.Contains(t, output, "("+strconv.Itoa(os.Getpid())+")")
+
+	// Test: The app ignoring the remap is flagged
+	assert.Contains(t, output, "("+strconv.Itoa(cmd.Process.Pid)+") listens on original port "+strconv.Itoa(orig)+" instead of "+strconv.Itoa(mappedPort))
+
+	// Test: Ports mapped by two environments are reported
+	assert.Contains(t, output, "port "+strconv.Itoa(mappedPort)+" is mapped by portstest:feature")
+}
@@ -3,6 +3,7 @@ package commands
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -11,9 +12,9 @@ import (
 
 	"github.com/caoer/denv/internal/config"
 	"github.com/caoer/denv/internal/environment"
+	"github.com/caoer/denv/internal/hooks"
 	"github.com/caoer/denv/internal/override"
 	"github.com/caoer/denv/internal/paths"
-	"github.com/caoer/denv/internal/ports"
 	"github.com/caoer/denv/internal/project"
 	"github.com/caoer/denv/internal/session"
 )
@@ -34,6 +35,9 @@ func PrepareEnv(envName string) error {
 	if envName == "" {
 		envName = "default"
 	}
+	if err := checkEnvironmentName(envName); err != nil {
+		return err
+	}
 
 	// Detect project
 	cwd, _ := os.Getwd()
@@ -44,7 +48,10 @@ func PrepareEnv(envName string) error {
 
 	// Check for project override
 	cfg, _ := config.LoadConfig(filepath.Join(paths.DenvHome(), "config.yaml"))
-	projectName := project.DetectProjectWithConfig(cwd, cfg)
+	projectName := project.RegisterProjectWithConfig(cwd, cfg)
+
+	// Patterns and services from an approved .denv.yaml
+	cfg = withRepoConfig(cfg, cwd)
 
 	// Create environment path
 	envPath := paths.EnvironmentPath(projectName, envName)
@@ -60,40 +67,17 @@ func PrepareEnv(envName string) error {
 
 	// Load or create runtime
 	runtime, _ := environment.LoadRuntime(envPath)
-	if runtime == nil {
+	isNew := runtime == nil
+	if isNew {
 		runtime = environment.NewRuntime(projectName, envName)
 	}
+	recordSubproject(runtime, cwd, cfg)
+	firstSession := countActiveSessions(runtime) == 0
 
-	// Setup port manager and initialize with existing runtime ports
-	pm := ports.NewPortManager(envPath)
-	
-	// Initialize port manager with existing runtime ports to respect them
-	if len(runtime.Ports) > 0 {
-		pm.InitializeWithPorts(runtime.Ports)
-	}
-	
-	// Reload config for pattern matching (already loaded above)
-	if cfg == nil {
-		cfgPath := filepath.Join(paths.DenvHome(), "config.yaml")
-		cfg, _ = config.LoadConfig(cfgPath)
-	}
-	
-	// Collect ports that are actually used by environment variables
-	usedPorts := collectUsedPorts(os.Environ(), cfg)
+	// Map the ports the environment uses, keeping existing mappings
 	portMappings := make(map[string]string)
-	
-	for port := range usedPorts {
-		// Check if we already have a mapping in runtime
-		if existingPort, exists := runtime.Ports[port]; exists {
-			// Use existing mapping
-			runtime.Ports[port] = existingPort
-			portMappings[strconv.Itoa(port)] = strconv.Itoa(existingPort)
-		} else {
-			// Get new mapping from port manager
-			mappedPort := pm.GetPort(port)
-			runtime.Ports[port] = mappedPort
-			portMappings[strconv.Itoa(port)] = strconv.Itoa(mappedPort)
-		}
+	for port := range mapEnvironmentPorts(cfg, runtime, envPath, cwd) {
+		portMappings[strconv.Itoa(port)] = strconv.Itoa(runtime.Ports[port])
 	}
 
 	// Create session
@@ -104,6 +88,9 @@ func PrepareEnv(envName string) error {
 		Started: time.Now(),
 	}
 
+	// Record usage for age-based garbage collection
+	runtime.MarkUsed()
+
 	// Save runtime
 	_ = environment.SaveRuntime(envPath, runtime)
 
@@ -117,6 +104,20 @@ func PrepareEnv(envName string) error {
 	}
 	overrides, _ := override.ApplyRules(envMap, cfg, runtime.Ports, envPath)
 
+	// Hook output must not end up in the JSON response on stdout
+	if isNew {
+		runHooks(hooks.OnCreate, runtime, envPath, sessionHandle.ID, os.Stderr)
+	}
+	if firstSession {
+		runHooks(hooks.OnFirstEnter, runtime, envPath, sessionHandle.ID, os.Stderr)
+	}
+	runHooks(hooks.OnEnter, runtime, envPath, sessionHandle.ID, os.Stderr)
+	if firstSession {
+		if err := startServices(cfg, runtime, envPath, nil, true, os.Stderr); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+
 	// Create response
 	response := PrepareEnvResponse{
 		EnvPath:     envPath,
@@ -151,6 +152,9 @@ func GetEnvOverrides(envName string) error {
 	cfg, _ := config.LoadConfig(filepath.Join(paths.DenvHome(), "config.yaml"))
 	projectName := project.DetectProjectWithConfig(cwd, cfg)
 
+	// Patterns and services from an approved .denv.yaml
+	cfg = withRepoConfig(cfg, cwd)
+
 	// Load runtime
 	envPath := paths.EnvironmentPath(projectName, envName)
 	runtime, err := environment.LoadRuntime(envPath)
@@ -193,15 +197,7 @@ func CleanupSession(sessionID string) error {
 		if info.IsDir() && info.Name() == "sessions" {
 			lockFile := filepath.Join(path, sessionID+".lock")
 			if _, err := os.Stat(lockFile); err == nil {
-				// Found the lock file, remove it
-				os.Remove(lockFile)
-				
-				// Also update the runtime to remove the session
-				envPath := filepath.Dir(path)
-				if runtime, err := environment.LoadRuntime(envPath); err == nil {
-					delete(runtime.Sessions, sessionID)
-					_ = environment.SaveRuntime(envPath, runtime)
-				}
+				releaseSession(filepath.Dir(path), sessionID, os.Stdout)
 			}
 		}
 		
@@ -211,6 +207,25 @@ func CleanupSession(sessionID string) error {
 	return err
 }
 
+// releaseSession removes a session's lock and runtime entry, running the
+// exit hooks and stopping autostarted services after the last session
+func releaseSession(envPath, sessionID string, w io.Writer) {
+	os.Remove(filepath.Join(envPath, "sessions", sessionID+".lock"))
+
+	runtime, err := environment.LoadRuntime(envPath)
+	if err != nil || runtime == nil {
+		return
+	}
+	delete(runtime.Sessions, sessionID)
+	_ = environment.SaveRuntime(envPath, runtime)
+
+	runHooks(hooks.OnExit, runtime, envPath, sessionID, w)
+	if countActiveSessions(runtime) == 0 {
+		runHooks(hooks.OnLastExit, runtime, envPath, sessionID, w)
+		_ = stopServices(envPath, nil, true, w)
+	}
+}
+
 // Helper function for escaping shell values
 func escapeShellValue(value string) string {
 	// Escape special characters for shell
@@ -27,6 +27,19 @@ func Project(action string, w io.Writer) error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
+	mode, err := cfg.SubprojectMode()
+	if err != nil {
+		return err
+	}
+	id, _ := project.DetectID(cwd)
+	sub := project.DetectSubproject(cwd, cfg)
+	if sub != nil {
+		id = sub.ID
+		if mode == config.SubprojectsSeparate {
+			projectName = sub.Alias()
+		}
+	}
+
 	switch {
 	case action == "":
 		// Show current project name
@@ -35,6 +48,16 @@ func Project(action string, w io.Writer) error {
 		} else {
 			fmt.Fprintf(w, "Project: %s\n", projectName)
 		}
+		if id != "" && id != projectName {
+			fmt.Fprintf(w, "ID: %s\n", id)
+		}
+		if sub != nil {
+			if mode == config.SubprojectsShared {
+				fmt.Fprintf(w, "Sub-project: %s (shares the environments of %s)\n", sub.Path, sub.Parent)
+			} else {
+				fmt.Fprintf(w, "Sub-project: %s of %s\n", sub.Path, sub.Parent)
+			}
+		}
 
 	case strings.HasPrefix(action, "rename "):
 		// Rename project
@@ -75,4 +98,11 @@ func Project(action string, w io.Writer) error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// currentProject returns the project of dir, honouring the project name
+// overrides and sub-project settings in config.yaml
+func currentProject(dir string) string {
+	cfg, _ := config.LoadConfig(filepath.Join(paths.DenvHome(), "config.yaml"))
+	return project.DetectProjectWithConfig(dir, cfg)
+}
@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/caoer/denv/internal/environment"
+	"github.com/caoer/denv/internal/shell"
+)
+
+// DefaultPromptFormat is the segment printed by denv prompt without --format
+const DefaultPromptFormat = "{{.Project}}:{{.Env}} [{{.Sessions}}s {{.Ports}}p]"
+
+// promptSegment is the data available to denv prompt templates
+type promptSegment struct {
+	Project  string
+	Env      string
+	Sessions int
+	Ports    int
+	// Color is the environment's 256-colour index, as used by denv enter
+	Color string
+}
+
+// Colored wraps text in the environment's ANSI colour
+func (s promptSegment) Colored(text string) string {
+	return shell.GetColorForEnvironment(s.Env) + text + "\033[0m"
+}
+
+// Prompt prints a prompt segment for the current environment, or nothing
+// outside one. It runs on every prompt, so it loads runtime.json only when
+// the format needs the sessions or ports.
+func Prompt(format string, w io.Writer) error {
+	if format == "" {
+		format = DefaultPromptFormat
+	}
+	tmpl, err := template.New("prompt").Parse(format)
+	if err != nil {
+		return fmt.Errorf("invalid prompt format: %w", err)
+	}
+
+	envName := os.Getenv("DENV_ENV_NAME")
+	if envName == "" {
+		return nil
+	}
+	segment := promptSegment{
+		Project: os.Getenv("DENV_PROJECT_NAME"),
+		Env:     envName,
+		Color:   shell.GetColorIndex(envName),
+	}
+	if strings.Contains(format, ".Sessions") || strings.Contains(format, ".Ports") {
+		runtime, _ := environment.LoadRuntime(os.Getenv("DENV_ENV"))
+		segment.Sessions = countActiveSessions(runtime)
+		if runtime != nil {
+			// Not the PORT_* variables: users define their own
+			segment.Ports = len(runtime.Ports)
+		}
+	}
+
+	return tmpl.Execute(w, segment)
+}
@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/caoer/denv/internal/environment"
+	"github.com/caoer/denv/internal/shell"
+)
+
+func TestPrompt(t *testing.T) {
+	envPath := t.TempDir()
+	runtime := environment.NewRuntime("myapp", "staging")
+	runtime.Sessions["live"] = environment.Session{ID: "live", PID: os.Getpid()}
+	runtime.Sessions["dead"] = environment.Session{ID: "dead", PID: 999999}
+	runtime.Ports = map[int]int{3000: 33000, 5432: 35432}
+	require.NoError(t, environment.SaveRuntime(envPath, runtime))
+
+	t.Setenv("DENV_ENV_NAME", "staging")
+	t.Setenv("DENV_PROJECT_NAME", "myapp")
+	t.Setenv("DENV_ENV", envPath)
+	t.Setenv("PORT_3000", "33000")
+	t.Setenv("PORT_5432", "35432")
+	// A variable of the user's, not a mapped port
+	t.Setenv("PORT_API", "8080")
+
+	t.Run("default format", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, Prompt("", &out))
+		assert.Equal(t, "myapp:staging [1s 2p]", out.String())
+	})
+
+	t.Run("color", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, Prompt("{{.Color}} {{.Colored .Env}}", &out))
+		assert.Equal(t, shell.GetColorIndex("staging")+" "+shell.GetColorForEnvironment("staging")+"staging\033[0m", out.String())
+	})
+
+	t.Run("runtime only read for sessions and ports", func(t *testing.T) {
+		t.Setenv("DENV_ENV", filepath.Join(envPath, "missing"))
+		var out bytes.Buffer
+		require.NoError(t, Prompt("{{.Project}}:{{.Env}}", &out))
+		assert.Equal(t, "myapp:staging", out.String())
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		err := Prompt("{{.Env", &bytes.Buffer{})
+		assert.ErrorContains(t, err, "invalid prompt format")
+	})
+}
+
+func TestPrompt_OutsideEnvironment(t *testing.T) {
+	unsetenv(t, "DENV_ENV_NAME")
+
+	var out bytes.Buffer
+	require.NoError(t, Prompt("", &out))
+	assert.Empty(t, out.String())
+}
@@ -0,0 +1,247 @@
+package commands
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/caoer/denv/internal/environment"
+	"github.com/caoer/denv/internal/paths"
+	"github.com/caoer/denv/internal/proxy"
+	"github.com/caoer/denv/internal/session"
+)
+
+const proxyRefreshInterval = 500 * time.Millisecond
+
+// Proxy listens on the original ports of an environment and forwards them
+// to its mapped ports until interrupted. `denv proxy switch` retargets it.
+// With useTLS the proxy terminates HTTPS using the local CA.
+func Proxy(envName string, useTLS bool, w io.Writer) error {
+	_, runtime, _, err := loadTargetEnvironment(envName)
+	if err != nil {
+		return err
+	}
+
+	// A running proxy would pick up the new target; it's retargeted with
+	// denv proxy switch instead
+	lock, err := claimProxy(runtime.Project)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+	if err := writeProxyTarget(runtime.Project, runtime.Environment); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Proxying project %s:\n", runtime.Project)
+	server := newProxyServer(runtime.Project, "127.0.0.1")
+	defer server.close()
+	if useTLS {
+		if server.tls, err = serverTLSConfig(); err != nil {
+			return err
+		}
+	}
+	if err := server.refresh(w); err != nil {
+		return err
+	}
+	if len(server.forwarders) == 0 {
+		return fmt.Errorf("no ports to proxy for environment '%s'", runtime.Environment)
+	}
+	fmt.Fprintln(w, "Press Ctrl+C to stop")
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	ticker := time.NewTicker(proxyRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-signals:
+			return nil
+		case <-ticker.C:
+			if err := server.refresh(w); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		}
+	}
+}
+
+// ProxySwitch points the running proxy of the current project at another
+// environment
+func ProxySwitch(envName string, w io.Writer) error {
+	if envName == "" {
+		return fmt.Errorf("environment name required")
+	}
+	_, runtime, _, err := loadTargetEnvironment(envName)
+	if err != nil {
+		return err
+	}
+	if err := writeProxyTarget(runtime.Project, runtime.Environment); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Proxy for project %s now targets '%s'\n", runtime.Project, runtime.Environment)
+	return nil
+}
+
+// proxyServer runs one forwarder per original port of a project's target
+// environment
+type proxyServer struct {
+	project    string
+	host       string
+	tls        *tls.Config
+	current    string
+	forwarders map[int]*proxy.Forwarder
+	locks      map[int]*session.FileLock
+	busy       map[int]bool
+}
+
+func newProxyServer(project, host string) *proxyServer {
+	return &proxyServer{
+		project:    project,
+		host:       host,
+		forwarders: make(map[int]*proxy.Forwarder),
+		locks:      make(map[int]*session.FileLock),
+		busy:       make(map[int]bool),
+	}
+}
+
+// refresh re-reads the target environment and updates the forwarders
+func (s *proxyServer) refresh(w io.Writer) error {
+	envName, err := readProxyTarget(s.project)
+	if err != nil {
+		return err
+	}
+	runtime, err := environment.LoadRuntime(paths.EnvironmentPath(s.project, envName))
+	if err != nil || runtime == nil {
+		return fmt.Errorf("environment '%s' not found", envName)
+	}
+
+	switched := envName != s.current
+	if switched && s.current != "" {
+		fmt.Fprintf(w, "Switched to environment '%s'\n", envName)
+	}
+	s.current = envName
+
+	var origs []int
+	for orig := range runtime.Ports {
+		origs = append(origs, orig)
+	}
+	sort.Ints(origs)
+
+	for _, orig := range origs {
+		target := s.host + ":" + strconv.Itoa(runtime.Ports[orig])
+		f, ok := s.forwarders[orig]
+		if !ok {
+			if f, err = s.listen(orig, target); err != nil {
+				if !s.busy[orig] {
+					fmt.Fprintf(w, "  ✗ %d: %v\n", orig, err)
+					s.busy[orig] = true
+				}
+				continue
+			}
+			delete(s.busy, orig)
+			fmt.Fprintf(w, "  %s:%d → %d (%s)\n", s.host, orig, runtime.Ports[orig], envName)
+			continue
+		}
+		if f.Target() != target {
+			f.SetTarget(target)
+			fmt.Fprintf(w, "  %s:%d → %d (%s)\n", s.host, orig, runtime.Ports[orig], envName)
+		}
+	}
+
+	// Ports the target doesn't use refuse connections
+	for orig, f := range s.forwarders {
+		if _, ok := runtime.Ports[orig]; !ok && f.Target() != "" {
+			f.SetTarget("")
+			fmt.Fprintf(w, "  %s:%d not used by '%s'\n", s.host, orig, envName)
+		}
+	}
+	return nil
+}
+
+// listen claims an original port machine-wide and starts forwarding it
+func (s *proxyServer) listen(orig int, target string) (*proxy.Forwarder, error) {
+	lock, err := claimPort(orig)
+	if err != nil {
+		return nil, err
+	}
+
+	ln, err := net.Listen("tcp", s.host+":"+strconv.Itoa(orig))
+	if err != nil {
+		_ = lock.Release()
+		return nil, err
+	}
+	if s.tls != nil {
+		ln = tls.NewListener(ln, s.tls)
+	}
+	f := proxy.NewForwarder(ln, target)
+	go func() { _ = f.Serve() }()
+
+	s.forwarders[orig] = f
+	s.locks[orig] = lock
+	return f, nil
+}
+
+func (s *proxyServer) close() {
+	for orig, f := range s.forwarders {
+		_ = f.Close()
+		_ = s.locks[orig].Release()
+	}
+	s.forwarders = make(map[int]*proxy.Forwarder)
+	s.locks = make(map[int]*session.FileLock)
+}
+
+// claimPort takes the machine-wide lock on an original port shared by
+// denv proxy and denv router
+func claimPort(orig int) (*session.FileLock, error) {
+	if err := os.MkdirAll(paths.ProxyPath(), 0755); err != nil {
+		return nil, err
+	}
+	lock, err := session.AcquireLock(filepath.Join(paths.ProxyPath(), strconv.Itoa(orig)+".lock"))
+	if err != nil {
+		return nil, fmt.Errorf("already served by another denv proxy or router")
+	}
+	return lock, nil
+}
+
+// claimProxy takes the lock held by the running proxy of a project
+func claimProxy(project string) (*session.FileLock, error) {
+	if err := os.MkdirAll(paths.ProxyPath(), 0755); err != nil {
+		return nil, err
+	}
+	lock, err := session.AcquireLock(proxyTargetPath(project) + ".lock")
+	if err != nil {
+		return nil, fmt.Errorf("a proxy for project %s is already running; use 'denv proxy switch <env>' to retarget it", project)
+	}
+	return lock, nil
+}
+
+func proxyTargetPath(project string) string {
+	return filepath.Join(paths.ProxyPath(), project+".target")
+}
+
+func writeProxyTarget(project, envName string) error {
+	if err := os.MkdirAll(paths.ProxyPath(), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(proxyTargetPath(project), []byte(envName+"\n"), 0644)
+}
+
+func readProxyTarget(project string) (string, error) {
+	data, err := os.ReadFile(proxyTargetPath(project))
+	if err != nil {
+		return "", fmt.Errorf("failed to read proxy target: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
@@ -0,0 +1,129 @@
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/caoer/denv/internal/environment"
+	"github.com/caoer/denv/internal/testutil"
+)
+
+// freePort returns a port nothing listens on right now
+func freePort(t *testing.T) int {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+// nameServer answers every connection with name and returns its port
+func nameServer(t *testing.T, name string) int {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			_, _ = conn.Write([]byte(name + "\n"))
+			conn.Close()
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func dialName(t *testing.T, port int) string {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), time.Second)
+	require.NoError(t, err)
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, _ := bufio.NewReader(conn).ReadString('\n')
+	return line
+}
+
+func setupProxyProject(t *testing.T) (string, int) {
+	tmpDir, _ := testutil.SetupProject(t, "proxytest")
+
+	// Two environments mapping the same original port to different servers
+	orig := freePort(t)
+	for _, env := range []string{"blue", "green"} {
+		rt := environment.NewRuntime("proxytest", env)
+		rt.Ports[orig] = nameServer(t, env)
+		envPath := filepath.Join(tmpDir, "proxytest-"+env)
+		require.NoError(t, os.MkdirAll(envPath, 0755))
+		require.NoError(t, environment.SaveRuntime(envPath, rt))
+	}
+	return tmpDir, orig
+}
+
+func TestProxy_SwitchesTarget(t *testing.T) {
+	_, orig := setupProxyProject(t)
+
+	require.NoError(t, writeProxyTarget("proxytest", "blue"))
+	server := newProxyServer("proxytest", "127.0.0.1")
+	defer server.close()
+
+	var out bytes.Buffer
+	require.NoError(t, server.refresh(&out))
+	assert.Equal(t, "blue\n", dialName(t, orig))
+
+	// Test: denv proxy switch retargets the running proxy
+	require.NoError(t, ProxySwitch("green", &out))
+	require.NoError(t, server.refresh(&out))
+	assert.Equal(t, "green\n", dialName(t, orig))
+	assert.Contains(t, out.String(), "Switched to environment 'green'")
+
+	err := ProxySwitch("missing", &out)
+	assert.Error(t, err)
+}
+
+func TestProxy_OneProxyPerPort(t *testing.T) {
+	_, orig := setupProxyProject(t)
+	require.NoError(t, writeProxyTarget("proxytest", "blue"))
+
+	first := newProxyServer("proxytest", "127.0.0.1")
+	defer first.close()
+	require.NoError(t, first.refresh(&bytes.Buffer{}))
+
+	// Test: A second proxy can't claim the same original port
+	second := newProxyServer("proxytest", "127.0.0.1")
+	defer second.close()
+	var out bytes.Buffer
+	require.NoError(t, second.refresh(&out))
+	assert.Empty(t, second.forwarders)
+	assert.Contains(t, out.String(), "already served")
+
+	// Test: The port is free again once the first proxy stops
+	first.close()
+	require.NoError(t, second.refresh(&out))
+	assert.Len(t, second.forwarders, 1)
+	assert.Equal(t, "blue\n", dialName(t, orig))
+}
+
+func TestProxy_RefusesWhileRunning(t *testing.T) {
+	setupProxyProject(t)
+	require.NoError(t, writeProxyTarget("proxytest", "blue"))
+
+	// The lock a running proxy holds
+	lock, err := claimProxy("proxytest")
+	require.NoError(t, err)
+	defer lock.Release()
+
+	// Test: A second proxy refuses without retargeting the running one
+	err = Proxy("green", false, &bytes.Buffer{})
+	assert.ErrorContains(t, err, "denv proxy switch")
+	target, err := readProxyTarget("proxytest")
+	require.NoError(t, err)
+	assert.Equal(t, "blue", target)
+}
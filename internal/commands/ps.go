@@ -8,7 +8,6 @@ import (
 
 	"github.com/caoer/denv/internal/environment"
 	"github.com/caoer/denv/internal/paths"
-	"github.com/caoer/denv/internal/project"
 	"github.com/caoer/denv/internal/ui"
 )
 
@@ -52,6 +51,14 @@ func showCurrentEnvironment() error {
 	// Show the environment details
 	showEnvironmentDetails(runtime)
 
+	// Show router URLs if denv router is running
+	if urls := routedURLs(runtime); len(urls) > 0 {
+		fmt.Println("\n🌐 Routed URLs:")
+		for _, u := range urls {
+			fmt.Printf("   %s\n", u)
+		}
+	}
+
 	// Show active sessions in this environment
 	if runtime != nil && len(runtime.Sessions) > 0 {
 		fmt.Println("\n👥 Active Sessions:")
@@ -78,10 +85,7 @@ func showCurrentEnvironment() error {
 func showSpecificEnvironment(envName string) error {
 	// Detect project for the current directory
 	cwd, _ := os.Getwd()
-	projectName, err := project.DetectProject(cwd)
-	if err != nil {
-		return fmt.Errorf("failed to detect project: %w", err)
-	}
+	projectName := currentProject(cwd)
 
 	// Build the environment path
 	envPath := paths.EnvironmentPath(projectName, envName)
@@ -105,6 +109,14 @@ func showSpecificEnvironment(envName string) error {
 	// Show the environment details
 	showEnvironmentDetails(runtime)
 
+	// Show router URLs if denv router is running
+	if urls := routedURLs(runtime); len(urls) > 0 {
+		fmt.Println("\n🌐 Routed URLs:")
+		for _, u := range urls {
+			fmt.Printf("   %s\n", u)
+		}
+	}
+
 	// Show sessions if any
 	if runtime != nil && len(runtime.Sessions) > 0 {
 		fmt.Println("\n👥 Sessions in this environment:")
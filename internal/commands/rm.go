@@ -1,32 +1,49 @@
 package commands
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/caoer/denv/internal/config"
 	"github.com/caoer/denv/internal/environment"
+	"github.com/caoer/denv/internal/hooks"
 	"github.com/caoer/denv/internal/paths"
-	"github.com/caoer/denv/internal/project"
-	"github.com/caoer/denv/internal/session"
 )
 
-func Rm(envName string, all bool) error {
+// trashTimeFormat names the per-removal directories inside the trash
+const trashTimeFormat = "20060102-150405"
+
+// removalCandidate describes an environment about to be moved to the trash
+type removalCandidate struct {
+	path    string
+	project string
+	env     string
+	runtime *environment.Runtime
+}
+
+// Rm moves an environment (or all inactive environments) to the trash.
+// On a terminal it asks for confirmation first unless yes is set.
+func Rm(envName string, all, yes bool) error {
+	cfg, _ := config.LoadConfig(filepath.Join(paths.DenvHome(), "config.yaml"))
+	purgeExpiredTrash(cfg, os.Stdout)
+
 	if all {
-		return rmAll()
+		return rmAll(yes)
 	}
-	
+
 	if envName == "" {
 		return fmt.Errorf("environment name required")
 	}
 
 	// Detect current project
 	cwd, _ := os.Getwd()
-	projectName, err := project.DetectProject(cwd)
-	if err != nil {
-		return fmt.Errorf("failed to detect project: %w", err)
-	}
+	projectName := currentProject(cwd)
 
 	envPath := paths.EnvironmentPath(projectName, envName)
 
@@ -37,87 +54,263 @@ func Rm(envName string, all bool) error {
 
 	// Check for active sessions
 	runtime, _ := environment.LoadRuntime(envPath)
-	if runtime != nil && len(runtime.Sessions) > 0 {
-		activeSessions := 0
-		for _, sess := range runtime.Sessions {
-			if session.ProcessExists(sess.PID) {
-				activeSessions++
-			}
-		}
-		if activeSessions > 0 {
-			return fmt.Errorf("cannot clean environment with %d active session(s)", activeSessions)
-		}
+	if activeSessions := countActiveSessions(runtime); activeSessions > 0 {
+		return fmt.Errorf("cannot clean environment with %d active session(s)", activeSessions)
+	}
+
+	candidates := []removalCandidate{{path: envPath, project: projectName, env: envName, runtime: runtime}}
+	if !confirmRemoval(candidates, yes) {
+		fmt.Println("Aborted")
+		return nil
 	}
 
-	// Remove environment directory
-	if err := os.RemoveAll(envPath); err != nil {
-		return fmt.Errorf("failed to remove environment: %w", err)
+	if err := moveToTrash(candidates); err != nil {
+		return err
 	}
+	cleanDanglingSymlinks(filepath.Join(cwd, ".denv"))
 
-	fmt.Printf("Removed environment '%s' for project %s\n", envName, projectName)
+	fmt.Printf("Moved environment '%s' for project %s to trash\n", envName, projectName)
+	fmt.Printf("Undo with: denv restore-trash %s\n", envName)
 	return nil
 }
 
-func rmAll() error {
+func rmAll(yes bool) error {
 	denvHome := paths.DenvHome()
-	
+
 	// Find all environment directories
 	entries, err := os.ReadDir(denvHome)
 	if err != nil {
 		return fmt.Errorf("failed to read denv home: %w", err)
 	}
 
-	removedCount := 0
-	var removedEnvs []string
+	var candidates []removalCandidate
 
 	for _, entry := range entries {
-		if !entry.IsDir() {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
 			continue
 		}
-		
-		name := entry.Name()
-		// Parse project-environment pattern
-		parts := strings.SplitN(name, "-", 2)
-		if len(parts) != 2 {
-			// Skip non-environment directories (like standalone project dirs)
+
+		// Only directories with a runtime are environments; shared project
+		// directories must survive
+		envPath := filepath.Join(denvHome, entry.Name())
+		runtime, _ := environment.LoadRuntime(envPath)
+		if runtime == nil {
 			continue
 		}
-		
-		project := parts[0]
-		env := parts[1]
-		envPath := filepath.Join(denvHome, name)
-		
-		// Check if environment has active sessions
-		runtime, _ := environment.LoadRuntime(envPath)
-		hasActiveSessions := false
-		
-		if runtime != nil && len(runtime.Sessions) > 0 {
-			for _, sess := range runtime.Sessions {
-				if session.ProcessExists(sess.PID) {
-					hasActiveSessions = true
-					break
-				}
-			}
-		}
-		
+
 		// Only remove if no active sessions
-		if !hasActiveSessions {
-			if err := os.RemoveAll(envPath); err != nil {
-				return fmt.Errorf("failed to remove environment %s: %w", name, err)
-			}
-			removedCount++
-			removedEnvs = append(removedEnvs, fmt.Sprintf("%s:%s", project, env))
+		if countActiveSessions(runtime) > 0 {
+			continue
 		}
+
+		candidates = append(candidates, removalCandidate{
+			path:    envPath,
+			project: runtime.Project,
+			env:     runtime.Environment,
+			runtime: runtime,
+		})
 	}
 
-	if removedCount == 0 {
+	if len(candidates) == 0 {
 		fmt.Println("No inactive environments found to remove")
-	} else {
-		fmt.Printf("Removed %d inactive environment(s):\n", removedCount)
-		for _, env := range removedEnvs {
-			fmt.Printf("  • %s\n", env)
+		return nil
+	}
+
+	if !confirmRemoval(candidates, yes) {
+		fmt.Println("Aborted")
+		return nil
+	}
+
+	if err := moveToTrash(candidates); err != nil {
+		return err
+	}
+
+	fmt.Printf("Moved %d inactive environment(s) to trash\n", len(candidates))
+	return nil
+}
+
+// confirmRemoval prints what is about to be removed and, on a terminal,
+// asks the user to confirm
+func confirmRemoval(candidates []removalCandidate, yes bool) bool {
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].project != candidates[j].project {
+			return candidates[i].project < candidates[j].project
+		}
+		return candidates[i].env < candidates[j].env
+	})
+
+	fmt.Printf("The following environment(s) will be moved to trash:\n")
+	for _, c := range candidates {
+		fmt.Printf("  • %s:%s  %s\n", c.project, c.env, describeEnvironment(c.path, c.runtime))
+	}
+
+	if yes || !isTerminal(os.Stdin) {
+		return true
+	}
+
+	fmt.Print("Continue? [y/N] ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// describeEnvironment summarizes size, ports and last use of an environment
+func describeEnvironment(envPath string, runtime *environment.Runtime) string {
+	parts := []string{formatSize(dirSize(envPath))}
+
+	if runtime != nil {
+		var origs []int
+		for orig := range runtime.Ports {
+			origs = append(origs, orig)
+		}
+		sort.Ints(origs)
+
+		var mappings []string
+		for _, orig := range origs {
+			mappings = append(mappings, fmt.Sprintf("%d→%d", orig, runtime.Ports[orig]))
+		}
+		if len(mappings) > 0 {
+			parts = append(parts, "ports "+strings.Join(mappings, " "))
+		}
+
+		if last := runtime.LastActivity(); !last.IsZero() {
+			parts = append(parts, "last used "+formatAge(time.Since(last))+" ago")
+		}
+	}
+
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+// moveToTrash moves environment directories into a fresh
+// DENV_HOME/.trash/<timestamp>/ directory
+func moveToTrash(candidates []removalCandidate) error {
+	stamp := time.Now().Format(trashTimeFormat)
+	trashDir := filepath.Join(paths.TrashPath(), stamp)
+	for i := 1; ; i++ {
+		if _, err := os.Stat(trashDir); os.IsNotExist(err) {
+			break
+		}
+		trashDir = filepath.Join(paths.TrashPath(), fmt.Sprintf("%s.%d", stamp, i))
+	}
+
+	if err := os.MkdirAll(trashDir, 0755); err != nil {
+		return fmt.Errorf("failed to create trash directory: %w", err)
+	}
+
+	// on-destroy waits until the trash is purged, so restore-trash brings
+	// back an intact environment; its services are restarted on entering
+	for _, c := range candidates {
+		_ = stopServices(c.path, nil, false, io.Discard)
+		if err := os.Rename(c.path, filepath.Join(trashDir, filepath.Base(c.path))); err != nil {
+			return fmt.Errorf("failed to move environment %s:%s to trash: %w", c.project, c.env, err)
 		}
 	}
-	
 	return nil
-}
\ No newline at end of file
+}
+
+// RestoreTrash moves the most recently trashed copy of an environment of the
+// current project back into place
+func RestoreTrash(envName string) error {
+	if envName == "" {
+		return fmt.Errorf("environment name required")
+	}
+
+	cwd, _ := os.Getwd()
+	projectName := currentProject(cwd)
+
+	envPath := paths.EnvironmentPath(projectName, envName)
+	if _, err := os.Stat(envPath); err == nil {
+		return fmt.Errorf("environment '%s' already exists", envName)
+	}
+
+	// Trash directories sort chronologically by name; newest first
+	entries, _ := os.ReadDir(paths.TrashPath())
+	for i := len(entries) - 1; i >= 0; i-- {
+		if !entries[i].IsDir() {
+			continue
+		}
+		trashDir := filepath.Join(paths.TrashPath(), entries[i].Name())
+		trashed := filepath.Join(trashDir, filepath.Base(envPath))
+		if _, err := os.Stat(trashed); err != nil {
+			continue
+		}
+
+		if err := os.Rename(trashed, envPath); err != nil {
+			return fmt.Errorf("failed to restore environment: %w", err)
+		}
+		// Drop the timestamp directory once it is empty
+		_ = os.Remove(trashDir)
+
+		fmt.Printf("Restored environment '%s' for project %s from trash\n", envName, projectName)
+		return nil
+	}
+
+	return fmt.Errorf("environment '%s' not found in trash", envName)
+}
+
+// purgeExpiredTrash permanently deletes trash older than the configured
+// retention, running the on-destroy hooks of the environments in it
+func purgeExpiredTrash(cfg *config.Config, w io.Writer) {
+	retention := "7d"
+	if cfg != nil && cfg.TrashRetention != "" {
+		retention = cfg.TrashRetention
+	}
+	maxAge, err := ParseAge(retention)
+	if err != nil {
+		return
+	}
+
+	entries, err := os.ReadDir(paths.TrashPath())
+	if err != nil {
+		return
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		stamp := strings.SplitN(entry.Name(), ".", 2)[0]
+		trashed, err := time.ParseInLocation(trashTimeFormat, stamp, time.Local)
+		if err != nil {
+			continue
+		}
+		if trashed.Before(cutoff) {
+			purgeTrash(filepath.Join(paths.TrashPath(), entry.Name()), w)
+		}
+	}
+}
+
+// purgeTrash deletes a trash directory after running the on-destroy hooks
+// of the environments in it
+func purgeTrash(trashDir string, w io.Writer) {
+	entries, _ := os.ReadDir(trashDir)
+	for _, entry := range entries {
+		envPath := filepath.Join(trashDir, entry.Name())
+		if runtime, _ := environment.LoadRuntime(envPath); runtime != nil {
+			runHooks(hooks.OnDestroy, runtime, envPath, "", w)
+		}
+	}
+	_ = os.RemoveAll(trashDir)
+}
+
+// dirSize returns the total size of regular files below path
+func dirSize(path string) int64 {
+	var size int64
+	_ = filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// isTerminal reports whether f is an interactive terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -1,11 +1,14 @@
 package commands
 
 import (
+	"io"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/caoer/denv/internal/config"
 	"github.com/caoer/denv/internal/environment"
 	"github.com/caoer/denv/internal/testutil"
 )
@@ -32,14 +35,14 @@ func TestRm_BasicFunctionality(t *testing.T) {
 	assert.DirExists(t, envPath)
 
 	// Test: Remove environment
-	err = Rm("test-env", false) // false = not --all flag
+	err = Rm("test-env", false, true) // false = not --all flag
 	assert.NoError(t, err)
 	assert.NoDirExists(t, envPath)
 }
 
 func TestRm_RequiresEnvironmentName(t *testing.T) {
 	// Test that Rm requires an environment name when not using --all
-	err := Rm("", false)
+	err := Rm("", false, true)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "environment name required")
 }
@@ -57,7 +60,7 @@ func TestRm_NonExistentEnvironment(t *testing.T) {
 	os.Setenv("DENV_HOME", tmpDir)
 
 	// Test: Remove non-existent environment
-	err := Rm("nonexistent", false)
+	err := Rm("nonexistent", false, true)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "does not exist")
 }
@@ -92,7 +95,7 @@ func TestRm_WithActiveSessions(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Test: Should fail to remove environment with active sessions
-	err = Rm("test-env", false)
+	err = Rm("test-env", false, true)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "active session")
 	assert.DirExists(t, envPath) // Should still exist
@@ -134,7 +137,7 @@ func TestRm_AllFlag_RemovesOnlyInactiveEnvironments(t *testing.T) {
 	assert.NoError(t, err)
 
 	// Test: Remove all inactive environments
-	err = Rm("", true) // true = --all flag
+	err = Rm("", true, true) // true = --all flag
 	assert.NoError(t, err)
 
 	// Verify only inactive environments were removed
@@ -156,7 +159,7 @@ func TestRm_AllFlag_NoEnvironments(t *testing.T) {
 	os.Setenv("DENV_HOME", tmpDir)
 
 	// Test: Remove all when no environments exist
-	err := Rm("", true) // true = --all flag
+	err := Rm("", true, true) // true = --all flag
 	assert.NoError(t, err) // Should not error
 }
 
@@ -192,11 +195,68 @@ func TestRm_AllFlag_AllEnvironmentsActive(t *testing.T) {
 	}
 
 	// Test: Remove all when all environments are active
-	err := Rm("", true) // true = --all flag
+	err := Rm("", true, true) // true = --all flag
 	assert.NoError(t, err)
 
 	// Verify all environments still exist
 	for _, name := range envNames {
 		assert.DirExists(t, filepath.Join(tmpDir, "testproject-"+name))
 	}
-}
\ No newline at end of file
+}
+func TestRm_MovesToTrashAndRestores(t *testing.T) {
+	tmpDir, _ := testutil.SetupProject(t, "testproject")
+
+	err := Enter("test-env")
+	assert.NoError(t, err)
+
+	envPath := filepath.Join(tmpDir, "testproject-test-env")
+	_ = os.WriteFile(filepath.Join(envPath, "data.db"), []byte("keep me"), 0644)
+
+	// Test: Removal keeps the data in the trash
+	err = Rm("test-env", false, true)
+	assert.NoError(t, err)
+	assert.NoDirExists(t, envPath)
+
+	trashed, _ := filepath.Glob(filepath.Join(tmpDir, ".trash", "*", "testproject-test-env", "data.db"))
+	assert.Len(t, trashed, 1)
+
+	// Test: restore-trash brings it back
+	err = RestoreTrash("test-env")
+	assert.NoError(t, err)
+	data, err := os.ReadFile(filepath.Join(envPath, "data.db"))
+	assert.NoError(t, err)
+	assert.Equal(t, "keep me", string(data))
+
+	// Test: Nothing left to restore
+	err = RestoreTrash("test-env")
+	assert.Error(t, err)
+}
+
+func TestRm_AllFlag_KeepsProjectDirectories(t *testing.T) {
+	tmpDir, _ := testutil.SetupProject(t, "my-project")
+
+	err := Enter("dev")
+	assert.NoError(t, err)
+
+	// Test: The shared "my-project" directory looks like "<project>-<env>"
+	// but has no runtime and must not be removed
+	err = Rm("", true, true)
+	assert.NoError(t, err)
+	assert.NoDirExists(t, filepath.Join(tmpDir, "my-project-dev"))
+	assert.DirExists(t, filepath.Join(tmpDir, "my-project"))
+}
+
+func TestPurgeExpiredTrash(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.Setenv("DENV_HOME", tmpDir)
+
+	old := filepath.Join(tmpDir, ".trash", time.Now().Add(-10*24*time.Hour).Format(trashTimeFormat))
+	recent := filepath.Join(tmpDir, ".trash", time.Now().Add(-time.Hour).Format(trashTimeFormat))
+	_ = os.MkdirAll(filepath.Join(old, "p-e"), 0755)
+	_ = os.MkdirAll(filepath.Join(recent, "p-e"), 0755)
+
+	purgeExpiredTrash(&config.Config{TrashRetention: "7d"}, io.Discard)
+
+	assert.NoDirExists(t, old)
+	assert.DirExists(t, recent)
+}
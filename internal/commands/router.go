@@ -0,0 +1,234 @@
+package commands
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/caoer/denv/internal/environment"
+	"github.com/caoer/denv/internal/paths"
+	"github.com/caoer/denv/internal/process"
+	"github.com/caoer/denv/internal/router"
+	"github.com/caoer/denv/internal/session"
+)
+
+const routerRefreshInterval = 2 * time.Second
+
+// routerState is written to DENV_HOME/router.json while a router runs
+type routerState struct {
+	PID   int   `json:"pid"`
+	Ports []int `json:"ports"`
+	TLS   bool  `json:"tls,omitempty"`
+}
+
+// Router serves every environment over HTTP at
+// <env>.<project>.localhost:<original port> until interrupted. Without
+// ports it listens on every original port any environment uses. With
+// useTLS it serves HTTPS using certificates from the local CA.
+func Router(ports []int, useTLS bool, w io.Writer) error {
+	server := newRouterServer(ports, "127.0.0.1")
+	defer server.close()
+	if useTLS {
+		var err error
+		if server.tls, err = serverTLSConfig(); err != nil {
+			return err
+		}
+	}
+
+	server.refresh(w)
+	if len(server.servers) == 0 {
+		return fmt.Errorf("no ports to route")
+	}
+	fmt.Fprintln(w, "Press Ctrl+C to stop")
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	ticker := time.NewTicker(routerRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-signals:
+			return nil
+		case <-ticker.C:
+			server.refresh(w)
+		}
+	}
+}
+
+// routerServer runs one HTTP listener per routed original port
+type routerServer struct {
+	table   *router.Table
+	fixed   []int
+	host    string
+	tls     *tls.Config
+	servers map[int]*http.Server
+	locks   map[int]*session.FileLock
+	failed  map[int]bool
+}
+
+func newRouterServer(ports []int, host string) *routerServer {
+	return &routerServer{
+		table:   router.NewTable(),
+		fixed:   ports,
+		host:    host,
+		servers: make(map[int]*http.Server),
+		locks:   make(map[int]*session.FileLock),
+		failed:  make(map[int]bool),
+	}
+}
+
+// refresh rebuilds the routing table from the environments on disk and
+// listens on any new ports
+func (s *routerServer) refresh(w io.Writer) {
+	s.table.Set(scanRoutes())
+
+	ports := s.fixed
+	if len(ports) == 0 {
+		ports = s.table.Ports()
+	}
+
+	changed := false
+	for _, port := range ports {
+		if _, ok := s.servers[port]; ok || s.failed[port] {
+			continue
+		}
+		if err := s.listen(port); err != nil {
+			fmt.Fprintf(w, "  ✗ %d: %v\n", port, err)
+			s.failed[port] = true
+			continue
+		}
+		fmt.Fprintf(w, "  %s://*%s:%d\n", s.scheme(), router.Suffix, port)
+		changed = true
+	}
+
+	if changed {
+		s.writeState()
+	}
+}
+
+func (s *routerServer) listen(port int) error {
+	lock, err := claimPort(port)
+	if err != nil {
+		return err
+	}
+
+	ln, err := net.Listen("tcp", s.host+":"+strconv.Itoa(port))
+	if err != nil {
+		_ = lock.Release()
+		return err
+	}
+	if s.tls != nil {
+		ln = tls.NewListener(ln, s.tls)
+	}
+
+	srv := &http.Server{
+		Handler:           router.NewHandler(s.table, port),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintf(os.Stderr, "Warning: router on port %d stopped: %v\n", port, err)
+		}
+	}()
+
+	s.servers[port] = srv
+	s.locks[port] = lock
+	return nil
+}
+
+func (s *routerServer) scheme() string {
+	if s.tls != nil {
+		return "https"
+	}
+	return "http"
+}
+
+func (s *routerServer) writeState() {
+	state := routerState{PID: os.Getpid(), TLS: s.tls != nil}
+	for port := range s.servers {
+		state.Ports = append(state.Ports, port)
+	}
+	sort.Ints(state.Ports)
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err == nil {
+		_ = os.WriteFile(paths.RouterPath(), data, 0644)
+	}
+}
+
+func (s *routerServer) close() {
+	for port, srv := range s.servers {
+		_ = srv.Close()
+		_ = s.locks[port].Release()
+	}
+	s.servers = make(map[int]*http.Server)
+	s.locks = make(map[int]*session.FileLock)
+
+	if state := runningRouter(); state != nil && state.PID == os.Getpid() {
+		_ = os.Remove(paths.RouterPath())
+	}
+}
+
+// scanRoutes builds the routing table from every environment's runtime
+func scanRoutes() router.Routes {
+	routes := make(router.Routes)
+	for _, env := range allEnvironments() {
+		if len(env.runtime.Ports) == 0 {
+			continue
+		}
+		ports := make(map[int]int, len(env.runtime.Ports))
+		for orig, mapped := range env.runtime.Ports {
+			ports[orig] = mapped
+		}
+		routes[router.Hostname(env.runtime.Project, env.runtime.Environment)] = ports
+	}
+	return routes
+}
+
+// runningRouter returns the state of the running router, or nil
+func runningRouter() *routerState {
+	data, err := os.ReadFile(paths.RouterPath())
+	if err != nil {
+		return nil
+	}
+	var state routerState
+	if json.Unmarshal(data, &state) != nil || !process.Alive(state.PID) {
+		return nil
+	}
+	return &state
+}
+
+// routedURLs returns the router URLs of an environment's ports, if a router
+// is running
+func routedURLs(runtime *environment.Runtime) []string {
+	state := runningRouter()
+	if state == nil || runtime == nil {
+		return nil
+	}
+
+	scheme := "http"
+	if state.TLS {
+		scheme = "https"
+	}
+
+	var urls []string
+	for _, port := range state.Ports {
+		if mapped, ok := runtime.Ports[port]; ok {
+			urls = append(urls, fmt.Sprintf("%s://%s:%d → %d", scheme,
+				router.Hostname(runtime.Project, runtime.Environment), port, mapped))
+		}
+	}
+	return urls
+}
@@ -0,0 +1,132 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/caoer/denv/internal/environment"
+	"github.com/caoer/denv/internal/paths"
+)
+
+func routedGet(t *testing.T, port int, host string) (int, string) {
+	req, err := http.NewRequest(http.MethodGet, "http://127.0.0.1:"+strconv.Itoa(port)+"/", nil)
+	require.NoError(t, err)
+	req.Host = host
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func addRoutedEnvironment(t *testing.T, tmpDir, env string, orig int) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, env)
+	}))
+	t.Cleanup(backend.Close)
+	u, _ := url.Parse(backend.URL)
+	mapped, _ := strconv.Atoi(u.Port())
+
+	rt := environment.NewRuntime("routertest", env)
+	rt.Ports[orig] = mapped
+	envPath := filepath.Join(tmpDir, "routertest-"+env)
+	require.NoError(t, os.MkdirAll(envPath, 0755))
+	require.NoError(t, environment.SaveRuntime(envPath, rt))
+}
+
+func TestRouter_RoutesEnvironments(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.Setenv("DENV_HOME", tmpDir)
+	orig := freePort(t)
+	addRoutedEnvironment(t, tmpDir, "main", orig)
+
+	server := newRouterServer(nil, "127.0.0.1")
+	defer server.close()
+
+	var out bytes.Buffer
+	server.refresh(&out)
+	assert.Contains(t, out.String(), "http://*.localhost:"+strconv.Itoa(orig))
+
+	status, body := routedGet(t, orig, "main.routertest.localhost")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "main", body)
+
+	// Test: New environments are picked up on refresh
+	addRoutedEnvironment(t, tmpDir, "feature", orig)
+	server.refresh(&out)
+	_, body = routedGet(t, orig, "feature.routertest.localhost:"+strconv.Itoa(orig))
+	assert.Equal(t, "feature", body)
+
+	// Test: ps lists routed URLs while the router runs
+	rt, err := environment.LoadRuntime(filepath.Join(tmpDir, "routertest-feature"))
+	require.NoError(t, err)
+	urls := routedURLs(rt)
+	require.Len(t, urls, 1)
+	assert.Contains(t, urls[0], "http://feature.routertest.localhost:"+strconv.Itoa(orig))
+
+	// Test: Removed environments stop being routed
+	require.NoError(t, os.RemoveAll(filepath.Join(tmpDir, "routertest-feature")))
+	server.refresh(&out)
+	status, _ = routedGet(t, orig, "feature.routertest.localhost")
+	assert.Equal(t, http.StatusNotFound, status)
+
+	server.close()
+	assert.NoFileExists(t, paths.RouterPath())
+	assert.Empty(t, routedURLs(rt))
+}
+
+func TestRouter_TLS(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.Setenv("DENV_HOME", tmpDir)
+	orig := freePort(t)
+	addRoutedEnvironment(t, tmpDir, "main", orig)
+
+	server := newRouterServer(nil, "127.0.0.1")
+	defer server.close()
+	var err error
+	server.tls, err = serverTLSConfig()
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	server.refresh(&out)
+	assert.Contains(t, out.String(), "https://*.localhost:"+strconv.Itoa(orig))
+
+	// Test: The certificate verifies against the exported root
+	var pem bytes.Buffer
+	require.NoError(t, CAExport(&pem))
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(pem.Bytes()))
+
+	host := "main.routertest.localhost"
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{RootCAs: roots},
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, "127.0.0.1:"+strconv.Itoa(orig))
+		},
+	}}
+	resp, err := client.Get("https://" + host + ":" + strconv.Itoa(orig) + "/")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "main", string(body))
+
+	rt, err := environment.LoadRuntime(filepath.Join(tmpDir, "routertest-main"))
+	require.NoError(t, err)
+	urls := routedURLs(rt)
+	require.Len(t, urls, 1)
+	assert.Contains(t, urls[0], "https://"+host)
+}
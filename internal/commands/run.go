@@ -0,0 +1,194 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/caoer/denv/internal/color"
+	"github.com/caoer/denv/internal/environment"
+	"github.com/caoer/denv/internal/ports"
+	"github.com/caoer/denv/internal/procfile"
+	"github.com/caoer/denv/internal/process"
+	"github.com/caoer/denv/internal/shell"
+)
+
+const (
+	// DefaultProcfileBasePort is the original port of the first process;
+	// each further process gets the next multiple of 100, like foreman
+	DefaultProcfileBasePort = 5000
+	procfilePortStep        = 100
+	procfileStopGrace       = 10 * time.Second
+)
+
+// RunProcfile runs every process of a Procfile inside an environment until
+// one of them exits or denv is interrupted, then stops the rest
+func RunProcfile(procfilePath, envName string, basePort int, w io.Writer) error {
+	if procfilePath == "" {
+		procfilePath = "Procfile"
+	}
+	entries, err := procfile.Load(procfilePath)
+	if err != nil {
+		return fmt.Errorf("failed to read Procfile: %w", err)
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("no processes in %s", procfilePath)
+	}
+
+	cfg, runtime, envPath, err := loadTargetEnvironment(envName)
+	if err != nil {
+		return err
+	}
+
+	// Give every process its own port through the environment's manager
+	pm := ports.NewPortManager(envPath)
+	pm.InitializeWithPorts(runtime.Ports)
+	processPorts := make([]int, len(entries))
+	for i := range entries {
+		orig := basePort + i*procfilePortStep
+		if _, ok := runtime.Ports[orig]; !ok {
+			runtime.Ports[orig] = pm.GetPort(orig)
+		}
+		processPorts[i] = runtime.Ports[orig]
+	}
+	if err := environment.SaveRuntime(envPath, runtime); err != nil {
+		return fmt.Errorf("failed to save runtime: %w", err)
+	}
+
+	env, _ := environmentVariables(cfg, runtime, envPath, "")
+	var base []string
+	for k, v := range env {
+		base = append(base, k+"="+v)
+	}
+
+	width := 0
+	for _, e := range entries {
+		if len(e.Name) > width {
+			width = len(e.Name)
+		}
+	}
+
+	var mu sync.Mutex
+	type exit struct {
+		name string
+		err  error
+	}
+	exits := make(chan exit, len(entries))
+	var cmds []*exec.Cmd
+	var writers []*prefixWriter
+
+	stopAll := func() {
+		var wg sync.WaitGroup
+		for _, cmd := range cmds {
+			wg.Add(1)
+			go func(pid int) {
+				defer wg.Done()
+				_ = process.Stop(pid, procfileStopGrace)
+			}(cmd.Process.Pid)
+		}
+		wg.Wait()
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	for i, e := range entries {
+		prefix := fmt.Sprintf("%s%-*s |%s ", shell.GetColorForEnvironment(e.Name), width, e.Name, color.Reset)
+		out := &prefixWriter{w: w, mu: &mu, prefix: prefix}
+		writers = append(writers, out)
+
+		cmd := process.Command(e.Command)
+		cmd.Env = append(append([]string{}, base...),
+			"PORT="+strconv.Itoa(processPorts[i]),
+			"PS="+e.Name,
+		)
+		cmd.Stdout = out
+		cmd.Stderr = out
+		cmd.WaitDelay = time.Second
+		process.NewGroup(cmd)
+
+		if err := cmd.Start(); err != nil {
+			stopAll()
+			return fmt.Errorf("failed to start %s: %w", e.Name, err)
+		}
+		out.Write([]byte(fmt.Sprintf("started with PORT=%d (pid %d)\n", processPorts[i], cmd.Process.Pid)))
+		cmds = append(cmds, cmd)
+
+		go func(name string, cmd *exec.Cmd) {
+			exits <- exit{name: name, err: cmd.Wait()}
+		}(e.Name, cmd)
+	}
+
+	var result error
+	var reason string
+	remaining := len(cmds)
+	select {
+	case sig := <-signals:
+		reason = fmt.Sprintf("Received %s", sig)
+	case first := <-exits:
+		remaining--
+		if first.err != nil {
+			result = fmt.Errorf("%s exited: %w", first.name, first.err)
+		}
+		reason = fmt.Sprintf("%s exited", first.name)
+	}
+
+	mu.Lock()
+	fmt.Fprintf(w, "%s, stopping all processes\n", reason)
+	mu.Unlock()
+
+	stopAll()
+	for ; remaining > 0; remaining-- {
+		<-exits
+	}
+	for _, out := range writers {
+		out.Flush()
+	}
+	return result
+}
+
+// prefixWriter writes complete lines to w, each preceded by prefix. Writers
+// sharing mu never interleave within a line.
+type prefixWriter struct {
+	w      io.Writer
+	mu     *sync.Mutex
+	prefix string
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(data []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.buf = append(p.buf, data...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if _, err := fmt.Fprintf(p.w, "%s%s\n", p.prefix, p.buf[:i]); err != nil {
+			return 0, err
+		}
+		p.buf = p.buf[i+1:]
+	}
+	return len(data), nil
+}
+
+// Flush writes out a trailing partial line
+func (p *prefixWriter) Flush() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.buf) > 0 {
+		fmt.Fprintf(p.w, "%s%s\n", p.prefix, p.buf)
+		p.buf = nil
+	}
+}
@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/caoer/denv/internal/environment"
+	"github.com/caoer/denv/internal/testutil"
+)
+
+func setupRunProject(t *testing.T) (string, string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell processes")
+	}
+
+	return testutil.SetupProject(t, "runtest")
+}
+
+func TestRunProcfile(t *testing.T) {
+	tmpDir, tmpProject := setupRunProject(t)
+	require.NoError(t, Enter("dev"))
+
+	marker := filepath.Join(tmpDir, "web-stopped")
+	procfile := `web: trap 'touch ` + marker + `; exit 0' TERM; echo "web PORT=$PORT PS=$PS env=$DENV_ENV_NAME"; while true; do sleep 0.05; done
+worker: echo "worker PORT=$PORT"; sleep 0.5; exit 3
+`
+	require.NoError(t, os.WriteFile(filepath.Join(tmpProject, "Procfile"), []byte(procfile), 0644))
+
+	var out bytes.Buffer
+	err := RunProcfile("", "dev", DefaultProcfileBasePort, &out)
+
+	// Test: One process exiting stops the others
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "worker exited")
+	assert.FileExists(t, marker)
+
+	rt, err := environment.LoadRuntime(filepath.Join(tmpDir, "runtest-dev"))
+	require.NoError(t, err)
+	webPort, ok := rt.Ports[5000]
+	require.True(t, ok)
+	workerPort, ok := rt.Ports[5100]
+	require.True(t, ok)
+	assert.NotEqual(t, webPort, workerPort)
+
+	// Test: Output is prefixed and each process gets its own PORT
+	assert.Contains(t, out.String(), "web    |\033[0m web PORT="+strconv.Itoa(webPort)+" PS=web env=dev")
+	assert.Contains(t, out.String(), "worker |\033[0m worker PORT="+strconv.Itoa(workerPort))
+	assert.Contains(t, out.String(), "worker exited, stopping all processes")
+}
+
+func TestRunProcfile_Errors(t *testing.T) {
+	_, tmpProject := setupRunProject(t)
+
+	err := RunProcfile("", "dev", DefaultProcfileBasePort, &bytes.Buffer{})
+	assert.ErrorContains(t, err, "failed to read Procfile")
+
+	require.NoError(t, os.WriteFile(filepath.Join(tmpProject, "Procfile"), []byte("# empty\n"), 0644))
+	err = RunProcfile("", "dev", DefaultProcfileBasePort, &bytes.Buffer{})
+	assert.ErrorContains(t, err, "no processes")
+}
+
+func TestPrefixWriter(t *testing.T) {
+	var out bytes.Buffer
+	var mu sync.Mutex
+	w := &prefixWriter{w: &out, mu: &mu, prefix: "a | "}
+
+	_, _ = w.Write([]byte("one\ntw"))
+	_, _ = w.Write([]byte("o\nthree"))
+	assert.Equal(t, "a | one\na | two\n", out.String())
+
+	w.Flush()
+	assert.Equal(t, "a | one\na | two\na | three\n", out.String())
+}
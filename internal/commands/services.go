@@ -0,0 +1,436 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/caoer/denv/internal/config"
+	"github.com/caoer/denv/internal/environment"
+	"github.com/caoer/denv/internal/override"
+	"github.com/caoer/denv/internal/paths"
+	"github.com/caoer/denv/internal/ports"
+	"github.com/caoer/denv/internal/probe"
+	"github.com/caoer/denv/internal/process"
+	"github.com/caoer/denv/internal/project"
+	"github.com/caoer/denv/internal/supervisor"
+)
+
+const (
+	defaultReadyTimeout = 30 * time.Second
+	serviceStopGrace    = 15 * time.Second
+)
+
+// supervisorCommand returns the command line of a service supervisor; the
+// spec path is appended. Tests replace it.
+var supervisorCommand = func() ([]string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	return []string{exe, "supervise"}, nil
+}
+
+// Up starts the configured services (or the named ones) of an environment
+func Up(envName string, names []string, w io.Writer) error {
+	cfg, runtime, envPath, err := loadTargetEnvironment(envName)
+	if err != nil {
+		return err
+	}
+	return startServices(cfg, runtime, envPath, names, false, w)
+}
+
+// Down stops the running services (or the named ones) of an environment
+func Down(envName string, names []string, w io.Writer) error {
+	_, _, envPath, err := loadTargetEnvironment(envName)
+	if err != nil {
+		return err
+	}
+	return stopServices(envPath, names, false, w)
+}
+
+// Logs prints the log of a service, or of all services if name is empty
+func Logs(envName, name string, follow bool, w io.Writer) error {
+	_, _, envPath, err := loadTargetEnvironment(envName)
+	if err != nil {
+		return err
+	}
+	logDir := filepath.Join(envPath, "logs")
+
+	if name == "" {
+		if follow {
+			return fmt.Errorf("service name required with --follow")
+		}
+		entries, _ := os.ReadDir(logDir)
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".log") {
+				continue
+			}
+			fmt.Fprintf(w, "==> %s <==\n", strings.TrimSuffix(entry.Name(), ".log"))
+			if err := copyLog(filepath.Join(logDir, entry.Name()), 0, w); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	logPath := filepath.Join(logDir, name+".log")
+	if _, err := os.Stat(logPath); os.IsNotExist(err) {
+		return fmt.Errorf("no logs for service '%s'", name)
+	}
+
+	offset := int64(0)
+	for {
+		if err := copyLog(logPath, offset, w); err != nil {
+			return err
+		}
+		if info, err := os.Stat(logPath); err == nil {
+			offset = info.Size()
+		}
+		if !follow {
+			return nil
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
+
+// Supervise runs a single service described by a spec file until it exits
+// for good or denv down stops it. It is started in the background by up.
+func Supervise(specPath string) error {
+	spec, err := supervisor.ReadSpec(specPath)
+	if err != nil {
+		return fmt.Errorf("failed to read service spec: %w", err)
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	return supervisor.Run(spec, stop)
+}
+
+// loadTargetEnvironment resolves the environment a command acts on:
+// the named one, else the current one, else "default"
+func loadTargetEnvironment(envName string) (*config.Config, *environment.Runtime, string, error) {
+	if envName == "" {
+		envName = os.Getenv("DENV_ENV_NAME")
+	}
+	if envName == "" {
+		envName = "default"
+	}
+
+	cwd, _ := os.Getwd()
+	cfg, _ := config.LoadConfig(filepath.Join(paths.DenvHome(), "config.yaml"))
+	projectName := project.DetectProjectWithConfig(cwd, cfg)
+	cfg = withRepoConfig(cfg, cwd)
+
+	envPath := paths.EnvironmentPath(projectName, envName)
+	runtime, err := environment.LoadRuntime(envPath)
+	if err != nil || runtime == nil {
+		return nil, nil, "", fmt.Errorf("environment '%s' does not exist (run 'denv enter %s' first)", envName, envName)
+	}
+	return cfg, runtime, envPath, nil
+}
+
+// startServices starts the named services (all if names is empty, or only
+// the autostart ones if autostart is set) and waits until they are ready
+func startServices(cfg *config.Config, runtime *environment.Runtime, envPath string, names []string, autostart bool, w io.Writer) error {
+	if cfg == nil || len(cfg.Services) == 0 {
+		if len(names) > 0 || !autostart {
+			return fmt.Errorf("no services configured")
+		}
+		return nil
+	}
+
+	selected, err := selectServices(cfg.Services, names)
+	if err != nil {
+		return err
+	}
+	if autostart {
+		var auto []string
+		for _, name := range selected {
+			if cfg.Services[name].Autostart {
+				auto = append(auto, name)
+			}
+		}
+		selected = auto
+	}
+	if len(selected) == 0 {
+		return nil
+	}
+
+	// Every service port gets a mapping, even if no variable mentions it
+	pm := ports.NewPortManager(envPath)
+	pm.InitializeWithPorts(runtime.Ports)
+	for port := range servicePorts(cfg) {
+		if _, ok := runtime.Ports[port]; !ok {
+			runtime.Ports[port] = pm.GetPort(port)
+		}
+	}
+	if err := environment.SaveRuntime(envPath, runtime); err != nil {
+		return fmt.Errorf("failed to save runtime: %w", err)
+	}
+
+	env, _ := environmentVariables(cfg, runtime, envPath, "")
+	cwd, _ := os.Getwd()
+
+	for _, name := range selected {
+		svc := cfg.Services[name]
+		if pid := servicePid(envPath, name); pid != 0 {
+			fmt.Fprintf(w, "• %s already running (pid %d)\n", name, pid)
+			continue
+		}
+
+		spec, err := serviceSpec(name, svc, env, envPath, cwd)
+		if err != nil {
+			return err
+		}
+		spec.Autostart = autostart
+
+		if err := launchSupervisor(envPath, spec); err != nil {
+			return fmt.Errorf("failed to start service '%s': %w", name, err)
+		}
+
+		if svc.Ready != nil {
+			if err := waitReady(svc.Ready, runtime.Ports, spec.Env); err != nil {
+				_ = stopServices(envPath, []string{name}, false, io.Discard)
+				return fmt.Errorf("service '%s' is not ready: %v (see %s)", name, err, spec.LogPath)
+			}
+		}
+
+		fmt.Fprintf(w, "✓ %s started%s\n", name, describeServicePorts(svc.Ports, runtime.Ports))
+	}
+	return nil
+}
+
+// stopServices stops the named running services (all if names is empty, or
+// only those that were autostarted if autostart is set)
+func stopServices(envPath string, names []string, autostart bool, w io.Writer) error {
+	if len(names) == 0 {
+		names = runningServices(envPath)
+	}
+
+	for _, name := range names {
+		pid := servicePid(envPath, name)
+		if pid == 0 {
+			fmt.Fprintf(w, "• %s is not running\n", name)
+			continue
+		}
+		if autostart {
+			spec, err := supervisor.ReadSpec(serviceFile(envPath, name, ".json"))
+			if err != nil || !spec.Autostart {
+				continue
+			}
+		}
+
+		// The supervisor stops the service's process group before exiting
+		if err := process.Stop(pid, serviceStopGrace); err != nil && process.Alive(pid) {
+			return fmt.Errorf("failed to stop service '%s': %w", name, err)
+		}
+		_ = os.Remove(serviceFile(envPath, name, ".pid"))
+		fmt.Fprintf(w, "✓ %s stopped\n", name)
+	}
+	return nil
+}
+
+// runningServices returns the names of services with a live supervisor
+func runningServices(envPath string) []string {
+	entries, _ := os.ReadDir(filepath.Join(envPath, "services"))
+
+	var names []string
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".pid")
+		if name != entry.Name() && servicePid(envPath, name) != 0 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// servicePorts returns the original ports of all configured services
+func servicePorts(cfg *config.Config) map[int]bool {
+	result := make(map[int]bool)
+	if cfg == nil {
+		return result
+	}
+	for _, svc := range cfg.Services {
+		for _, port := range svc.Ports {
+			result[port] = true
+		}
+	}
+	return result
+}
+
+// selectServices validates names against the configured services; no names
+// selects them all
+func selectServices(services map[string]config.Service, names []string) ([]string, error) {
+	if len(names) == 0 {
+		for name := range services {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return names, nil
+	}
+
+	for _, name := range names {
+		if _, ok := services[name]; !ok {
+			return nil, fmt.Errorf("unknown service '%s'", name)
+		}
+	}
+	return names, nil
+}
+
+// serviceSpec builds the supervisor spec of a service
+func serviceSpec(name string, svc config.Service, env map[string]string, envPath, dir string) (supervisor.Spec, error) {
+	if svc.Command == "" {
+		return supervisor.Spec{}, fmt.Errorf("service '%s' has no command", name)
+	}
+
+	restart := svc.Restart
+	switch restart {
+	case "":
+		restart = supervisor.RestartNo
+	case supervisor.RestartNo, supervisor.RestartOnFailure, supervisor.RestartAlways:
+	default:
+		return supervisor.Spec{}, fmt.Errorf("service '%s' has unknown restart policy '%s'", name, restart)
+	}
+
+	vars := make(map[string]string, len(env)+len(svc.Env)+1)
+	for k, v := range env {
+		vars[k] = v
+	}
+	vars["DENV_SERVICE"] = name
+	for k, v := range svc.Env {
+		vars[k] = os.Expand(v, func(key string) string { return env[key] })
+	}
+
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	environ := make([]string, 0, len(keys))
+	for _, k := range keys {
+		environ = append(environ, k+"="+vars[k])
+	}
+
+	return supervisor.Spec{
+		Name:    name,
+		Command: svc.Command,
+		Env:     environ,
+		Dir:     dir,
+		Restart: restart,
+		LogPath: filepath.Join(envPath, "logs", name+".log"),
+		PidPath: serviceFile(envPath, name, ".pid"),
+	}, nil
+}
+
+// launchSupervisor starts a detached supervisor for spec and records its pid
+func launchSupervisor(envPath string, spec supervisor.Spec) error {
+	specPath := serviceFile(envPath, spec.Name, ".json")
+	if err := supervisor.WriteSpec(specPath, spec); err != nil {
+		return err
+	}
+
+	argv, err := supervisorCommand()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(argv[0], append(argv[1:], specPath)...)
+	cmd.Dir = spec.Dir
+	process.Detach(cmd)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	pid := cmd.Process.Pid
+	if err := os.WriteFile(spec.PidPath, []byte(strconv.Itoa(pid)), 0644); err != nil {
+		_ = process.Kill(pid)
+		return err
+	}
+
+	// Reap the supervisor if it exits while we are still running
+	go func() { _ = cmd.Wait() }()
+	return nil
+}
+
+// waitReady waits for a service's readiness probe
+func waitReady(ready *config.Probe, portMap map[int]int, env []string) error {
+	timeout := defaultReadyTimeout
+	if ready.Timeout != "" {
+		d, err := time.ParseDuration(ready.Timeout)
+		if err != nil {
+			return fmt.Errorf("invalid timeout %q", ready.Timeout)
+		}
+		timeout = d
+	}
+
+	p := probe.Probe{Env: env}
+	switch {
+	case ready.TCP != 0:
+		port := ready.TCP
+		if mapped, ok := portMap[port]; ok {
+			port = mapped
+		}
+		p.TCP = "127.0.0.1:" + strconv.Itoa(port)
+	case ready.HTTP != "":
+		p.HTTP = override.RewriteURL(ready.HTTP, portMap)
+	case ready.Exec != "":
+		p.Exec = process.ShellCommand(ready.Exec)
+	default:
+		return nil
+	}
+
+	return p.Wait(timeout, 250*time.Millisecond)
+}
+
+// servicePid returns the pid of a service's running supervisor, or 0
+func servicePid(envPath, name string) int {
+	data, err := os.ReadFile(serviceFile(envPath, name, ".pid"))
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || !process.Alive(pid) {
+		return 0
+	}
+	return pid
+}
+
+// serviceFile returns the path of a service's state file with ext
+func serviceFile(envPath, name, ext string) string {
+	return filepath.Join(envPath, "services", name+ext)
+}
+
+// describeServicePorts formats the mapped ports of a service
+func describeServicePorts(servicePorts []int, portMap map[int]int) string {
+	var parts []string
+	for _, port := range servicePorts {
+		parts = append(parts, fmt.Sprintf("%d→%d", port, portMap[port]))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " (ports " + strings.Join(parts, " ") + ")"
+}
+
+// copyLog copies a log file from offset to w
+func copyLog(path string, offset int64, w io.Writer) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	_, err = io.Copy(w, f)
+	return err
+}
@@ -0,0 +1,138 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/caoer/denv/internal/environment"
+	"github.com/caoer/denv/internal/process"
+	"github.com/caoer/denv/internal/testutil"
+)
+
+// TestSupervisorHelperProcess is run as the service supervisor by the
+// tests below instead of the denv binary
+func TestSupervisorHelperProcess(t *testing.T) {
+	if os.Getenv("DENV_SUPERVISOR_HELPER") != "1" {
+		return
+	}
+	err := Supervise(os.Args[len(os.Args)-1])
+	if err != nil {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+const servicesConfig = `services:
+  web:
+    command: echo "port=$PORT_4000 mode=$APP_MODE service=$DENV_SERVICE"; touch "$DENV_ENV/web-ready"; exec sleep 30
+    env:
+      APP_MODE: ${DENV_ENV_NAME}-mode
+    ports: [4000]
+    ready:
+      exec: test -f "$DENV_ENV/web-ready"
+      timeout: 5s
+  broken:
+    command: exit 1
+    ready:
+      exec: "false"
+      timeout: 300ms
+`
+
+func setupServicesProject(t *testing.T, cfg string) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell services")
+	}
+
+	tmpDir, _ := testutil.SetupProject(t, "svctest")
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "config.yaml"), []byte(cfg), 0644))
+
+	t.Setenv("DENV_SUPERVISOR_HELPER", "1")
+	original := supervisorCommand
+	supervisorCommand = func() ([]string, error) {
+		return []string{os.Args[0], "-test.run=^TestSupervisorHelperProcess$", "--"}, nil
+	}
+	t.Cleanup(func() { supervisorCommand = original })
+	return tmpDir
+}
+
+func TestServices_UpLogsDown(t *testing.T) {
+	tmpDir := setupServicesProject(t, servicesConfig)
+	envPath := filepath.Join(tmpDir, "svctest-dev")
+
+	require.NoError(t, Enter("dev"))
+	t.Cleanup(func() { _ = stopServices(envPath, nil, false, &bytes.Buffer{}) })
+
+	// Test: Service ports are mapped on enter
+	rt, err := environment.LoadRuntime(envPath)
+	require.NoError(t, err)
+	mapped, ok := rt.Ports[4000]
+	require.True(t, ok)
+
+	var out bytes.Buffer
+	require.NoError(t, Up("dev", []string{"web"}, &out))
+	assert.Contains(t, out.String(), "web started (ports 4000→"+strconv.Itoa(mapped)+")")
+	assert.Equal(t, []string{"web"}, runningServices(envPath))
+	pid := servicePid(envPath, "web")
+
+	// Test: Service sees the environment's variables
+	out.Reset()
+	require.NoError(t, Logs("dev", "web", false, &out))
+	assert.Contains(t, out.String(), "port="+strconv.Itoa(mapped)+" mode=dev-mode service=web")
+
+	out.Reset()
+	require.NoError(t, Up("dev", []string{"web"}, &out))
+	assert.Contains(t, out.String(), "already running")
+
+	out.Reset()
+	require.NoError(t, Down("dev", nil, &out))
+	assert.Contains(t, out.String(), "web stopped")
+	assert.False(t, process.Alive(pid))
+	assert.Empty(t, runningServices(envPath))
+}
+
+func TestServices_NotReady(t *testing.T) {
+	tmpDir := setupServicesProject(t, servicesConfig)
+	envPath := filepath.Join(tmpDir, "svctest-dev")
+	require.NoError(t, Enter("dev"))
+
+	err := Up("dev", []string{"broken"}, &bytes.Buffer{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not ready")
+	assert.Empty(t, runningServices(envPath))
+
+	err = Up("dev", []string{"missing"}, &bytes.Buffer{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown service")
+}
+
+func TestServices_Autostart(t *testing.T) {
+	cfg := `services:
+  worker:
+    command: exec sleep 30
+    autostart: true
+`
+	tmpDir := setupServicesProject(t, cfg)
+	envPath := filepath.Join(tmpDir, "svctest-dev")
+
+	require.NoError(t, Enter("dev"))
+	t.Cleanup(func() { _ = stopServices(envPath, nil, false, &bytes.Buffer{}) })
+	assert.Equal(t, []string{"worker"}, runningServices(envPath))
+
+	// Test: Autostarted services stop with the last session
+	require.NoError(t, stopServices(envPath, nil, true, &bytes.Buffer{}))
+	assert.Empty(t, runningServices(envPath))
+}
+
+func TestServices_RequireEnvironment(t *testing.T) {
+	_ = setupServicesProject(t, servicesConfig)
+
+	err := Up("nope", nil, &bytes.Buffer{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "does not exist")
+}
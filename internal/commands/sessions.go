@@ -7,17 +7,13 @@ import (
 
 	"github.com/caoer/denv/internal/environment"
 	"github.com/caoer/denv/internal/paths"
-	"github.com/caoer/denv/internal/project"
 	"github.com/caoer/denv/internal/session"
 )
 
 func Sessions(cleanup, kill bool) error {
 	// Detect current project
 	cwd, _ := os.Getwd()
-	projectName, err := project.DetectProject(cwd)
-	if err != nil {
-		return fmt.Errorf("failed to detect project: %w", err)
-	}
+	projectName := currentProject(cwd)
 
 	// Get all environments for this project
 	home := paths.DenvHome()
@@ -62,4 +58,19 @@ func Sessions(cleanup, kill bool) error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// countActiveSessions returns the number of sessions whose process is still alive
+func countActiveSessions(runtime *environment.Runtime) int {
+	if runtime == nil {
+		return 0
+	}
+
+	active := 0
+	for _, sess := range runtime.Sessions {
+		if sessionExists(sess.PID) {
+			active++
+		}
+	}
+	return active
+}
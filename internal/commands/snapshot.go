@@ -0,0 +1,252 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/caoer/denv/internal/config"
+	"github.com/caoer/denv/internal/environment"
+	"github.com/caoer/denv/internal/paths"
+	"github.com/caoer/denv/internal/ports"
+	"github.com/caoer/denv/internal/project"
+	"github.com/caoer/denv/internal/snapshot"
+)
+
+// Version is the denv version recorded in snapshots (set by main)
+var Version = "dev"
+
+const snapshotExt = ".tar.gz"
+
+// snapshotListNames are the words `denv snapshot` lists snapshots for in
+// place of an environment name, so no environment or snapshot may use them
+var snapshotListNames = []string{"ls", "list"}
+
+// checkEnvironmentName rejects environment names `denv snapshot` would
+// take for listing
+func checkEnvironmentName(name string) error {
+	for _, reserved := range snapshotListNames {
+		if name == reserved {
+			return fmt.Errorf("'%s' is reserved for 'denv snapshot %s' and can't name an environment", name, name)
+		}
+	}
+	return nil
+}
+
+// Snapshot archives an environment directory under DENV_HOME
+func Snapshot(envName, name string) error {
+	if envName == "" {
+		return fmt.Errorf("environment name required")
+	}
+
+	cwd, _ := os.Getwd()
+	cfg, _ := config.LoadConfig(filepath.Join(paths.DenvHome(), "config.yaml"))
+	projectName := project.DetectProjectWithConfig(cwd, cfg)
+
+	envPath := paths.EnvironmentPath(projectName, envName)
+	if _, err := os.Stat(envPath); os.IsNotExist(err) {
+		return fmt.Errorf("environment '%s' does not exist", envName)
+	}
+
+	now := time.Now()
+	if name == "" {
+		name = fmt.Sprintf("%s-%s", envName, now.Format("20060102-150405"))
+	}
+	if strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("invalid snapshot name: %s", name)
+	}
+	for _, reserved := range snapshotListNames {
+		if name == reserved {
+			return fmt.Errorf("'%s' is reserved for 'denv snapshot %s' and can't name a snapshot", name, name)
+		}
+	}
+
+	archivePath := filepath.Join(paths.SnapshotsPath(projectName), name+snapshotExt)
+	if _, err := os.Stat(archivePath); err == nil {
+		return fmt.Errorf("snapshot '%s' already exists", name)
+	}
+
+	meta := snapshot.Metadata{
+		Name:        name,
+		Project:     projectName,
+		Environment: envName,
+		EnvPath:     envPath,
+		DenvVersion: Version,
+		Created:     now,
+	}
+	if err := snapshot.Create(archivePath, envPath, meta); err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+
+	if runtime, _ := environment.LoadRuntime(envPath); countActiveSessions(runtime) > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: environment has active sessions; snapshot may be inconsistent\n")
+	}
+
+	size := int64(0)
+	if info, err := os.Stat(archivePath); err == nil {
+		size = info.Size()
+	}
+	fmt.Printf("Created snapshot '%s' of environment '%s' (%s)\n", name, envName, formatSize(size))
+	return nil
+}
+
+// SnapshotList lists the snapshots of the current project
+func SnapshotList(w io.Writer) error {
+	cwd, _ := os.Getwd()
+	cfg, _ := config.LoadConfig(filepath.Join(paths.DenvHome(), "config.yaml"))
+	projectName := project.DetectProjectWithConfig(cwd, cfg)
+
+	entries, err := os.ReadDir(paths.SnapshotsPath(projectName))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read snapshots: %w", err)
+	}
+
+	type snapshotRecord struct {
+		meta *snapshot.Metadata
+		size int64
+	}
+	var records []snapshotRecord
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), snapshotExt) {
+			continue
+		}
+		archivePath := filepath.Join(paths.SnapshotsPath(projectName), entry.Name())
+		meta, err := snapshot.ReadMetadata(archivePath)
+		if err != nil {
+			continue
+		}
+		info, _ := entry.Info()
+		size := int64(0)
+		if info != nil {
+			size = info.Size()
+		}
+		records = append(records, snapshotRecord{meta: meta, size: size})
+	}
+
+	if len(records) == 0 {
+		fmt.Fprintf(w, "No snapshots found for project %s\n", projectName)
+		return nil
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].meta.Created.Before(records[j].meta.Created)
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tENVIRONMENT\tCREATED\tSIZE\tVERSION")
+	for _, r := range records {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			r.meta.Name, r.meta.Environment, r.meta.Created.Format("2006-01-02 15:04"),
+			formatSize(r.size), r.meta.DenvVersion)
+	}
+	return tw.Flush()
+}
+
+// Restore replaces an environment with the contents of a snapshot
+func Restore(envName, snapshotName string) error {
+	if envName == "" || snapshotName == "" {
+		return fmt.Errorf("environment and snapshot names required")
+	}
+	if err := checkEnvironmentName(envName); err != nil {
+		return err
+	}
+
+	cwd, _ := os.Getwd()
+	cfg, _ := config.LoadConfig(filepath.Join(paths.DenvHome(), "config.yaml"))
+	projectName := project.DetectProjectWithConfig(cwd, cfg)
+
+	archivePath := filepath.Join(paths.SnapshotsPath(projectName), snapshotName+snapshotExt)
+	if strings.HasSuffix(snapshotName, snapshotExt) {
+		// Allow restoring from an explicit archive path
+		archivePath = snapshotName
+	}
+
+	meta, err := snapshot.ReadMetadata(archivePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("snapshot '%s' does not exist", snapshotName)
+		}
+		return err
+	}
+	if meta.Project != projectName {
+		return fmt.Errorf("snapshot '%s' belongs to project %s, not %s", snapshotName, meta.Project, projectName)
+	}
+
+	envPath := paths.EnvironmentPath(projectName, envName)
+	existing, _ := environment.LoadRuntime(envPath)
+	if active := countActiveSessions(existing); active > 0 {
+		return fmt.Errorf("cannot restore environment with %d active session(s)", active)
+	}
+
+	// Extract next to the environment, then swap directories
+	tmpPath := envPath + ".restore"
+	_ = os.RemoveAll(tmpPath)
+	if _, err := snapshot.Extract(archivePath, tmpPath); err != nil {
+		_ = os.RemoveAll(tmpPath)
+		return fmt.Errorf("failed to extract snapshot: %w", err)
+	}
+
+	runtime, _ := environment.LoadRuntime(tmpPath)
+	if runtime == nil {
+		runtime = environment.NewRuntime(projectName, envName)
+	}
+	runtime.Project = projectName
+	runtime.Environment = envName
+	runtime.Sessions = make(map[string]environment.Session)
+
+	// A snapshot restored into another environment must not share the
+	// source environment's ports: keep the target's mappings, allocate the rest
+	if meta.EnvPath != envPath {
+		_ = os.Remove(filepath.Join(tmpPath, "ports.json"))
+		pm := ports.NewPortManager(tmpPath)
+		for mapped := range getAllProjectEnvironmentPorts(projectName, envName) {
+			pm.Reserve(mapped)
+		}
+		if existing != nil {
+			pm.InitializeWithPorts(existing.Ports)
+		}
+		newPorts := make(map[int]int)
+		for orig := range runtime.Ports {
+			newPorts[orig] = pm.GetPort(orig)
+		}
+		runtime.Overrides = relocateOverrides(runtime.Overrides, meta.EnvPath, envPath, newPorts)
+		runtime.Ports = newPorts
+	}
+
+	if err := environment.SaveRuntime(tmpPath, runtime); err != nil {
+		_ = os.RemoveAll(tmpPath)
+		return fmt.Errorf("failed to save runtime: %w", err)
+	}
+
+	if err := os.RemoveAll(envPath); err != nil {
+		_ = os.RemoveAll(tmpPath)
+		return fmt.Errorf("failed to remove environment: %w", err)
+	}
+	if err := os.Rename(tmpPath, envPath); err != nil {
+		return fmt.Errorf("failed to restore environment: %w", err)
+	}
+
+	fmt.Printf("Restored environment '%s' from snapshot '%s' (taken %s)\n",
+		envName, meta.Name, meta.Created.Format("2006-01-02 15:04"))
+	return nil
+}
+
+// formatSize formats a byte count for humans
+func formatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,125 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/caoer/denv/internal/environment"
+	"github.com/caoer/denv/internal/testutil"
+)
+
+func TestSnapshotAndRestore(t *testing.T) {
+	tmpDir, _ := testutil.SetupProject(t, "snaptest")
+
+	require.NoError(t, Enter("default"))
+	envPath := filepath.Join(tmpDir, "snaptest-default")
+	dbFile := filepath.Join(envPath, "db.sqlite")
+	require.NoError(t, os.WriteFile(dbFile, []byte("v1"), 0644))
+
+	// Test: Take a snapshot
+	require.NoError(t, Snapshot("default", "v1"))
+	assert.FileExists(t, filepath.Join(tmpDir, ".snapshots", "snaptest", "v1.tar.gz"))
+
+	var out bytes.Buffer
+	require.NoError(t, SnapshotList(&out))
+	assert.Contains(t, out.String(), "v1")
+	assert.Contains(t, out.String(), "default")
+
+	// Test: Restore brings back the old state
+	require.NoError(t, os.WriteFile(dbFile, []byte("v2"), 0644))
+	require.NoError(t, Restore("default", "v1"))
+
+	data, err := os.ReadFile(dbFile)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(data))
+
+	runtime, err := environment.LoadRuntime(envPath)
+	require.NoError(t, err)
+	assert.Equal(t, "default", runtime.Environment)
+}
+
+func TestSnapshot_DuplicateName(t *testing.T) {
+	testutil.SetupProject(t, "snapdup")
+
+	require.NoError(t, Enter("default"))
+	require.NoError(t, Snapshot("default", "same"))
+
+	err := Snapshot("default", "same")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "already exists")
+}
+
+func TestSnapshot_ReservedNames(t *testing.T) {
+	tmpDir, _ := testutil.SetupProject(t, "snapls")
+
+	require.NoError(t, Enter("default"))
+
+	// Test: Names 'denv snapshot ls' would take for listing are refused
+	for _, name := range []string{"ls", "list"} {
+		assert.ErrorContains(t, Snapshot("default", name), "reserved")
+		assert.ErrorContains(t, Enter(name), "reserved")
+		assert.ErrorContains(t, Clone("default", name, false), "reserved")
+		assert.NoDirExists(t, filepath.Join(tmpDir, "snapls-"+name))
+	}
+}
+
+func TestRestore_RefusesActiveSessions(t *testing.T) {
+	tmpDir, _ := testutil.SetupProject(t, "snapactive")
+
+	require.NoError(t, Enter("default"))
+	require.NoError(t, Snapshot("default", "v1"))
+
+	envPath := filepath.Join(tmpDir, "snapactive-default")
+	runtime, err := environment.LoadRuntime(envPath)
+	require.NoError(t, err)
+	runtime.Sessions["active-session"] = environment.Session{ID: "active-session", PID: os.Getpid()}
+	require.NoError(t, environment.SaveRuntime(envPath, runtime))
+
+	err = Restore("default", "v1")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "active session")
+}
+
+func TestRestore_RefusesOtherProject(t *testing.T) {
+	tmpDir, _ := testutil.SetupProject(t, "snapfirst")
+
+	require.NoError(t, Enter("default"))
+	require.NoError(t, Snapshot("default", "v1"))
+	archive := filepath.Join(tmpDir, ".snapshots", "snapfirst", "v1.tar.gz")
+
+	// Switch to another project sharing the same DENV_HOME
+	testutil.SetupProject(t, "snapsecond")
+	t.Setenv("DENV_HOME", tmpDir)
+
+	err := Restore("default", archive)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "belongs to project snapfirst")
+}
+
+func TestRestore_IntoOtherEnvironmentRelocates(t *testing.T) {
+	tmpDir, _ := testutil.SetupProject(t, "snapmove")
+
+	os.Setenv("API_PORT", "3000")
+	os.Setenv("CACHE_DIR", "/tmp/cache")
+	defer os.Unsetenv("API_PORT")
+	defer os.Unsetenv("CACHE_DIR")
+
+	require.NoError(t, Enter("default"))
+	require.NoError(t, Snapshot("default", "v1"))
+
+	// Test: Restoring into a new environment gets its own paths and ports
+	require.NoError(t, Restore("feature", "v1"))
+
+	src, err := environment.LoadRuntime(filepath.Join(tmpDir, "snapmove-default"))
+	require.NoError(t, err)
+	dst, err := environment.LoadRuntime(filepath.Join(tmpDir, "snapmove-feature"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "feature", dst.Environment)
+	assert.NotEqual(t, src.Ports[3000], dst.Ports[3000])
+	assert.Equal(t, filepath.Join(tmpDir, "snapmove-feature", "cache"), dst.Overrides["CACHE_DIR"].Current)
+}
@@ -0,0 +1,223 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/caoer/denv/internal/environment"
+	"github.com/caoer/denv/internal/project"
+	"github.com/caoer/denv/internal/shell"
+)
+
+// stackVar holds the activations suspended by denv push, most recent first
+const stackVar = "DENV_STACK"
+
+// enteredVars are the variables denv enter sets besides the port mappings
+// and overrides
+var enteredVars = []string{"DENV_ENV", "DENV_PROJECT", "DENV_ENV_NAME", "DENV_PROJECT_NAME", "DENV_SESSION", "DENV_ISOLATION"}
+
+// Switch prints the commands moving the calling shell (pid) from its
+// current environment to envName, releasing the old session
+func Switch(shellName, envName string, pid int, w io.Writer) error {
+	return evalChanges("switch", shellName, w, func(dir string) (map[string]*string, error) {
+		return switchChanges(dir, envName, pid)
+	})
+}
+
+// Push is Switch keeping the current environment's session open so Pop can
+// return to it
+func Push(shellName, envName string, pid int, w io.Writer) error {
+	return evalChanges("push", shellName, w, func(dir string) (map[string]*string, error) {
+		return pushChanges(dir, envName, pid)
+	})
+}
+
+// Pop prints the commands leaving the current environment for the one
+// suspended by the last Push
+func Pop(shellName string, pid int, w io.Writer) error {
+	return evalChanges("pop", shellName, w, func(string) (map[string]*string, error) {
+		return popChanges(pid)
+	})
+}
+
+// evalChanges writes the changes computed for the current directory as
+// commands for the shell to evaluate. Commands printed to a terminal would
+// change nothing, so that is refused before touching any session.
+func evalChanges(command, shellName string, w io.Writer, compute func(dir string) (map[string]*string, error)) error {
+	if shellName == "" {
+		_, shellName = shell.DetectShell(os.Getenv("SHELL"))
+	}
+	shellType, err := shell.ParseShellName(shellName)
+	if err != nil {
+		return err
+	}
+	if f, ok := w.(*os.File); ok {
+		if info, err := f.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+			return fmt.Errorf("'denv %s' changes the current shell; install the shell hook (eval \"$(denv hook %s)\") or run eval \"$(denv %s ...)\"", command, shellType, command)
+		}
+	}
+
+	cwd, _ := os.Getwd()
+	changes, err := compute(cwd)
+	writeChanges(shellType, changes, w)
+	return err
+}
+
+func switchChanges(dir, envName string, pid int) (map[string]*string, error) {
+	root, err := switchRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	current := currentActivation(dir)
+	if current != nil && current.Root == root && current.Env == envName {
+		fmt.Fprintf(os.Stderr, "denv: already in %s:%s\n", current.Project, current.Env)
+		return nil, nil
+	}
+
+	changes := make(map[string]*string)
+	if current != nil {
+		deactivate(current, pid, changes)
+	}
+	return changes, activate(root, envName, pid, changes)
+}
+
+func pushChanges(dir, envName string, pid int) (map[string]*string, error) {
+	root, err := switchRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+	current := currentActivation(dir)
+	if current == nil {
+		return nil, fmt.Errorf("not in an environment, use 'denv switch %s'", envName)
+	}
+
+	// The new environment is computed from the variables as they were
+	// before the current one, which stays open underneath
+	changes := make(map[string]*string)
+	current.unwind(changes)
+	if err := activate(root, envName, pid, changes); err != nil {
+		current.reapply(changes)
+		return changes, err
+	}
+	setStack(append([]*activation{current}, loadStack()...), changes)
+	return changes, nil
+}
+
+func popChanges(pid int) (map[string]*string, error) {
+	stack := loadStack()
+	if len(stack) == 0 {
+		return nil, errors.New("no environment to pop to")
+	}
+
+	changes := make(map[string]*string)
+	if current := decodeActivation(os.Getenv(activationVar)); current != nil {
+		deactivate(current, pid, changes)
+	}
+	previous := stack[0]
+	previous.reapply(changes)
+	setStack(stack[1:], changes)
+	fmt.Fprintf(os.Stderr, "denv: back in %s:%s\n", previous.Project, previous.Env)
+	return changes, nil
+}
+
+// deactivateAll deactivates the current activation and every one
+// suspended under it
+func deactivateAll(current *activation, pid int, changes map[string]*string) {
+	deactivate(current, pid, changes)
+	for _, a := range loadStack() {
+		deactivate(a, pid, changes)
+	}
+	setStack(nil, changes)
+}
+
+// switchRoot returns the directory an environment switched to from dir is
+// activated for
+func switchRoot(dir string) (string, error) {
+	if root := activationRoot(dir); root != "" {
+		return root, nil
+	}
+	if _, err := project.DetectProject(dir); err != nil {
+		return "", fmt.Errorf("failed to detect project: %w", err)
+	}
+	return dir, nil
+}
+
+// currentActivation returns the activation of the shell, describing a shell
+// started by denv enter as one that doesn't own its session
+func currentActivation(dir string) *activation {
+	if a := decodeActivation(os.Getenv(activationVar)); a != nil {
+		return a
+	}
+	if os.Getenv("DENV_ENV_NAME") == "" {
+		return nil
+	}
+
+	a := &activation{
+		Project: os.Getenv("DENV_PROJECT_NAME"),
+		Env:     os.Getenv("DENV_ENV_NAME"),
+		EnvPath: os.Getenv("DENV_ENV"),
+		Root:    activationRoot(dir),
+		Session: os.Getenv("DENV_SESSION"),
+		Saved:   make(map[string]*string),
+		Values:  make(map[string]string),
+	}
+	if a.Root == "" {
+		a.Root = dir
+	}
+
+	keys := append([]string{}, enteredVars...)
+	saved := make(map[string]string)
+	if runtime, _ := environment.LoadRuntime(a.EnvPath); runtime != nil {
+		for orig := range runtime.Ports {
+			keys = append(keys, fmt.Sprintf("PORT_%d", orig), fmt.Sprintf("ORIGINAL_PORT_%d", orig))
+		}
+		for key, o := range runtime.Overrides {
+			keys = append(keys, key)
+			saved[key] = o.Original
+		}
+	}
+	for _, key := range keys {
+		value, ok := os.LookupEnv(key)
+		if !ok {
+			continue
+		}
+		a.Values[key] = value
+		if original, ok := saved[key]; ok {
+			a.Saved[key] = &original
+		} else {
+			a.Saved[key] = nil
+		}
+	}
+	return a
+}
+
+func loadStack() []*activation {
+	var stack []*activation
+	for _, entry := range strings.Split(os.Getenv(stackVar), ":") {
+		if a := decodeActivation(entry); a != nil {
+			stack = append(stack, a)
+		}
+	}
+	return stack
+}
+
+func setStack(stack []*activation, changes map[string]*string) {
+	if len(stack) == 0 {
+		if _, ok := os.LookupEnv(stackVar); ok {
+			os.Unsetenv(stackVar)
+			changes[stackVar] = nil
+		}
+		return
+	}
+
+	entries := make([]string, len(stack))
+	for i, a := range stack {
+		entries[i] = a.encode()
+	}
+	value := strings.Join(entries, ":")
+	os.Setenv(stackVar, value)
+	changes[stackVar] = &value
+}
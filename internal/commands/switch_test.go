@@ -0,0 +1,159 @@
+package commands
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/caoer/denv/internal/environment"
+)
+
+func TestSwitchChanges(t *testing.T) {
+	tmpDir, tmpProject := setupHookProject(t)
+	unsetenv(t, stackVar)
+	pid := os.Getpid()
+
+	_, err := hookChanges(tmpProject, pid)
+	require.NoError(t, err)
+	defaultPort := os.Getenv("PORT")
+
+	// Test: Switching releases the old session and opens the new one
+	changes, err := switchChanges(tmpProject, "staging", pid)
+	require.NoError(t, err)
+	require.NotNil(t, changes["DENV_ENV_NAME"])
+	assert.Equal(t, "staging", *changes["DENV_ENV_NAME"])
+	assert.NotEqual(t, defaultPort, os.Getenv("PORT"))
+	assert.NotEqual(t, "3000", os.Getenv("PORT"))
+
+	rt, err := environment.LoadRuntime(filepath.Join(tmpDir, "hooktest-default"))
+	require.NoError(t, err)
+	assert.Empty(t, rt.Sessions)
+	rt, err = environment.LoadRuntime(filepath.Join(tmpDir, "hooktest-staging"))
+	require.NoError(t, err)
+	assert.Len(t, rt.Sessions, 1)
+
+	// Test: The hook keeps the switched environment within the project
+	changes, err = hookChanges(filepath.Join(tmpProject, "src"), pid)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+
+	// Test: Switching to the current environment changes nothing
+	changes, err = switchChanges(tmpProject, "staging", pid)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+
+	// Test: Leaving the project restores the original values
+	_, err = hookChanges(t.TempDir(), pid)
+	require.NoError(t, err)
+	assert.Equal(t, "3000", os.Getenv("PORT"))
+	_, ok := os.LookupEnv("DENV_ENV_NAME")
+	assert.False(t, ok)
+}
+
+func TestPushPopChanges(t *testing.T) {
+	tmpDir, tmpProject := setupHookProject(t)
+	unsetenv(t, stackVar)
+	pid := os.Getpid()
+
+	// Test: Push needs an environment to suspend
+	_, err := pushChanges(tmpProject, "staging", pid)
+	assert.Error(t, err)
+
+	_, err = hookChanges(tmpProject, pid)
+	require.NoError(t, err)
+	defaultPort := os.Getenv("PORT")
+	defaultSession := os.Getenv("DENV_SESSION")
+
+	// Test: Push keeps the suspended session and computes the new
+	// environment from the original values
+	changes, err := pushChanges(tmpProject, "staging", pid)
+	require.NoError(t, err)
+	require.NotNil(t, changes[stackVar])
+	assert.Equal(t, "staging", os.Getenv("DENV_ENV_NAME"))
+	stagingRT, err := environment.LoadRuntime(filepath.Join(tmpDir, "hooktest-staging"))
+	require.NoError(t, err)
+	assert.Equal(t, "3000", stagingRT.Overrides["PORT"].Original)
+
+	rt, err := environment.LoadRuntime(filepath.Join(tmpDir, "hooktest-default"))
+	require.NoError(t, err)
+	assert.Contains(t, rt.Sessions, defaultSession)
+
+	// Test: Pop returns to the suspended environment
+	changes, err = popChanges(pid)
+	require.NoError(t, err)
+	assert.Contains(t, changes, stackVar)
+	assert.Nil(t, changes[stackVar])
+	assert.Equal(t, "default", os.Getenv("DENV_ENV_NAME"))
+	assert.Equal(t, defaultPort, os.Getenv("PORT"))
+	assert.Equal(t, defaultSession, os.Getenv("DENV_SESSION"))
+
+	stagingRT, err = environment.LoadRuntime(filepath.Join(tmpDir, "hooktest-staging"))
+	require.NoError(t, err)
+	assert.Empty(t, stagingRT.Sessions)
+
+	// Test: Nothing left to pop
+	_, err = popChanges(pid)
+	assert.Error(t, err)
+
+	// Test: Leaving the project with a pushed environment unwinds both
+	_, err = pushChanges(tmpProject, "staging", pid)
+	require.NoError(t, err)
+	_, err = hookChanges(t.TempDir(), pid)
+	require.NoError(t, err)
+	assert.Equal(t, "3000", os.Getenv("PORT"))
+	_, ok := os.LookupEnv(stackVar)
+	assert.False(t, ok)
+	rt, err = environment.LoadRuntime(filepath.Join(tmpDir, "hooktest-default"))
+	require.NoError(t, err)
+	assert.Empty(t, rt.Sessions)
+}
+
+func TestSwitchChanges_FromEnteredShell(t *testing.T) {
+	tmpDir, tmpProject := setupHookProject(t)
+	unsetenv(t, stackVar)
+	pid := os.Getpid()
+
+	// Simulate a shell started by denv enter
+	oldCwd, _ := os.Getwd()
+	require.NoError(t, os.Chdir(tmpProject))
+	defer func() { _ = os.Chdir(oldCwd) }()
+	require.NoError(t, Enter("default"))
+	envPath := filepath.Join(tmpDir, "hooktest-default")
+	rt, err := environment.LoadRuntime(envPath)
+	require.NoError(t, err)
+	rt.Sessions["entered"] = environment.Session{ID: "entered", PID: os.Getpid()}
+	require.NoError(t, environment.SaveRuntime(envPath, rt))
+	t.Setenv("DENV_SESSION", "entered")
+	t.Setenv("DENV_ENV", envPath)
+	t.Setenv("DENV_ENV_NAME", "default")
+	t.Setenv("DENV_PROJECT_NAME", "hooktest")
+	t.Setenv("PORT", rt.Overrides["PORT"].Current)
+
+	// Test: Switching unwinds the overrides recorded by denv enter
+	changes, err := switchChanges(tmpProject, "staging", pid)
+	require.NoError(t, err)
+	assert.Equal(t, "staging", os.Getenv("DENV_ENV_NAME"))
+	require.NotNil(t, changes["PORT"])
+	assert.NotEqual(t, rt.Overrides["PORT"].Current, *changes["PORT"])
+	stagingRT, err := environment.LoadRuntime(filepath.Join(tmpDir, "hooktest-staging"))
+	require.NoError(t, err)
+	assert.Equal(t, "3000", stagingRT.Overrides["PORT"].Original)
+
+	// Test: The session of denv enter is left to it
+	rt, err = environment.LoadRuntime(envPath)
+	require.NoError(t, err)
+	assert.Contains(t, rt.Sessions, "entered")
+}
+
+func TestSwitch_UnknownShell(t *testing.T) {
+	var buf bytes.Buffer
+	unsetenv(t, activationVar)
+
+	// Test: An unknown shell is rejected before anything changes
+	err := Switch("tcsh", "staging", os.Getpid(), &buf)
+	assert.Error(t, err)
+	assert.Empty(t, buf.String())
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/caoer/denv/internal/environment"
 )
 
 func TestCreateProjectSymlinks(t *testing.T) {
@@ -240,4 +241,39 @@ func TestCleanupOldSymlinks(t *testing.T) {
 	// New symlinks should exist
 	assert.FileExists(t, filepath.Join(denvDir, "*app-test"))
 	assert.FileExists(t, filepath.Join(denvDir, "app"))
-}
\ No newline at end of file
+}
+
+func TestDashedProjectNames(t *testing.T) {
+	// "other" and "other-api", as when a second api repository is qualified
+	tmpDir := t.TempDir()
+	t.Setenv("DENV_HOME", tmpDir)
+	projectDir := filepath.Join(t.TempDir(), "other")
+	require.NoError(t, os.MkdirAll(projectDir, 0755))
+	_ = os.Chdir(projectDir)
+
+	for _, env := range []struct {
+		project, name string
+		port          int
+	}{
+		{"other", "dev", 31000},
+		{"other-api", "dev", 32000},
+	} {
+		envPath := filepath.Join(tmpDir, env.project+"-"+env.name)
+		require.NoError(t, os.MkdirAll(envPath, 0755))
+		rt := environment.NewRuntime(env.project, env.name)
+		rt.Ports[3000] = env.port
+		require.NoError(t, environment.SaveRuntime(envPath, rt))
+		require.NoError(t, createProjectSymlinks(projectDir, envPath, filepath.Join(tmpDir, env.project), env.project, env.name))
+	}
+
+	// Test: Ports of a project whose name extends this one's aren't its own
+	assert.Equal(t, map[int]string{31000: "dev"}, getAllProjectEnvironmentPorts("other", ""))
+
+	// Test: Leaving the last environment removes the project link even when
+	// a project whose name extends this one's is still linked
+	cleanupProjectSymlinks("other", "dev")
+	_, err := os.Lstat(filepath.Join(projectDir, ".denv", "other"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Lstat(filepath.Join(projectDir, ".denv", "other-api"))
+	assert.NoError(t, err)
+}
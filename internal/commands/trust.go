@@ -0,0 +1,128 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/caoer/denv/internal/config"
+	"github.com/caoer/denv/internal/paths"
+	"github.com/caoer/denv/internal/trust"
+)
+
+// Allow approves the current content of a repository config file so its
+// patterns and hooks are used. An empty file means the current repository's.
+func Allow(file string) error {
+	file, err := resolveRepoConfig(file)
+	if err != nil {
+		return err
+	}
+
+	store, err := trust.Load(paths.TrustPath())
+	if err != nil {
+		return fmt.Errorf("failed to load trust store: %w", err)
+	}
+	if err := store.Allow(file); err != nil {
+		return fmt.Errorf("failed to allow %s: %w", file, err)
+	}
+
+	fmt.Printf("Allowed %s\n", file)
+	return nil
+}
+
+// Deny revokes the approval of a repository config file
+func Deny(file string) error {
+	file, err := resolveRepoConfig(file)
+	if err != nil {
+		return err
+	}
+
+	store, err := trust.Load(paths.TrustPath())
+	if err != nil {
+		return fmt.Errorf("failed to load trust store: %w", err)
+	}
+	if err := store.Deny(file); err != nil {
+		return fmt.Errorf("failed to deny %s: %w", file, err)
+	}
+
+	fmt.Printf("Denied %s\n", file)
+	return nil
+}
+
+// resolveRepoConfig turns a file or directory argument into the absolute
+// path of a repository config file
+func resolveRepoConfig(file string) (string, error) {
+	if file == "" {
+		cwd, _ := os.Getwd()
+		file = config.FindRepoConfig(cwd)
+		if file == "" {
+			return "", fmt.Errorf("no %s found in this repository", config.RepoConfigName)
+		}
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		file = filepath.Join(file, config.RepoConfigName)
+		if _, err := os.Stat(file); err != nil {
+			return "", err
+		}
+	}
+	return filepath.Abs(file)
+}
+
+// loadRepoConfig returns the repository config found from dir together with
+// its path and trust status. It returns nil if there is none.
+func loadRepoConfig(dir string) (*config.RepoConfig, string, trust.Status) {
+	path := config.FindRepoConfig(dir)
+	if path == "" {
+		return nil, "", trust.NotAllowed
+	}
+
+	// Hash the same bytes that are parsed so a concurrent edit can't slip
+	// past the check
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", trust.NotAllowed
+	}
+	repoCfg, err := config.ParseRepoConfig(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring %s: %v\n", path, err)
+		return nil, "", trust.NotAllowed
+	}
+
+	store, err := trust.Load(paths.TrustPath())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to load trust store: %v\n", err)
+		return repoCfg, path, trust.NotAllowed
+	}
+	return repoCfg, path, store.Check(path, data)
+}
+
+// blockedMessage explains why a repository config is not used
+func blockedMessage(path string, status trust.Status) string {
+	if status == trust.Changed {
+		return fmt.Sprintf("%s has changed since it was allowed. Review it and run 'denv allow' to use it", path)
+	}
+	return fmt.Sprintf("%s is blocked. Review it and run 'denv allow' to use it", path)
+}
+
+// withRepoConfig returns cfg with the approved repository patterns matched
+// first and the repository's services added. An unapproved repository
+// config is skipped with a warning.
+func withRepoConfig(cfg *config.Config, dir string) *config.Config {
+	repoCfg, path, status := loadRepoConfig(dir)
+	if repoCfg == nil || cfg == nil || (len(repoCfg.Patterns) == 0 && len(repoCfg.Services) == 0) {
+		return cfg
+	}
+	if status != trust.Allowed {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", blockedMessage(path, status))
+		return cfg
+	}
+
+	merged := cfg.WithPatterns(repoCfg.Patterns)
+	merged.Services = config.MergeServices(cfg.Services, repoCfg.Services)
+	return merged
+}
@@ -0,0 +1,75 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/caoer/denv/internal/environment"
+	"github.com/caoer/denv/internal/testutil"
+)
+
+func setupTrustProject(t *testing.T) (string, string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell hooks")
+	}
+
+	return testutil.SetupProject(t, "trusttest")
+}
+
+func TestTrust_RepoConfigRequiresApproval(t *testing.T) {
+	tmpDir, tmpProject := setupTrustProject(t)
+
+	os.Setenv("ASSETS_LOCATION", "/srv/assets")
+	defer os.Unsetenv("ASSETS_LOCATION")
+
+	marker := filepath.Join(tmpDir, "hook-ran")
+	repoConfig := filepath.Join(tmpProject, ".denv.yaml")
+	content := `patterns:
+  - pattern: "ASSETS_LOCATION"
+    rule:
+      action: isolate
+hooks:
+  on-enter:
+    - touch ` + marker + `
+`
+	require.NoError(t, os.WriteFile(repoConfig, []byte(content), 0644))
+
+	// Test: Blocked until allowed
+	require.NoError(t, Enter("dev"))
+	assert.NoFileExists(t, marker)
+	rt, err := environment.LoadRuntime(filepath.Join(tmpDir, "trusttest-dev"))
+	require.NoError(t, err)
+	assert.NotContains(t, rt.Overrides, "ASSETS_LOCATION")
+
+	// Test: Allowed config is used
+	require.NoError(t, Allow(""))
+	require.NoError(t, Enter("dev"))
+	assert.FileExists(t, marker)
+	rt, err = environment.LoadRuntime(filepath.Join(tmpDir, "trusttest-dev"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(tmpDir, "trusttest-dev", "assets"), rt.Overrides["ASSETS_LOCATION"].Current)
+
+	// Test: Editing the file invalidates the approval
+	require.NoError(t, os.Remove(marker))
+	require.NoError(t, os.WriteFile(repoConfig, []byte(content+"# edited\n"), 0644))
+	require.NoError(t, Enter("dev"))
+	assert.NoFileExists(t, marker)
+
+	// Test: Deny revokes a fresh approval
+	require.NoError(t, Allow(tmpProject))
+	require.NoError(t, Deny(""))
+	require.NoError(t, Enter("dev"))
+	assert.NoFileExists(t, marker)
+}
+
+func TestTrust_NoRepoConfig(t *testing.T) {
+	_, _ = setupTrustProject(t)
+
+	err := Allow("")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), ".denv.yaml")
+}
@@ -0,0 +1,159 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caoer/denv/internal/environment"
+	"github.com/caoer/denv/internal/probe"
+)
+
+const (
+	DefaultWaitTimeout = 60 * time.Second
+	waitInterval       = 250 * time.Millisecond
+)
+
+// Wait blocks until every target accepts connections, or answers httpPath
+// with a non-error status if set. A target is an original port or the name
+// of a variable holding a port or URL; ports are resolved to the
+// environment's mapped ports. Targets are waited on in parallel.
+func Wait(envName string, targets []string, timeout time.Duration, httpPath string, w io.Writer) error {
+	if len(targets) == 0 {
+		return fmt.Errorf("port or variable name required")
+	}
+	_, runtime, _, err := loadTargetEnvironment(envName)
+	if err != nil {
+		return err
+	}
+
+	probes := make([]probe.Probe, len(targets))
+	for i, target := range targets {
+		if probes[i], err = waitProbe(target, runtime, httpPath); err != nil {
+			return err
+		}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	failed := 0
+	for i := range targets {
+		wg.Add(1)
+		go func(target string, p probe.Probe) {
+			defer wg.Done()
+			address := p.TCP
+			if p.HTTP != "" {
+				address = p.HTTP
+			}
+
+			err := p.Wait(timeout, waitInterval)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				fmt.Fprintf(w, "✗ %s (%s) %v\n", target, address, err)
+				failed++
+				return
+			}
+			fmt.Fprintf(w, "✓ %s (%s) ready\n", target, address)
+		}(targets[i], probes[i])
+	}
+	wg.Wait()
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d target(s) not ready", failed, len(targets))
+	}
+	return nil
+}
+
+// waitProbe resolves a target to the probe checking it
+func waitProbe(target string, runtime *environment.Runtime, httpPath string) (probe.Probe, error) {
+	host, port, err := resolveWaitTarget(target, runtime)
+	if err != nil {
+		return probe.Probe{}, err
+	}
+
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	if httpPath == "" {
+		return probe.Probe{TCP: address}, nil
+	}
+	if !strings.HasPrefix(httpPath, "/") {
+		httpPath = "/" + httpPath
+	}
+	return probe.Probe{HTTP: "http://" + address + httpPath}, nil
+}
+
+// resolveWaitTarget returns the host and mapped port a target refers to
+func resolveWaitTarget(target string, runtime *environment.Runtime) (string, int, error) {
+	if port, err := strconv.Atoi(target); err == nil {
+		return "127.0.0.1", mapPort(port, runtime), nil
+	}
+
+	// The environment's value of a variable, falling back to the process
+	value := ""
+	if o, ok := runtime.Overrides[target]; ok {
+		value = o.Current
+	} else {
+		value = os.Getenv(target)
+	}
+	if value == "" {
+		return "", 0, fmt.Errorf("%s is not set", target)
+	}
+
+	if port, err := strconv.Atoi(value); err == nil {
+		return "127.0.0.1", mapPort(port, runtime), nil
+	}
+
+	u, err := url.Parse(value)
+	if err != nil || u.Hostname() == "" {
+		return "", 0, fmt.Errorf("%s=%s is neither a port nor a URL", target, value)
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		if port = defaultSchemePort(u.Scheme); port == 0 {
+			return "", 0, fmt.Errorf("%s=%s has no port", target, value)
+		}
+	}
+
+	host := u.Hostname()
+	switch host {
+	case "localhost", "127.0.0.1", "0.0.0.0":
+		return "127.0.0.1", mapPort(port, runtime), nil
+	}
+	return host, port, nil
+}
+
+// mapPort returns the mapped port of an original port; other ports (for
+// instance already mapped ones) are returned as-is
+func mapPort(port int, runtime *environment.Runtime) int {
+	if mapped, ok := runtime.Ports[port]; ok {
+		return mapped
+	}
+	return port
+}
+
+func defaultSchemePort(scheme string) int {
+	switch scheme {
+	case "http", "ws":
+		return 80
+	case "https", "wss":
+		return 443
+	case "postgres", "postgresql":
+		return 5432
+	case "mysql":
+		return 3306
+	case "redis":
+		return 6379
+	case "mongodb":
+		return 27017
+	case "amqp":
+		return 5672
+	}
+	return 0
+}
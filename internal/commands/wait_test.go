@@ -0,0 +1,92 @@
+package commands
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/caoer/denv/internal/environment"
+	"github.com/caoer/denv/internal/testutil"
+)
+
+func setupWaitProject(t *testing.T, ports map[int]int) {
+	tmpDir, _ := testutil.SetupProject(t, "waittest")
+	t.Setenv("DENV_ENV_NAME", "")
+
+	rt := environment.NewRuntime("waittest", "dev")
+	rt.Ports = ports
+	rt.Overrides["DATABASE_URL"] = environment.Override{
+		Original: "postgres://localhost:5432/app",
+		Current:  "postgres://localhost:" + strconv.Itoa(ports[5432]) + "/app",
+		Rule:     "rewrite_ports",
+	}
+	envPath := filepath.Join(tmpDir, "waittest-dev")
+	require.NoError(t, os.MkdirAll(envPath, 0755))
+	require.NoError(t, environment.SaveRuntime(envPath, rt))
+}
+
+func TestWait_ResolvesPortsAndVariables(t *testing.T) {
+	db, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer db.Close()
+	dbPort := db.Addr().(*net.TCPAddr).Port
+
+	web := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer web.Close()
+	u, _ := url.Parse(web.URL)
+	webPort, _ := strconv.Atoi(u.Port())
+
+	setupWaitProject(t, map[int]int{3000: webPort, 5432: dbPort})
+	t.Setenv("API_URL", "http://localhost:3000/api")
+
+	// Test: Original ports and variables are resolved to mapped ports
+	var out bytes.Buffer
+	require.NoError(t, Wait("dev", []string{"5432", "DATABASE_URL", "API_URL"}, time.Second, "", &out))
+	assert.Contains(t, out.String(), "✓ 5432 (127.0.0.1:"+strconv.Itoa(dbPort)+") ready")
+	assert.Contains(t, out.String(), "✓ DATABASE_URL (127.0.0.1:"+strconv.Itoa(dbPort)+") ready")
+	assert.Contains(t, out.String(), "✓ API_URL (127.0.0.1:"+strconv.Itoa(webPort)+") ready")
+
+	// Test: HTTP checks require a successful status
+	require.NoError(t, Wait("dev", []string{"3000"}, time.Second, "health", &bytes.Buffer{}))
+	err = Wait("dev", []string{"3000"}, 300*time.Millisecond, "/missing", &bytes.Buffer{})
+	assert.Error(t, err)
+
+	err = Wait("dev", []string{"UNSET_VARIABLE"}, time.Second, "", &bytes.Buffer{})
+	assert.ErrorContains(t, err, "UNSET_VARIABLE is not set")
+}
+
+func TestWait_Timeout(t *testing.T) {
+	idle := freePort(t)
+	setupWaitProject(t, map[int]int{5432: idle})
+
+	var out bytes.Buffer
+	start := time.Now()
+	err := Wait("dev", []string{"5432"}, 300*time.Millisecond, "", &out)
+	assert.ErrorContains(t, err, "1 of 1 target(s) not ready")
+	assert.Less(t, time.Since(start), 3*time.Second)
+	assert.Contains(t, out.String(), "✗ 5432")
+	assert.Contains(t, out.String(), "not ready after 300ms")
+}
+
+func TestWait_InParallel(t *testing.T) {
+	setupWaitProject(t, map[int]int{5432: freePort(t), 6379: freePort(t)})
+
+	// Test: Two failing targets take one timeout, not two
+	start := time.Now()
+	err := Wait("dev", []string{"5432", "6379"}, 500*time.Millisecond, "", &bytes.Buffer{})
+	assert.ErrorContains(t, err, "2 of 2 target(s) not ready")
+	assert.Less(t, time.Since(start), 900*time.Millisecond)
+}
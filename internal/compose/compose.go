@@ -0,0 +1,221 @@
+package compose
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileNames are the compose files docker compose looks for, in its order
+var FileNames = []string{"compose.yaml", "compose.yml", "docker-compose.yaml", "docker-compose.yml"}
+
+// File is the part of a compose file denv rewrites
+type File struct {
+	Services map[string]Service `yaml:"services"`
+}
+
+// Service is a compose service
+type Service struct {
+	ContainerName string `yaml:"container_name"`
+	Ports         []Port `yaml:"ports"`
+}
+
+// Port is a ports entry, in short ("127.0.0.1:8080:80/tcp") or long syntax
+type Port struct {
+	HostIP    string
+	Published string
+	Target    string
+	Protocol  string
+	// Long is the long syntax mapping, kept as written apart from published
+	Long *yaml.Node
+}
+
+// Find returns the compose file in dir or the nearest parent up to the
+// repository root, or "" if there is none
+func Find(dir string) string {
+	for {
+		for _, name := range FileNames {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+				return path
+			}
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return ""
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Load parses the compose file at path
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse reads the services and their ports from a compose file
+func Parse(data []byte) (*File, error) {
+	var f File
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// UnmarshalYAML reads either port syntax
+func (p *Port) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.MappingNode {
+		var long struct {
+			HostIP    string `yaml:"host_ip"`
+			Published string `yaml:"published"`
+			Target    string `yaml:"target"`
+			Protocol  string `yaml:"protocol"`
+		}
+		if err := node.Decode(&long); err != nil {
+			return err
+		}
+		*p = Port{HostIP: long.HostIP, Published: long.Published, Target: long.Target, Protocol: long.Protocol, Long: node}
+		return nil
+	}
+
+	var short string
+	if err := node.Decode(&short); err != nil {
+		return fmt.Errorf("line %d: invalid port: %w", node.Line, err)
+	}
+	*p = parseShort(short)
+	return nil
+}
+
+// parseShort splits [[host_ip:]published:]target[/protocol]; the host IP
+// may itself hold colons, as in "[::1]:8080:80"
+func parseShort(spec string) Port {
+	var p Port
+	if i := strings.LastIndex(spec, "/"); i >= 0 {
+		spec, p.Protocol = spec[:i], spec[i+1:]
+	}
+	parts := strings.Split(spec, ":")
+	p.Target = parts[len(parts)-1]
+	if len(parts) >= 2 {
+		p.Published = parts[len(parts)-2]
+		p.HostIP = strings.Join(parts[:len(parts)-2], ":")
+	}
+	return p
+}
+
+// PublishedPort returns the fixed host port of p, if it has one; ranges,
+// variables and ports left to docker have none
+func (p Port) PublishedPort() (int, bool) {
+	port, err := strconv.Atoi(p.Published)
+	if err != nil || port <= 0 {
+		return 0, false
+	}
+	return port, true
+}
+
+// PublishedPorts returns every fixed host port published by the services
+func (f *File) PublishedPorts() map[int]bool {
+	result := make(map[int]bool)
+	for _, svc := range f.Services {
+		for _, p := range svc.Ports {
+			if port, ok := p.PublishedPort(); ok {
+				result[port] = true
+			}
+		}
+	}
+	return result
+}
+
+// Override returns a compose file replacing the published ports of every
+// service with their mapping in ports, and suffixing explicit container
+// names, so the stack can run next to other environments' copies. Compose
+// appends ports from override files, so the lists are tagged !override
+// (docker compose 2.24 or later).
+func (f *File) Override(ports map[int]int, suffix string) ([]byte, error) {
+	names := make([]string, 0, len(f.Services))
+	for name := range f.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	services := &yaml.Node{Kind: yaml.MappingNode}
+	for _, name := range names {
+		svc := f.Services[name]
+		fields := &yaml.Node{Kind: yaml.MappingNode}
+
+		if svc.ContainerName != "" {
+			fields.Content = append(fields.Content, scalar("container_name"), scalar(svc.ContainerName+"-"+suffix))
+		}
+
+		remapped := false
+		list := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!override"}
+		for _, p := range svc.Ports {
+			if port, ok := p.PublishedPort(); ok {
+				if mapped, ok := ports[port]; ok {
+					p.Published = strconv.Itoa(mapped)
+					remapped = true
+				}
+			}
+			list.Content = append(list.Content, p.node())
+		}
+		if remapped {
+			fields.Content = append(fields.Content, scalar("ports"), list)
+		}
+
+		if len(fields.Content) > 0 {
+			services.Content = append(services.Content, scalar(name), fields)
+		}
+	}
+
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	root.Content = append(root.Content, scalar("services"), services)
+	return yaml.Marshal(root)
+}
+
+// node returns p in the syntax it was written in
+func (p Port) node() *yaml.Node {
+	if p.Long == nil {
+		spec := p.Target
+		if p.Published != "" {
+			spec = p.Published + ":" + spec
+			if p.HostIP != "" {
+				spec = p.HostIP + ":" + spec
+			}
+		}
+		if p.Protocol != "" {
+			spec += "/" + p.Protocol
+		}
+		return scalar(spec)
+	}
+
+	long := &yaml.Node{Kind: yaml.MappingNode}
+	published := false
+	for i := 0; i+1 < len(p.Long.Content); i += 2 {
+		key, value := p.Long.Content[i], p.Long.Content[i+1]
+		if key.Value == "published" {
+			value = scalar(p.Published)
+			published = true
+		}
+		long.Content = append(long.Content, key, value)
+	}
+	if !published && p.Published != "" {
+		long.Content = append(long.Content, scalar("published"), scalar(p.Published))
+	}
+	return long
+}
+
+func scalar(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
@@ -0,0 +1,104 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testCompose = `services:
+  web:
+    image: nginx
+    container_name: web
+    ports:
+      - "3000:80"
+      - 127.0.0.1:9229:9229/tcp
+      - "[::1]:6006:6006"
+      - 8080
+      - "${PORT_4000:-4000}:4000"
+      - "7000-7001:7000-7001"
+  db:
+    image: postgres
+    ports:
+      - target: 5432
+        published: "5432"
+        host_ip: 127.0.0.1
+        protocol: tcp
+  worker:
+    image: busybox
+`
+
+func TestParse(t *testing.T) {
+	f, err := Parse([]byte(testCompose))
+	require.NoError(t, err)
+
+	web := f.Services["web"]
+	require.Len(t, web.Ports, 6)
+	assert.Equal(t, Port{Published: "3000", Target: "80"}, web.Ports[0])
+	assert.Equal(t, Port{HostIP: "127.0.0.1", Published: "9229", Target: "9229", Protocol: "tcp"}, web.Ports[1])
+	assert.Equal(t, Port{HostIP: "[::1]", Published: "6006", Target: "6006"}, web.Ports[2])
+	assert.Equal(t, Port{Target: "8080"}, web.Ports[3])
+
+	db := f.Services["db"]
+	require.Len(t, db.Ports, 1)
+	assert.Equal(t, "5432", db.Ports[0].Published)
+	assert.Equal(t, "127.0.0.1", db.Ports[0].HostIP)
+
+	// Test: Only fixed host ports are published ports
+	assert.Equal(t, map[int]bool{3000: true, 9229: true, 6006: true, 5432: true}, f.PublishedPorts())
+}
+
+func TestOverride(t *testing.T) {
+	f, err := Parse([]byte(testCompose))
+	require.NoError(t, err)
+
+	data, err := f.Override(map[int]int{3000: 33000, 5432: 35432, 6006: 36006}, "myapp-dev")
+	require.NoError(t, err)
+
+	expected := `services:
+    db:
+        ports: !override
+            - target: 5432
+              published: "35432"
+              host_ip: 127.0.0.1
+              protocol: tcp
+    web:
+        container_name: web-myapp-dev
+        ports: !override
+            - 33000:80
+            - 127.0.0.1:9229:9229/tcp
+            - '[::1]:36006:6006'
+            - "8080"
+            - ${PORT_4000:-4000}:4000
+            - 7000-7001:7000-7001
+`
+	assert.Equal(t, expected, string(data))
+}
+
+func TestOverride_NothingToChange(t *testing.T) {
+	f, err := Parse([]byte(testCompose))
+	require.NoError(t, err)
+
+	// Test: Services without mapped ports or container names are left out
+	data, err := f.Override(map[int]int{}, "myapp-dev")
+	require.NoError(t, err)
+	assert.Equal(t, "services:\n    web:\n        container_name: web-myapp-dev\n", string(data))
+}
+
+func TestFind(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(root, ".git"), 0755))
+	sub := filepath.Join(root, "src", "app")
+	require.NoError(t, os.MkdirAll(sub, 0755))
+
+	assert.Equal(t, "", Find(sub))
+
+	// Test: compose.yaml wins over docker-compose.yml
+	require.NoError(t, os.WriteFile(filepath.Join(root, "docker-compose.yml"), []byte(testCompose), 0644))
+	assert.Equal(t, filepath.Join(root, "docker-compose.yml"), Find(sub))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "compose.yaml"), []byte(testCompose), 0644))
+	assert.Equal(t, filepath.Join(root, "compose.yaml"), Find(sub))
+}
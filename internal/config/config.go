@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 
@@ -22,6 +23,71 @@ type PatternRule struct {
 type Config struct {
 	Projects map[string]string `yaml:"projects"`
 	Patterns []PatternRule     `yaml:"patterns"`
+	// Pinned environments are never garbage collected. Entries are either
+	// an environment name ("default") or "project:environment".
+	Pinned []string `yaml:"pinned,omitempty"`
+	// TrashRetention is how long removed environments are kept in the
+	// trash before being deleted for good (e.g. "7d")
+	TrashRetention string `yaml:"trash_retention,omitempty"`
+	// HookTimeout limits how long a single lifecycle hook may run
+	// (e.g. "30s")
+	HookTimeout string `yaml:"hook_timeout,omitempty"`
+	// Services are run per environment by denv up
+	Services map[string]Service `yaml:"services,omitempty"`
+	// Isolation selects how denv enter keeps environments apart: "remap"
+	// (default) rewrites port variables, "netns" runs the shell in its own
+	// network namespace on Linux
+	Isolation string `yaml:"isolation,omitempty"`
+	// Subprojects configures how monorepo sub-projects are detected
+	Subprojects Subprojects `yaml:"subprojects,omitempty"`
+}
+
+const (
+	IsolationRemap = "remap"
+	IsolationNetns = "netns"
+)
+
+// Subprojects configures monorepo sub-project detection
+type Subprojects struct {
+	// Markers are the files making their directory, below a repository's
+	// root, a sub-project (default: DefaultSubprojectMarkers)
+	Markers []string `yaml:"markers,omitempty"`
+	// Mode is "shared" (default): sub-projects use the repository's
+	// environments and ports, "separate": they get their own, or "off":
+	// sub-projects aren't detected
+	Mode string `yaml:"mode,omitempty"`
+}
+
+const (
+	SubprojectsSeparate = "separate"
+	SubprojectsShared   = "shared"
+	SubprojectsOff      = "off"
+)
+
+// DefaultSubprojectMarkers are the files marking a sub-project by default
+var DefaultSubprojectMarkers = []string{RepoConfigName, "package.json", "go.mod", "Cargo.toml"}
+
+// SubprojectMode returns the configured sub-project mode, SubprojectsShared
+// by default, so existing environments keep their names until the user
+// opts in to separate ones
+func (c *Config) SubprojectMode() (string, error) {
+	if c == nil || c.Subprojects.Mode == "" {
+		return SubprojectsShared, nil
+	}
+	switch c.Subprojects.Mode {
+	case SubprojectsSeparate, SubprojectsShared, SubprojectsOff:
+		return c.Subprojects.Mode, nil
+	}
+	return SubprojectsShared, fmt.Errorf("unknown subprojects mode '%s' (supported: %s, %s, %s)",
+		c.Subprojects.Mode, SubprojectsShared, SubprojectsSeparate, SubprojectsOff)
+}
+
+// SubprojectMarkers returns the configured markers or the defaults
+func (c *Config) SubprojectMarkers() []string {
+	if c == nil || c.Subprojects.Markers == nil {
+		return DefaultSubprojectMarkers
+	}
+	return c.Subprojects.Markers
 }
 
 func LoadConfig(path string) (*Config, error) {
@@ -54,6 +120,14 @@ func LoadConfig(path string) (*Config, error) {
 		cfg.Projects = make(map[string]string)
 	}
 
+	if cfg.Pinned == nil {
+		cfg.Pinned = defaultPinned()
+	}
+
+	if cfg.TrashRetention == "" {
+		cfg.TrashRetention = defaultTrashRetention
+	}
+
 	return &cfg, nil
 }
 
@@ -74,9 +148,27 @@ func SaveConfig(path string, cfg *Config) error {
 
 func defaultConfig() *Config {
 	return &Config{
-		Projects: make(map[string]string),
-		Patterns: defaultPatterns(),
+		Projects:       make(map[string]string),
+		Patterns:       defaultPatterns(),
+		Pinned:         defaultPinned(),
+		TrashRetention: defaultTrashRetention,
+	}
+}
+
+const defaultTrashRetention = "7d"
+
+func defaultPinned() []string {
+	return []string{"default"}
+}
+
+// IsPinned reports whether an environment is protected from garbage collection
+func (c *Config) IsPinned(project, env string) bool {
+	for _, p := range c.Pinned {
+		if p == env || p == project+":"+env {
+			return true
+		}
 	}
+	return false
 }
 
 // GetDefaultPatterns returns the default pattern rules
@@ -56,4 +56,58 @@ func TestDefaultConfig(t *testing.T) {
 		}
 	}
 	assert.True(t, found, "Default patterns should include *_PORT|PORT")
-}
\ No newline at end of file
+}
+
+func TestPinnedEnvironments(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	// Test: "default" is pinned unless configured otherwise
+	_ = os.WriteFile(configPath, []byte("projects: {}\n"), 0644)
+	cfg, err := LoadConfig(configPath)
+	assert.NoError(t, err)
+	assert.True(t, cfg.IsPinned("myapp", "default"))
+	assert.False(t, cfg.IsPinned("myapp", "feature"))
+
+	// Test: Pins can target a single project
+	_ = os.WriteFile(configPath, []byte("pinned:\n  - staging\n  - myapp:feature\n"), 0644)
+	cfg, err = LoadConfig(configPath)
+	assert.NoError(t, err)
+	assert.True(t, cfg.IsPinned("other", "staging"))
+	assert.True(t, cfg.IsPinned("myapp", "feature"))
+	assert.False(t, cfg.IsPinned("other", "feature"))
+	assert.False(t, cfg.IsPinned("myapp", "default"))
+}
+
+func TestIsolation(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+
+	_ = os.WriteFile(configPath, []byte("isolation: netns\n"), 0644)
+	cfg, err := LoadConfig(configPath)
+	assert.NoError(t, err)
+	assert.Equal(t, IsolationNetns, cfg.Isolation)
+}
+
+func TestSubprojectMode(t *testing.T) {
+	// Test: Sub-projects share the repository's environments by default
+	mode, err := (*Config)(nil).SubprojectMode()
+	assert.NoError(t, err)
+	assert.Equal(t, SubprojectsShared, mode)
+
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	_ = os.WriteFile(configPath, []byte("subprojects:\n  mode: separate\n  markers: [BUILD]\n"), 0644)
+	cfg, err := LoadConfig(configPath)
+	assert.NoError(t, err)
+	mode, err = cfg.SubprojectMode()
+	assert.NoError(t, err)
+	assert.Equal(t, SubprojectsSeparate, mode)
+	assert.Equal(t, []string{"BUILD"}, cfg.SubprojectMarkers())
+
+	// Test: Unknown modes are rejected
+	cfg.Subprojects.Mode = "seperate"
+	mode, err = cfg.SubprojectMode()
+	assert.Error(t, err)
+	assert.Equal(t, SubprojectsShared, mode)
+}
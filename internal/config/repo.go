@@ -0,0 +1,58 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RepoConfigName is the name of the configuration file a repository can
+// provide. It is only used once approved with `denv allow`.
+const RepoConfigName = ".denv.yaml"
+
+// RepoConfig is configuration checked into a repository
+type RepoConfig struct {
+	// Patterns are matched before the global patterns
+	Patterns []PatternRule `yaml:"patterns,omitempty"`
+	// Hooks maps lifecycle events (e.g. "on-create") to shell commands
+	Hooks map[string][]string `yaml:"hooks,omitempty"`
+	// Services add to or replace the globally configured services
+	Services map[string]Service `yaml:"services,omitempty"`
+}
+
+// ParseRepoConfig parses the content of a repository config file
+func ParseRepoConfig(data []byte) (*RepoConfig, error) {
+	var cfg RepoConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// FindRepoConfig looks for a repository config file in dir and its parents,
+// stopping at the repository root. It returns "" if there is none.
+func FindRepoConfig(dir string) string {
+	for {
+		path := filepath.Join(dir, RepoConfigName)
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			return path
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return ""
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// WithPatterns returns a copy of c with extra patterns matched first
+func (c *Config) WithPatterns(patterns []PatternRule) *Config {
+	merged := *c
+	merged.Patterns = append(append([]PatternRule{}, patterns...), c.Patterns...)
+	return &merged
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRepoConfig(t *testing.T) {
+	data := []byte(`patterns:
+  - pattern: "GRPC_ADDR"
+    rule:
+      action: rewrite_ports
+hooks:
+  on-create:
+    - make setup
+  on-last-exit:
+    - docker compose stop
+`)
+
+	cfg, err := ParseRepoConfig(data)
+	require.NoError(t, err)
+	require.Len(t, cfg.Patterns, 1)
+	assert.Equal(t, "rewrite_ports", cfg.Patterns[0].Rule.Action)
+	assert.Equal(t, []string{"make setup"}, cfg.Hooks["on-create"])
+	assert.Equal(t, []string{"docker compose stop"}, cfg.Hooks["on-last-exit"])
+}
+
+func TestFindRepoConfig(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(root, ".git"), 0755))
+	sub := filepath.Join(root, "services", "api")
+	require.NoError(t, os.MkdirAll(sub, 0755))
+
+	assert.Equal(t, "", FindRepoConfig(sub))
+
+	// Test: Found from a subdirectory
+	path := filepath.Join(root, RepoConfigName)
+	require.NoError(t, os.WriteFile(path, []byte("{}"), 0644))
+	assert.Equal(t, path, FindRepoConfig(sub))
+
+	// Test: Files above the repository root are ignored
+	inner := filepath.Join(root, "vendor", "other")
+	require.NoError(t, os.MkdirAll(filepath.Join(inner, ".git"), 0755))
+	assert.Equal(t, "", FindRepoConfig(inner))
+}
+
+func TestWithPatterns(t *testing.T) {
+	cfg := defaultConfig()
+	extra := []PatternRule{{Pattern: "GRPC_ADDR", Rule: Rule{Action: "rewrite_ports"}}}
+
+	merged := cfg.WithPatterns(extra)
+	assert.Equal(t, "GRPC_ADDR", merged.Patterns[0].Pattern)
+	assert.Len(t, merged.Patterns, len(cfg.Patterns)+1)
+	// The original is untouched
+	assert.NotEqual(t, "GRPC_ADDR", cfg.Patterns[0].Pattern)
+}
@@ -0,0 +1,45 @@
+package config
+
+// Service is a process denv runs for each environment
+type Service struct {
+	// Command is run through the system shell with the environment's
+	// variables, so it can refer to e.g. $PORT_5432
+	Command string `yaml:"command"`
+	// Env holds extra variables; ${VAR} refers to environment variables
+	Env map[string]string `yaml:"env,omitempty"`
+	// Ports are original ports the service listens on; each gets a mapped
+	// port even if no variable refers to it
+	Ports []int `yaml:"ports,omitempty"`
+	// Ready is checked after starting before the service counts as up
+	Ready *Probe `yaml:"ready,omitempty"`
+	// Restart is "no" (default), "on-failure" or "always"
+	Restart string `yaml:"restart,omitempty"`
+	// Autostart starts the service with the first session and stops it
+	// with the last
+	Autostart bool `yaml:"autostart,omitempty"`
+}
+
+// Probe describes a readiness check. Exactly one of TCP, HTTP or Exec
+// should be set.
+type Probe struct {
+	// TCP is an original port; the probe connects to its mapped port
+	TCP int `yaml:"tcp,omitempty"`
+	// HTTP is a URL whose ports are rewritten like rewrite_ports
+	HTTP string `yaml:"http,omitempty"`
+	// Exec is a shell command that must exit successfully
+	Exec string `yaml:"exec,omitempty"`
+	// Timeout is how long to wait for readiness (default "30s")
+	Timeout string `yaml:"timeout,omitempty"`
+}
+
+// MergeServices returns the services of base overridden by extra
+func MergeServices(base, extra map[string]Service) map[string]Service {
+	merged := make(map[string]Service, len(base)+len(extra))
+	for name, svc := range base {
+		merged[name] = svc
+	}
+	for name, svc := range extra {
+		merged[name] = svc
+	}
+	return merged
+}
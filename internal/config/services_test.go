@@ -0,0 +1,49 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadServices(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	yaml := `services:
+  postgres:
+    command: postgres -D "$DENV_ENV/pgdata" -p "$PORT_5432"
+    ports: [5432]
+    env:
+      PGDATA: ${DENV_ENV}/pgdata
+    ready:
+      tcp: 5432
+      timeout: 10s
+    restart: on-failure
+    autostart: true
+`
+	require.NoError(t, os.WriteFile(configPath, []byte(yaml), 0644))
+
+	cfg, err := LoadConfig(configPath)
+	require.NoError(t, err)
+
+	svc, ok := cfg.Services["postgres"]
+	require.True(t, ok)
+	assert.Equal(t, []int{5432}, svc.Ports)
+	assert.Equal(t, "${DENV_ENV}/pgdata", svc.Env["PGDATA"])
+	assert.Equal(t, 5432, svc.Ready.TCP)
+	assert.Equal(t, "10s", svc.Ready.Timeout)
+	assert.Equal(t, "on-failure", svc.Restart)
+	assert.True(t, svc.Autostart)
+}
+
+func TestMergeServices(t *testing.T) {
+	base := map[string]Service{"db": {Command: "global-db"}, "redis": {Command: "redis-server"}}
+	extra := map[string]Service{"db": {Command: "repo-db"}}
+
+	merged := MergeServices(base, extra)
+	assert.Equal(t, "repo-db", merged["db"].Command)
+	assert.Equal(t, "redis-server", merged["redis"].Command)
+	assert.Equal(t, "global-db", base["db"].Command)
+}
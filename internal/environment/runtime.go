@@ -22,13 +22,34 @@ type Session struct {
 
 type Runtime struct {
 	Created     time.Time            `json:"created"`
+	LastUsed    time.Time            `json:"last_used"`
+	EnterCount  int                  `json:"enter_count"`
 	Project     string               `json:"project"`
 	Environment string               `json:"environment"`
+	// Parent and Subproject name the repository's project and the
+	// sub-project's path in it, for environments of monorepo sub-projects
+	Parent      string               `json:"parent,omitempty"`
+	Subproject  string               `json:"subproject,omitempty"`
 	Ports       map[int]int          `json:"ports"`
 	Overrides   map[string]Override  `json:"overrides"`
 	Sessions    map[string]Session   `json:"sessions"`
 }
 
+// MarkUsed records that the environment was entered
+func (r *Runtime) MarkUsed() {
+	r.LastUsed = time.Now()
+	r.EnterCount++
+}
+
+// LastActivity returns when the environment was last entered, falling back
+// to its creation time for environments created before last-use tracking
+func (r *Runtime) LastActivity() time.Time {
+	if r.LastUsed.IsZero() {
+		return r.Created
+	}
+	return r.LastUsed
+}
+
 func SaveRuntime(envPath string, runtime *Runtime) error {
 	data, err := json.MarshalIndent(runtime, "", "  ")
 	if err != nil {
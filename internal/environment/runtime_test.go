@@ -228,4 +228,18 @@ func TestRuntimePortMerging(t *testing.T) {
 	assert.Equal(t, 33000, final.Ports[3000])
 	assert.Equal(t, 34000, final.Ports[4000])
 	assert.Len(t, final.Ports, 2)
-}
\ No newline at end of file
+}
+
+func TestRuntimeMarkUsed(t *testing.T) {
+	runtime := NewRuntime("myproject", "default")
+	runtime.Created = time.Now().Add(-48 * time.Hour)
+
+	// Test: Environments never entered fall back to their creation time
+	assert.Equal(t, runtime.Created, runtime.LastActivity())
+
+	runtime.MarkUsed()
+	runtime.MarkUsed()
+
+	assert.Equal(t, 2, runtime.EnterCount)
+	assert.WithinDuration(t, time.Now(), runtime.LastActivity(), time.Second)
+}
@@ -0,0 +1,171 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/caoer/denv/internal/process"
+)
+
+// Event is a point in an environment's lifecycle at which hooks run
+type Event string
+
+const (
+	OnCreate     Event = "on-create"
+	OnFirstEnter Event = "on-first-enter"
+	OnEnter      Event = "on-enter"
+	OnExit       Event = "on-exit"
+	OnLastExit   Event = "on-last-exit"
+	OnDestroy    Event = "on-destroy"
+)
+
+// DefaultTimeout bounds how long a single hook may run
+const DefaultTimeout = 30 * time.Second
+
+// Context describes the environment a hook runs for. It is exported to the
+// hook as DENV_* variables and written to its stdin as JSON.
+type Context struct {
+	Event       Event       `json:"event"`
+	Project     string      `json:"project"`
+	Environment string      `json:"environment"`
+	EnvPath     string      `json:"env_path"`
+	ProjectPath string      `json:"project_path"`
+	Session     string      `json:"session,omitempty"`
+	Ports       map[int]int `json:"ports"`
+}
+
+// Runner finds and executes hooks in a list of directories
+type Runner struct {
+	Dirs []string
+	// Commands are shell commands run per event after the hooks in Dirs
+	Commands map[Event][]string
+	Timeout  time.Duration
+	Stdout   io.Writer
+	Stderr   io.Writer
+}
+
+// Find returns the hooks for an event in the order they run: for every
+// directory, an executable named after the event followed by the
+// executables in <event>.d/ sorted by name
+func (r *Runner) Find(event Event) []string {
+	var found []string
+	for _, dir := range r.Dirs {
+		path := filepath.Join(dir, string(event))
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			found = append(found, path)
+		}
+
+		entries, err := os.ReadDir(filepath.Join(dir, string(event)+".d"))
+		if err != nil {
+			continue
+		}
+		var names []string
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				names = append(names, entry.Name())
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			found = append(found, filepath.Join(dir, string(event)+".d", name))
+		}
+	}
+	return found
+}
+
+// Run executes every hook for ctx.Event. A failing hook does not stop the
+// others; all failures are returned.
+func (r *Runner) Run(ctx Context) []error {
+	var errs []error
+	for _, path := range r.Find(ctx.Event) {
+		if err := r.runFile(path, ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s hook %s: %w", ctx.Event, path, err))
+		}
+	}
+	for _, command := range r.Commands[ctx.Event] {
+		if err := r.run(process.ShellCommand(command), ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s hook %q: %w", ctx.Event, command, err))
+		}
+	}
+	return errs
+}
+
+func (r *Runner) runFile(path string, hc Context) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if runtime.GOOS != "windows" && info.Mode()&0111 == 0 {
+		return errors.New("not executable")
+	}
+	return r.run([]string{path}, hc)
+}
+
+func (r *Runner) run(argv []string, hc Context) error {
+	input, err := json.Marshal(hc)
+	if err != nil {
+		return err
+	}
+
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Env = append(os.Environ(), Environ(hc)...)
+	cmd.Stdin = bytes.NewReader(input)
+	// Don't wait on background children still holding the output open
+	cmd.WaitDelay = time.Second
+	cmd.Stdout = r.Stdout
+	cmd.Stderr = r.Stderr
+	if cmd.Stdout == nil {
+		cmd.Stdout = os.Stdout
+	}
+	if cmd.Stderr == nil {
+		cmd.Stderr = os.Stderr
+	}
+
+	err = cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+	return err
+}
+
+// Environ returns the variables describing hc in KEY=value form
+func Environ(hc Context) []string {
+	env := []string{
+		"DENV_HOOK_EVENT=" + string(hc.Event),
+		"DENV_PROJECT_NAME=" + hc.Project,
+		"DENV_ENV_NAME=" + hc.Environment,
+		"DENV_ENV=" + hc.EnvPath,
+		"DENV_PROJECT=" + hc.ProjectPath,
+	}
+	if hc.Session != "" {
+		env = append(env, "DENV_SESSION="+hc.Session)
+	}
+
+	origs := make([]int, 0, len(hc.Ports))
+	for orig := range hc.Ports {
+		origs = append(origs, orig)
+	}
+	sort.Ints(origs)
+	for _, orig := range origs {
+		env = append(env, fmt.Sprintf("PORT_%d=%s", orig, strconv.Itoa(hc.Ports[orig])))
+	}
+	return env
+}
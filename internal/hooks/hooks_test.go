@@ -0,0 +1,105 @@
+package hooks
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeHook(t *testing.T, path, body string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0755))
+}
+
+func TestFindOrder(t *testing.T) {
+	projectHooks := t.TempDir()
+	envHooks := t.TempDir()
+
+	writeHook(t, filepath.Join(projectHooks, "on-create"), "true")
+	writeHook(t, filepath.Join(projectHooks, "on-create.d", "20-b"), "true")
+	writeHook(t, filepath.Join(projectHooks, "on-create.d", "10-a"), "true")
+	writeHook(t, filepath.Join(envHooks, "on-create"), "true")
+	// Sourced shell hooks are not run by the runner
+	writeHook(t, filepath.Join(projectHooks, "on-enter.sh"), "true")
+
+	r := &Runner{Dirs: []string{projectHooks, envHooks, filepath.Join(t.TempDir(), "missing")}}
+	assert.Equal(t, []string{
+		filepath.Join(projectHooks, "on-create"),
+		filepath.Join(projectHooks, "on-create.d", "10-a"),
+		filepath.Join(projectHooks, "on-create.d", "20-b"),
+		filepath.Join(envHooks, "on-create"),
+	}, r.Find(OnCreate))
+	assert.Empty(t, r.Find(OnEnter))
+}
+
+func TestRunPassesContext(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell hooks")
+	}
+	dir := t.TempDir()
+	writeHook(t, filepath.Join(dir, "on-first-enter"),
+		`echo "$DENV_HOOK_EVENT $DENV_ENV_NAME $PORT_3000"; cat`)
+
+	var stdout bytes.Buffer
+	r := &Runner{Dirs: []string{dir}, Stdout: &stdout}
+	errs := r.Run(Context{
+		Event:       OnFirstEnter,
+		Project:     "app",
+		Environment: "dev",
+		Ports:       map[int]int{3000: 33000},
+	})
+	require.Empty(t, errs)
+
+	lines := strings.SplitN(stdout.String(), "\n", 2)
+	assert.Equal(t, "on-first-enter dev 33000", lines[0])
+	assert.Contains(t, lines[1], `"environment":"dev"`)
+	assert.Contains(t, lines[1], `"3000":33000`)
+}
+
+func TestRunReportsFailures(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell hooks")
+	}
+	dir := t.TempDir()
+	writeHook(t, filepath.Join(dir, "on-exit.d", "10-fail"), "exit 3")
+	writeHook(t, filepath.Join(dir, "on-exit.d", "20-slow"), "sleep 5")
+	writeHook(t, filepath.Join(dir, "on-exit.d", "30-ok"), "touch "+filepath.Join(dir, "ran"))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "on-exit.d", "40-plain"), []byte("x"), 0644))
+
+	r := &Runner{Dirs: []string{dir}, Timeout: 200 * time.Millisecond, Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}}
+	errs := r.Run(Context{Event: OnExit})
+
+	require.Len(t, errs, 3)
+	assert.Contains(t, errs[0].Error(), "exit status 3")
+	assert.Contains(t, errs[1].Error(), "timed out")
+	assert.Contains(t, errs[2].Error(), "not executable")
+	// Later hooks still run after a failure
+	assert.FileExists(t, filepath.Join(dir, "ran"))
+}
+
+func TestRunCommands(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell hooks")
+	}
+	dir := t.TempDir()
+	writeHook(t, filepath.Join(dir, "on-create"), "echo file")
+
+	var stdout bytes.Buffer
+	r := &Runner{
+		Dirs:     []string{dir},
+		Commands: map[Event][]string{OnCreate: {"echo command $DENV_ENV_NAME", "exit 2"}},
+		Stdout:   &stdout,
+	}
+	errs := r.Run(Context{Event: OnCreate, Environment: "dev"})
+
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), `"exit 2"`)
+	assert.Equal(t, "file\ncommand dev\n", stdout.String())
+}
@@ -0,0 +1,5 @@
+// Package netns runs a command in an unprivileged user and network
+// namespace so it can listen on its original ports without colliding with
+// other environments. Connections to the mapped ports on the host are
+// forwarded into the namespace.
+package netns
@@ -0,0 +1,297 @@
+//go:build linux
+
+package netns
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/caoer/denv/internal/proxy"
+)
+
+const (
+	capNetAdmin = 12
+
+	prCapAmbient         = 47
+	prCapAmbientClearAll = 4
+
+	dialTimeout = 5 * time.Second
+)
+
+// helperCommand re-executes denv as the namespace helper; tests replace it
+var helperCommand = func(argv []string) (*exec.Cmd, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	return exec.Command(self, append([]string{"netns-exec", "--"}, argv...)...), nil
+}
+
+// isolate makes cmd start in new user and network namespaces, keeping the
+// current user and the capability to configure the namespace's network
+func isolate(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags:  syscall.CLONE_NEWUSER | syscall.CLONE_NEWNET,
+		UidMappings: []syscall.SysProcIDMap{{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1}},
+		GidMappings: []syscall.SysProcIDMap{{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1}},
+		AmbientCaps: []uintptr{capNetAdmin},
+	}
+}
+
+// Available reports why namespaces can't be used, or nil if they can
+func Available() error {
+	path, err := exec.LookPath("true")
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(path)
+	isolate(cmd)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("cannot create user and network namespaces: %w", err)
+	}
+	return nil
+}
+
+// Session is a command running in its own network namespace
+type Session struct {
+	cmd       *exec.Cmd
+	sock      *os.File
+	mu        sync.Mutex
+	listeners []net.Listener
+}
+
+// Start runs argv with env (nil for the current environment) in a new
+// network namespace. Connections to
+// 127.0.0.1:<mapped> on the host reach <original> inside the namespace for
+// every original → mapped entry of ports.
+func Start(argv, env []string, ports map[int]int, stdin io.Reader, stdout, stderr io.Writer) (*Session, error) {
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_SEQPACKET|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+	s := &Session{sock: os.NewFile(uintptr(fds[0]), "denv-netns")}
+	child := os.NewFile(uintptr(fds[1]), "denv-netns")
+	defer child.Close()
+
+	for orig, mapped := range ports {
+		ln, err := net.Listen("tcp", "127.0.0.1:"+strconv.Itoa(mapped))
+		if err != nil {
+			s.close()
+			return nil, fmt.Errorf("failed to listen on port %d: %w", mapped, err)
+		}
+		s.listeners = append(s.listeners, ln)
+		go s.accept(ln, orig)
+	}
+
+	cmd, err := helperCommand(argv)
+	if err != nil {
+		s.close()
+		return nil, err
+	}
+	if env != nil {
+		cmd.Env = env
+	}
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.ExtraFiles = []*os.File{child}
+	isolate(cmd)
+	if err := cmd.Start(); err != nil {
+		s.close()
+		return nil, err
+	}
+	s.cmd = cmd
+	return s, nil
+}
+
+// Wait waits for the command to exit and stops forwarding
+func (s *Session) Wait() error {
+	err := s.cmd.Wait()
+	s.close()
+	return err
+}
+
+func (s *Session) close() {
+	for _, ln := range s.listeners {
+		_ = ln.Close()
+	}
+	_ = s.sock.Close()
+}
+
+// accept hands every connection on ln to the helper inside the namespace
+func (s *Session) accept(ln net.Listener, orig int) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		s.send(conn.(*net.TCPConn), orig)
+	}
+}
+
+func (s *Session) send(conn *net.TCPConn, orig int) {
+	defer conn.Close()
+	f, err := conn.File()
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	msg := make([]byte, 4)
+	binary.BigEndian.PutUint32(msg, uint32(orig))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_ = syscall.Sendmsg(int(s.sock.Fd()), msg, syscall.UnixRights(int(f.Fd())), nil, 0)
+}
+
+// Helper runs inside the namespace: it brings up the loopback interface,
+// connects forwarded connections to their original ports and runs argv.
+// It returns argv's exit code.
+func Helper(argv []string) (int, error) {
+	if len(argv) > 0 && argv[0] == "--" {
+		argv = argv[1:]
+	}
+	if len(argv) == 0 {
+		return 1, fmt.Errorf("command required")
+	}
+	if err := bringUp("lo"); err != nil {
+		return 1, fmt.Errorf("failed to bring up loopback: %w", err)
+	}
+	go receive(os.NewFile(3, "denv-netns"))
+
+	// The command handles interrupts itself; termination is passed on
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGQUIT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	// Capabilities are per thread: drop them on the thread that forks so
+	// the command doesn't inherit them
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prCapAmbient, prCapAmbientClearAll, 0); errno != 0 {
+		return 1, errno
+	}
+
+	if err := cmd.Start(); err != nil {
+		return 1, err
+	}
+	go func() {
+		for sig := range signals {
+			if sig == syscall.SIGTERM || sig == syscall.SIGHUP {
+				_ = cmd.Process.Signal(sig)
+			}
+		}
+	}()
+	if err := cmd.Wait(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return exitErr.ExitCode(), nil
+		}
+		return 1, err
+	}
+	return 0, nil
+}
+
+// receive connects every connection sent by the host to its original port
+func receive(sock *os.File) {
+	buf := make([]byte, 4)
+	oob := make([]byte, syscall.CmsgSpace(4))
+	fd := int(sock.Fd())
+	for {
+		n, oobn, _, _, err := syscall.Recvmsg(fd, buf, oob, syscall.MSG_CMSG_CLOEXEC)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil || (n == 0 && oobn == 0) {
+			return
+		}
+
+		msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+		if err != nil {
+			continue
+		}
+		for _, msg := range msgs {
+			rights, err := syscall.ParseUnixRights(&msg)
+			if err != nil {
+				continue
+			}
+			for _, r := range rights {
+				f := os.NewFile(uintptr(r), "conn")
+				conn, err := net.FileConn(f)
+				f.Close()
+				if err != nil {
+					continue
+				}
+				if n != 4 {
+					conn.Close()
+					continue
+				}
+				go connect(conn, int(binary.BigEndian.Uint32(buf)))
+			}
+		}
+	}
+}
+
+func connect(conn net.Conn, port int) {
+	defer conn.Close()
+
+	var upstream net.Conn
+	var err error
+	for _, host := range []string{"127.0.0.1", "::1"} {
+		if upstream, err = net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(port)), dialTimeout); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return
+	}
+	defer upstream.Close()
+
+	proxy.Join(conn, upstream)
+}
+
+// ifreq is struct ifreq from <net/if.h> with the flags member of its union
+type ifreq struct {
+	name  [syscall.IFNAMSIZ]byte
+	flags uint16
+	_     [22]byte
+}
+
+// bringUp sets the named interface up
+func bringUp(name string) error {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+
+	var req ifreq
+	copy(req.name[:], name)
+	if err := ioctl(fd, syscall.SIOCGIFFLAGS, &req); err != nil {
+		return err
+	}
+	req.flags |= syscall.IFF_UP
+	return ioctl(fd, syscall.SIOCSIFFLAGS, &req)
+}
+
+func ioctl(fd int, request uintptr, req *ifreq) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), request, uintptr(unsafe.Pointer(req))); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+//go:build linux
+
+package netns
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNetnsHelperProcess is re-executed as the namespace helper and as the
+// command it runs
+func TestNetnsHelperProcess(t *testing.T) {
+	if os.Getenv("DENV_NETNS_HELPER") == "1" {
+		os.Unsetenv("DENV_NETNS_HELPER")
+		args := os.Args
+		for i, arg := range args {
+			if arg == "--" {
+				args = args[i+1:]
+				break
+			}
+		}
+		code, err := Helper(args)
+		if err != nil {
+			os.Stderr.WriteString(err.Error() + "\n")
+		}
+		os.Exit(code)
+	}
+	if port := os.Getenv("DENV_NETNS_SERVE"); port != "" {
+		ln, err := net.Listen("tcp", "127.0.0.1:"+port)
+		if err != nil {
+			os.Stderr.WriteString("serve: " + err.Error() + "\n")
+			os.Exit(2)
+		}
+		conn, err := ln.Accept()
+		if err != nil {
+			os.Exit(3)
+		}
+		// Stay up until the client has read the reply
+		_, _ = conn.Write([]byte("inside\n"))
+		_, _ = io.Copy(io.Discard, conn)
+		conn.Close()
+		os.Exit(0)
+	}
+}
+
+func freePort(t *testing.T) int {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func TestSession_ForwardsMappedPorts(t *testing.T) {
+	if err := Available(); err != nil {
+		t.Skip(err)
+	}
+
+	self, err := os.Executable()
+	require.NoError(t, err)
+	helperCommand = func(argv []string) (*exec.Cmd, error) {
+		cmd := exec.Command(self, append([]string{"-test.run=^TestNetnsHelperProcess$", "--"}, argv...)...)
+		cmd.Env = append(os.Environ(), "DENV_NETNS_HELPER=1")
+		return cmd, nil
+	}
+
+	// The original port is taken on the host, yet free inside the namespace
+	host, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer host.Close()
+	orig := host.Addr().(*net.TCPAddr).Port
+	mapped := freePort(t)
+
+	t.Setenv("DENV_NETNS_SERVE", strconv.Itoa(orig))
+	session, err := Start([]string{self, "-test.run=^TestNetnsHelperProcess$"}, nil, map[int]int{orig: mapped}, nil, os.Stdout, os.Stderr)
+	require.NoError(t, err)
+
+	var line string
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		conn, err := net.Dial("tcp", "127.0.0.1:"+strconv.Itoa(mapped))
+		require.NoError(t, err)
+		line, _ = bufio.NewReader(conn).ReadString('\n')
+		conn.Close()
+		if line != "" {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	assert.Equal(t, "inside\n", line)
+
+	require.NoError(t, session.Wait())
+
+	// Test: Forwarding stops with the command
+	_, err = net.Dial("tcp", "127.0.0.1:"+strconv.Itoa(mapped))
+	assert.Error(t, err)
+}
@@ -0,0 +1,33 @@
+//go:build !linux
+
+package netns
+
+import (
+	"errors"
+	"io"
+)
+
+var errUnsupported = errors.New("network namespaces are only available on Linux")
+
+// Available reports why namespaces can't be used, or nil if they can
+func Available() error {
+	return errUnsupported
+}
+
+// Session is a command running in its own network namespace
+type Session struct{}
+
+// Start runs argv in a new network namespace
+func Start(argv, env []string, ports map[int]int, stdin io.Reader, stdout, stderr io.Writer) (*Session, error) {
+	return nil, errUnsupported
+}
+
+// Wait waits for the command to exit and stops forwarding
+func (s *Session) Wait() error {
+	return errUnsupported
+}
+
+// Helper runs inside the namespace
+func Helper(argv []string) (int, error) {
+	return 1, errUnsupported
+}
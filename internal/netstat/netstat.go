@@ -0,0 +1,89 @@
+// Package netstat reports which processes are listening on TCP ports
+package netstat
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// stateListen is TCP_LISTEN in /proc/net/tcp
+const stateListen = "0A"
+
+// Listener is a listening TCP socket and the process owning it, if known
+type Listener struct {
+	Addr    string
+	Port    int
+	Inode   uint64
+	PID     int
+	Command string
+}
+
+// parseTCP parses /proc/net/tcp or /proc/net/tcp6, returning the
+// listening sockets
+func parseTCP(r io.Reader) ([]Listener, error) {
+	var listeners []Listener
+
+	scanner := bufio.NewScanner(r)
+	first := true
+	for scanner.Scan() {
+		if first {
+			// Header
+			first = false
+			continue
+		}
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[3] != stateListen {
+			continue
+		}
+
+		addr, port, err := parseAddr(fields[1])
+		if err != nil {
+			return nil, err
+		}
+		inode, err := strconv.ParseUint(fields[9], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid inode %q", fields[9])
+		}
+		listeners = append(listeners, Listener{Addr: addr, Port: port, Inode: inode})
+	}
+	return listeners, scanner.Err()
+}
+
+// parseAddr decodes an address like 0100007F:0BB8, whose IP is stored as
+// host-order 32-bit words
+func parseAddr(s string) (string, int, error) {
+	hexIP, hexPort, ok := strings.Cut(s, ":")
+	if !ok {
+		return "", 0, fmt.Errorf("invalid address %q", s)
+	}
+	port, err := strconv.ParseUint(hexPort, 16, 16)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid port in %q", s)
+	}
+	raw, err := hex.DecodeString(hexIP)
+	if err != nil || (len(raw) != net.IPv4len && len(raw) != net.IPv6len) {
+		return "", 0, fmt.Errorf("invalid IP in %q", s)
+	}
+
+	ip := make(net.IP, len(raw))
+	for i := 0; i < len(raw); i += 4 {
+		ip[i], ip[i+1], ip[i+2], ip[i+3] = raw[i+3], raw[i+2], raw[i+1], raw[i]
+	}
+	return ip.String(), int(port), nil
+}
+
+// parseEnviron parses the NUL-separated contents of /proc/<pid>/environ
+func parseEnviron(data []byte) map[string]string {
+	env := make(map[string]string)
+	for _, kv := range strings.Split(string(data), "\x00") {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env[k] = v
+		}
+	}
+	return env
+}
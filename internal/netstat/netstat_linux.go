@@ -0,0 +1,90 @@
+//go:build linux
+
+package netstat
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Listening returns every listening TCP socket on the machine. Owners are
+// only known for processes the current user may inspect.
+func Listening() ([]Listener, error) {
+	var listeners []Listener
+	for _, path := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		f, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found, err := parseTCP(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, found...)
+	}
+
+	owners := socketOwners()
+	for i := range listeners {
+		if pid, ok := owners[listeners[i].Inode]; ok {
+			listeners[i].PID = pid
+			listeners[i].Command = command(pid)
+		}
+	}
+	return listeners, nil
+}
+
+// ProcessEnv returns the environment a process was started with, or nil
+// if it can't be read
+func ProcessEnv(pid int) map[string]string {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "environ"))
+	if err != nil {
+		return nil
+	}
+	return parseEnviron(data)
+}
+
+// socketOwners maps socket inodes to the PIDs holding them open
+func socketOwners() map[uint64]int {
+	owners := make(map[uint64]int)
+
+	procs, _ := os.ReadDir("/proc")
+	for _, proc := range procs {
+		pid, err := strconv.Atoi(proc.Name())
+		if err != nil {
+			continue
+		}
+		fdDir := filepath.Join("/proc", proc.Name(), "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+			inode, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]"), 10, 64)
+			if err != nil {
+				continue
+			}
+			if _, ok := owners[inode]; !ok {
+				owners[inode] = pid
+			}
+		}
+	}
+	return owners
+}
+
+func command(pid int) string {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "comm"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,40 @@
+//go:build linux
+
+package netstat
+
+import (
+	"net"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListening(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	listeners, err := Listening()
+	require.NoError(t, err)
+
+	var found *Listener
+	for i := range listeners {
+		if listeners[i].Port == port {
+			found = &listeners[i]
+		}
+	}
+	require.NotNil(t, found)
+	assert.Equal(t, "127.0.0.1", found.Addr)
+	assert.Equal(t, os.Getpid(), found.PID)
+	assert.NotEmpty(t, found.Command)
+}
+
+func TestProcessEnv(t *testing.T) {
+	// The initial environment is what /proc reports
+	env := ProcessEnv(os.Getpid())
+	require.NotNil(t, env)
+	assert.Equal(t, os.Getenv("PATH"), env["PATH"])
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package netstat
+
+import "errors"
+
+// Listening returns every listening TCP socket on the machine
+func Listening() ([]Listener, error) {
+	return nil, errors.New("port inspection is only available on Linux")
+}
+
+// ProcessEnv returns the environment a process was started with
+func ProcessEnv(pid int) map[string]string {
+	return nil
+}
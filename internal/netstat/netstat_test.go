@@ -0,0 +1,44 @@
+package netstat
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const procNetTCP = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 0100007F:0BB8 00000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 12345 1 0000000000000000 100 0 0 10 0
+   1: 00000000:1538 00000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 12346 1 0000000000000000 100 0 0 10 0
+   2: 0100007F:0BB8 0100007F:D431 01 00000000:00000000 00:00000000 00000000  1000        0 12347 1 0000000000000000 20 4 30 10 -1
+`
+
+const procNetTCP6 = `  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000000000000000000001000000:84D0 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 22222 1 0000000000000000 100 0 0 10 0
+`
+
+func TestParseTCP(t *testing.T) {
+	listeners, err := parseTCP(strings.NewReader(procNetTCP))
+	require.NoError(t, err)
+
+	// Test: Only listening sockets are returned
+	require.Len(t, listeners, 2)
+	assert.Equal(t, Listener{Addr: "127.0.0.1", Port: 3000, Inode: 12345}, listeners[0])
+	assert.Equal(t, Listener{Addr: "0.0.0.0", Port: 5432, Inode: 12346}, listeners[1])
+
+	listeners, err = parseTCP(strings.NewReader(procNetTCP6))
+	require.NoError(t, err)
+	require.Len(t, listeners, 1)
+	assert.Equal(t, Listener{Addr: "::1", Port: 34000, Inode: 22222}, listeners[0])
+}
+
+func TestParseTCP_Invalid(t *testing.T) {
+	_, err := parseTCP(strings.NewReader("header\n 0: zz:0BB8 00000000:0000 0A 0 0 0 0 0 1\n"))
+	assert.Error(t, err)
+}
+
+func TestParseEnviron(t *testing.T) {
+	env := parseEnviron([]byte("DENV_ENV_NAME=feature\x00PATH=/bin:/usr/bin\x00EMPTY=\x00"))
+	assert.Equal(t, map[string]string{"DENV_ENV_NAME": "feature", "PATH": "/bin:/usr/bin", "EMPTY": ""}, env)
+}
@@ -22,6 +22,41 @@ func EnvironmentPath(project, env string) string {
 	return filepath.Join(DenvHome(), fmt.Sprintf("%s-%s", project, env))
 }
 
+// SnapshotsPath returns the directory holding snapshot archives for a project
+func SnapshotsPath(project string) string {
+	return filepath.Join(DenvHome(), ".snapshots", project)
+}
+
+// TrashPath returns the directory removed environments are moved into
+func TrashPath() string {
+	return filepath.Join(DenvHome(), ".trash")
+}
+
+// ProjectsPath returns the registry of project IDs and their aliases
+func ProjectsPath() string {
+	return filepath.Join(DenvHome(), "projects.json")
+}
+
+// TrustPath returns the file recording approved repository files
+func TrustPath() string {
+	return filepath.Join(DenvHome(), "trust.json")
+}
+
+// ProxyPath returns the directory holding proxy locks and targets
+func ProxyPath() string {
+	return filepath.Join(DenvHome(), "proxy")
+}
+
+// RouterPath returns the state file of the running router
+func RouterPath() string {
+	return filepath.Join(DenvHome(), "router.json")
+}
+
+// CAPath returns the directory holding the local certificate authority
+func CAPath() string {
+	return filepath.Join(DenvHome(), "ca")
+}
+
 // ShortenPath shortens a path by replacing the home directory with ~ and optionally limiting segments
 // maxSegments controls how many path segments to show after ~/ (0 means no limit)
 // For paths with more segments than the limit, it shows first segment, ..., and last segment
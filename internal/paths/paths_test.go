@@ -31,6 +31,37 @@ func TestEnvironmentPath(t *testing.T) {
 		EnvironmentPath("myproject", "default"))
 }
 
+func TestSnapshotsPath(t *testing.T) {
+	// Test: Snapshots live in a hidden directory so they are not listed as environments
+	home := DenvHome()
+	assert.Equal(t, filepath.Join(home, ".snapshots", "myproject"), SnapshotsPath("myproject"))
+}
+
+func TestTrashPath(t *testing.T) {
+	home := DenvHome()
+	assert.Equal(t, filepath.Join(home, ".trash"), TrashPath())
+}
+
+func TestTrustPath(t *testing.T) {
+	home := DenvHome()
+	assert.Equal(t, filepath.Join(home, "trust.json"), TrustPath())
+}
+
+func TestProxyPath(t *testing.T) {
+	home := DenvHome()
+	assert.Equal(t, filepath.Join(home, "proxy"), ProxyPath())
+}
+
+func TestRouterPath(t *testing.T) {
+	home := DenvHome()
+	assert.Equal(t, filepath.Join(home, "router.json"), RouterPath())
+}
+
+func TestCAPath(t *testing.T) {
+	home := DenvHome()
+	assert.Equal(t, filepath.Join(home, "ca"), CAPath())
+}
+
 func TestShortenPath(t *testing.T) {
 	home := os.Getenv("HOME")
 	
@@ -17,6 +17,7 @@ type PortManager struct {
 	maxPort  int
 	mu       sync.Mutex
 	mappings map[int]int
+	reserved map[int]bool
 }
 
 func NewPortManager(dir string) *PortManager {
@@ -25,6 +26,7 @@ func NewPortManager(dir string) *PortManager {
 		minPort:  30000,
 		maxPort:  39999,
 		mappings: make(map[int]int),
+		reserved: make(map[int]bool),
 	}
 	pm.load()
 	return pm
@@ -47,13 +49,25 @@ func (pm *PortManager) GetPort(originalPort int) int {
 		}
 	}
 
-	// Find a new free port
-	newPort := FindFreePort(pm.minPort, pm.maxPort)
+	// Find a new free port, skipping ports reserved by other environments
+	newPort := findFreePort(pm.minPort, pm.maxPort, pm.reserved)
 	pm.mappings[originalPort] = newPort
 	pm.save()
 	return newPort
 }
 
+// Reserve marks ports that must not be handed out for new mappings.
+// Ports mapped by other environments may be free right now (nothing is
+// listening) but still belong to them.
+func (pm *PortManager) Reserve(ports ...int) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	for _, port := range ports {
+		pm.reserved[port] = true
+	}
+}
+
 // InitializeWithPorts sets the port mappings from an existing runtime
 // This ensures that existing port mappings are respected
 func (pm *PortManager) InitializeWithPorts(ports map[int]int) {
@@ -92,6 +106,10 @@ func (pm *PortManager) save() {
 }
 
 func FindFreePort(minPort, maxPort int) int {
+	return findFreePort(minPort, maxPort, nil)
+}
+
+func findFreePort(minPort, maxPort int, reserved map[int]bool) int {
 	// rand.Seed is deprecated in Go 1.20+, auto-seeded by default
 	
 	// Try random ports in range
@@ -102,14 +120,14 @@ func FindFreePort(minPort, maxPort int) int {
 			break
 		}
 		port := minPort + int(n.Int64())
-		if IsPortAvailable(port) {
+		if !reserved[port] && IsPortAvailable(port) {
 			return port
 		}
 	}
 	
 	// Fallback: scan sequentially
 	for port := minPort; port <= maxPort; port++ {
-		if IsPortAvailable(port) {
+		if !reserved[port] && IsPortAvailable(port) {
 			return port
 		}
 	}
@@ -165,4 +165,15 @@ func TestPortManagerHandlesPortConflicts(t *testing.T) {
 		assert.GreaterOrEqual(t, mappedPort, 30000)
 		assert.LessOrEqual(t, mappedPort, 39999)
 	}
-}
\ No newline at end of file
+}
+
+func TestPortManagerSkipsReservedPorts(t *testing.T) {
+	// Test that reserved ports are never handed out for new mappings
+	tmpDir := t.TempDir()
+
+	pm := NewPortManager(tmpDir)
+	pm.SetRange(31000, 31002)
+	pm.Reserve(31000, 31001)
+
+	assert.Equal(t, 31002, pm.GetPort(3000))
+}
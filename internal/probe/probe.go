@@ -0,0 +1,81 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"time"
+)
+
+// Probe checks whether a service is ready. Exactly one of TCP, HTTP or
+// Exec is used, in that order of preference.
+type Probe struct {
+	// TCP is a host:port that must accept connections
+	TCP string
+	// HTTP is a URL that must answer with a non-error status
+	HTTP string
+	// Exec is a command that must exit successfully
+	Exec []string
+	// Env is the environment of the Exec command
+	Env []string
+}
+
+// Check runs the probe once
+func (p Probe) Check(ctx context.Context) error {
+	switch {
+	case p.TCP != "":
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", p.TCP)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+
+	case p.HTTP != "":
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.HTTP, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("%s returned %s", p.HTTP, resp.Status)
+		}
+		return nil
+
+	case len(p.Exec) > 0:
+		cmd := exec.CommandContext(ctx, p.Exec[0], p.Exec[1:]...)
+		cmd.Env = p.Env
+		return cmd.Run()
+	}
+
+	return errors.New("empty probe")
+}
+
+// Wait runs the probe every interval until it succeeds or timeout expires,
+// returning the last failure
+func (p Probe) Wait(timeout, interval time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	for {
+		attemptCtx, attemptCancel := context.WithTimeout(ctx, interval+time.Second)
+		err := p.Check(attemptCtx)
+		attemptCancel()
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("not ready after %s: %w", timeout, err)
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package probe
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTCPProbe(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+
+	p := Probe{TCP: addr}
+	assert.NoError(t, p.Check(context.Background()))
+
+	ln.Close()
+	assert.Error(t, p.Check(context.Background()))
+}
+
+func TestHTTPProbe(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	p := Probe{HTTP: srv.URL}
+	assert.Error(t, p.Check(context.Background()))
+
+	status = http.StatusOK
+	assert.NoError(t, p.Check(context.Background()))
+}
+
+func TestExecProbe(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	assert.NoError(t, Probe{Exec: []string{"sh", "-c", "exit 0"}}.Check(context.Background()))
+	assert.Error(t, Probe{Exec: []string{"sh", "-c", "exit 1"}}.Check(context.Background()))
+}
+
+func TestWait(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	ln.Close()
+
+	// Test: Times out while nothing listens
+	err = Probe{TCP: addr}.Wait(200*time.Millisecond, 50*time.Millisecond)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not ready")
+
+	// Test: Succeeds once the port opens
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		if ln, err := net.Listen("tcp", addr); err == nil {
+			defer ln.Close()
+			time.Sleep(time.Second)
+		}
+	}()
+	assert.NoError(t, Probe{TCP: addr}.Wait(2*time.Second, 50*time.Millisecond))
+}
+
+func TestEmptyProbe(t *testing.T) {
+	assert.Error(t, Probe{}.Check(context.Background()))
+}
@@ -0,0 +1,30 @@
+package process
+
+import (
+	"os/exec"
+	"time"
+)
+
+// Command returns a command running a shell command line through the
+// system shell
+func Command(command string) *exec.Cmd {
+	argv := ShellCommand(command)
+	return exec.Command(argv[0], argv[1:]...)
+}
+
+// Stop asks the process group led by pid to terminate and kills it if it is
+// still running after grace
+func Stop(pid int, grace time.Duration) error {
+	if err := Terminate(pid); err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(grace)
+	for time.Now().Before(deadline) {
+		if !Alive(pid) {
+			return nil
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return Kill(pid)
+}
@@ -0,0 +1,60 @@
+//go:build !windows
+
+package process
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStopProcessGroup(t *testing.T) {
+	// The shell's child must be stopped with it
+	cmd := Command("sleep 30 & wait")
+	NewGroup(cmd)
+	require.NoError(t, cmd.Start())
+	pid := cmd.Process.Pid
+	assert.True(t, Alive(pid))
+
+	done := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(done)
+	}()
+
+	require.NoError(t, Stop(pid, 2*time.Second))
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("process group still running")
+	}
+	assert.False(t, Alive(pid))
+}
+
+func TestIgnoresTermGetsKilled(t *testing.T) {
+	cmd := Command("trap '' TERM; sleep 30")
+	NewGroup(cmd)
+	require.NoError(t, cmd.Start())
+	// Give the shell time to install the trap
+	time.Sleep(100 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(done)
+	}()
+
+	require.NoError(t, Stop(cmd.Process.Pid, 200*time.Millisecond))
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("process survived kill")
+	}
+}
+
+func TestAlive(t *testing.T) {
+	assert.False(t, Alive(0))
+	assert.False(t, Alive(-1))
+}
@@ -0,0 +1,49 @@
+//go:build !windows
+
+package process
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// ShellCommand returns the argv running command through the system shell
+func ShellCommand(command string) []string {
+	return []string{"sh", "-c", command}
+}
+
+// Detach makes cmd outlive the calling process and its terminal
+func Detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
+
+// NewGroup starts cmd in its own process group so it can be signalled
+// together with its children
+func NewGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// Terminate sends SIGTERM to the process group led by pid
+func Terminate(pid int) error {
+	if err := syscall.Kill(-pid, syscall.SIGTERM); err != nil {
+		return syscall.Kill(pid, syscall.SIGTERM)
+	}
+	return nil
+}
+
+// Kill sends SIGKILL to the process group led by pid
+func Kill(pid int) error {
+	if err := syscall.Kill(-pid, syscall.SIGKILL); err != nil {
+		return syscall.Kill(pid, syscall.SIGKILL)
+	}
+	return nil
+}
+
+// Alive reports whether a process with pid exists
+func Alive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,57 @@
+//go:build windows
+
+package process
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+const (
+	createNewProcessGroup = 0x00000200
+	detachedProcess       = 0x00000008
+)
+
+// ShellCommand returns the argv running command through the system shell
+func ShellCommand(command string) []string {
+	return []string{"cmd", "/C", command}
+}
+
+// Detach makes cmd outlive the calling process and its console
+func Detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: createNewProcessGroup | detachedProcess}
+}
+
+// NewGroup starts cmd in its own process group
+func NewGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: createNewProcessGroup}
+}
+
+// Terminate stops the process; Windows has no SIGTERM for console-less
+// processes, so this is the same as Kill
+func Terminate(pid int) error {
+	return Kill(pid)
+}
+
+// Kill stops the process
+func Kill(pid int) error {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return p.Kill()
+}
+
+// Alive reports whether a process with pid exists
+func Alive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
@@ -0,0 +1,56 @@
+package procfile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Entry is one process type of a Procfile
+type Entry struct {
+	Name    string
+	Command string
+}
+
+var entryPattern = regexp.MustCompile(`^([A-Za-z0-9_-]+):\s*(.+)$`)
+
+// Load parses the Procfile at path
+func Load(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse reads "name: command" lines, ignoring blank lines and # comments
+func Parse(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		m := entryPattern.FindStringSubmatch(line)
+		if m == nil {
+			return nil, fmt.Errorf("line %d: expected 'name: command'", lineNo)
+		}
+		if seen[m[1]] {
+			return nil, fmt.Errorf("line %d: duplicate process '%s'", lineNo, m[1])
+		}
+		seen[m[1]] = true
+		entries = append(entries, Entry{Name: m[1], Command: strings.TrimSpace(m[2])})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,47 @@
+package procfile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	input := `# Processes
+web: bundle exec rails server -p $PORT
+
+worker:   bundle exec sidekiq
+css-watch: npm run watch:css
+`
+	entries, err := Parse(strings.NewReader(input))
+	require.NoError(t, err)
+	assert.Equal(t, []Entry{
+		{Name: "web", Command: "bundle exec rails server -p $PORT"},
+		{Name: "worker", Command: "bundle exec sidekiq"},
+		{Name: "css-watch", Command: "npm run watch:css"},
+	}, entries)
+}
+
+func TestParseErrors(t *testing.T) {
+	_, err := Parse(strings.NewReader("web: a\nnot a process\n"))
+	assert.ErrorContains(t, err, "line 2")
+
+	_, err = Parse(strings.NewReader("web: a\nweb: b\n"))
+	assert.ErrorContains(t, err, "duplicate process 'web'")
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Procfile")
+	require.NoError(t, os.WriteFile(path, []byte("web: ./server\n"), 0644))
+
+	entries, err := Load(path)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
@@ -1,30 +1,120 @@
 package project
 
 import (
+	"fmt"
+	"net/url"
+	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/caoer/denv/internal/config"
+	"github.com/caoer/denv/internal/paths"
 )
 
+// remotePriority orders the remotes consulted for a project's identity;
+// other remotes follow by name
+var remotePriority = []string{"origin", "upstream"}
+
+// DetectProject returns the alias of the project in dir, the name its
+// directories in DENV_HOME and DENV_PROJECT_NAME use. It only reads the
+// registry: an unregistered project gets the alias registering it would.
 func DetectProject(dir string) (string, error) {
-	// Try git remote first
-	cmd := exec.Command("git", "remote", "get-url", "origin")
+	id, err := DetectID(dir)
+	if err != nil {
+		return "", err
+	}
+	return lookupAlias(id), nil
+}
+
+// RegisterProject returns the alias of the project in dir like
+// DetectProject, registering the project if new. Only commands creating
+// environments register, so other directories don't take up aliases.
+func RegisterProject(dir string) (string, error) {
+	id, err := DetectID(dir)
+	if err != nil {
+		return "", err
+	}
+	return registerAlias(id), nil
+}
+
+// DetectID returns the canonical ID of the project in dir: host/owner/repo
+// from the first git remote in priority order, or the directory itself
+// outside a repository with remotes
+func DetectID(dir string) (string, error) {
+	cmd := exec.Command("git", "remote", "-v")
 	cmd.Dir = dir
 	output, err := cmd.Output()
 	if err == nil {
-		// Extract project name from git URL
-		url := strings.TrimSpace(string(output))
-		return extractProjectName(url), nil
+		if remote := preferredRemote(string(output)); remote != "" {
+			return CanonicalID(remote), nil
+		}
+	}
+
+	// Fall back to the directory
+	return filepath.Abs(dir)
+}
+
+// lookupAlias returns the alias of id without writing the registry
+func lookupAlias(id string) string {
+	registry, err := LoadRegistry(paths.ProjectsPath())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to load project registry: %v\n", err)
+		return fallbackAlias(id)
+	}
+	if alias, ok := registry.Lookup(id); ok {
+		return alias
+	}
+	return registry.Preview(id)
+}
+
+// registerAlias returns the alias of id, registering id if new
+func registerAlias(id string) string {
+	path := paths.ProjectsPath()
+	registry, err := LoadRegistry(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to load project registry: %v\n", err)
+		return fallbackAlias(id)
+	}
+	if alias, ok := registry.Lookup(id); ok {
+		return alias
+	}
+
+	a, err := Register(path, id)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to register project: %v\n", err)
+		if a.Alias == "" {
+			return fallbackAlias(id)
+		}
+	}
+	if a.Taken != "" {
+		fmt.Fprintf(os.Stderr, "Warning: project name '%s' is used by %s; %s is called '%s' (run 'denv project rename <name>' to choose another)\n",
+			aliasCandidates(id)[0], a.Taken, id, a.Alias)
 	}
+	return a.Alias
+}
 
-	// Fall back to folder name
-	return filepath.Base(dir), nil
+// fallbackAlias is the alias used when the registry can't be consulted:
+// the most qualified one, which is least likely to be another project's
+func fallbackAlias(id string) string {
+	candidates := aliasCandidates(id)
+	return candidates[len(candidates)-1]
 }
 
+// DetectProjectWithConfig returns the name of the project in dir, honouring
+// the overrides and sub-project settings in cfg, without registering it
 func DetectProjectWithConfig(dir string, cfg *config.Config) string {
+	return projectWithConfig(dir, cfg, DetectProject)
+}
+
+// RegisterProjectWithConfig is DetectProjectWithConfig for commands creating
+// environments: it registers the project if new
+func RegisterProjectWithConfig(dir string, cfg *config.Config) string {
+	return projectWithConfig(dir, cfg, RegisterProject)
+}
+
+func projectWithConfig(dir string, cfg *config.Config, detect func(string) (string, error)) string {
 	// Check for override in config
 	if cfg != nil && cfg.Projects != nil {
 		if name, ok := cfg.Projects[dir]; ok {
@@ -32,34 +122,71 @@ func DetectProjectWithConfig(dir string, cfg *config.Config) string {
 		}
 	}
 
+	// Sub-projects of a monorepo get their own environments if separate
+	if subprojectMode(cfg) == config.SubprojectsSeparate {
+		if sub := DetectSubproject(dir, cfg); sub != nil {
+			sub.Parent = projectWithConfig(sub.Root, cfg, detect)
+			return sub.Alias()
+		}
+	}
+
 	// Fall back to regular detection
-	name, _ := DetectProject(dir)
+	name, _ := detect(dir)
 	return name
 }
 
-func extractProjectName(gitURL string) string {
-	// Remove .git suffix
-	gitURL = strings.TrimSuffix(gitURL, ".git")
+// preferredRemote returns the fetch URL of the highest priority remote in
+// `git remote -v` output
+func preferredRemote(output string) string {
+	urls := make(map[string]string)
+	var names []string
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[2] != "(fetch)" {
+			continue
+		}
+		if _, ok := urls[fields[0]]; !ok {
+			names = append(names, fields[0])
+		}
+		urls[fields[0]] = fields[1]
+	}
 
-	// Handle SSH URLs (git@github.com:user/repo)
-	if strings.HasPrefix(gitURL, "git@") {
-		parts := strings.Split(gitURL, ":")
-		if len(parts) >= 2 {
-			gitURL = parts[1]
+	for _, name := range remotePriority {
+		if u, ok := urls[name]; ok {
+			return u
 		}
 	}
+	sort.Strings(names)
+	if len(names) > 0 {
+		return urls[names[0]]
+	}
+	return ""
+}
 
-	// Handle HTTPS URLs (https://github.com/user/repo)
-	re := regexp.MustCompile(`^https?://[^/]+/(.+)$`)
-	if matches := re.FindStringSubmatch(gitURL); len(matches) > 1 {
-		gitURL = matches[1]
+// CanonicalID turns a git remote URL into host/owner/repo, so HTTPS and
+// SSH remotes of a repository agree. Local paths stay paths.
+func CanonicalID(remote string) string {
+	remote = strings.TrimSuffix(strings.TrimSuffix(strings.TrimSpace(remote), "/"), ".git")
+
+	if strings.Contains(remote, "://") {
+		u, err := url.Parse(remote)
+		if err == nil {
+			path := strings.Trim(u.Path, "/")
+			if u.Scheme == "file" || u.Host == "" {
+				return "/" + path
+			}
+			return strings.ToLower(u.Hostname()) + "/" + path
+		}
 	}
 
-	// Extract the repo name (last part of the path)
-	parts := strings.Split(gitURL, "/")
-	if len(parts) > 0 {
-		return parts[len(parts)-1]
+	// scp-like syntax: [user@]host:owner/repo
+	if i := strings.Index(remote, ":"); i > 1 && !strings.Contains(remote[:i], "/") {
+		host := remote[:i]
+		if at := strings.LastIndex(host, "@"); at >= 0 {
+			host = host[at+1:]
+		}
+		return strings.ToLower(host) + "/" + strings.Trim(remote[i+1:], "/")
 	}
 
-	return "unknown"
-}
\ No newline at end of file
+	return filepath.Clean(remote)
+}
@@ -1,15 +1,22 @@
 package project
 
 import (
+	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/caoer/denv/internal/config"
+	"github.com/caoer/denv/internal/environment"
+	"github.com/caoer/denv/internal/paths"
 	"github.com/caoer/denv/internal/testutil"
 )
 
 func TestDetectGitProject(t *testing.T) {
+	t.Setenv("DENV_HOME", t.TempDir())
+
 	// Setup: Create temp git repo
 	tmpDir := t.TempDir()
 	testutil.RunCmd(t, tmpDir, "git", "init")
@@ -22,6 +29,8 @@ func TestDetectGitProject(t *testing.T) {
 }
 
 func TestDetectGitWorktree(t *testing.T) {
+	t.Setenv("DENV_HOME", t.TempDir())
+
 	// Setup: Create main repo and worktree
 	mainDir := filepath.Join(t.TempDir(), "main")
 	worktreeDir := filepath.Join(t.TempDir(), "worktree")
@@ -42,6 +51,8 @@ func TestDetectGitWorktree(t *testing.T) {
 }
 
 func TestDetectFolderName(t *testing.T) {
+	t.Setenv("DENV_HOME", t.TempDir())
+
 	// Test: Should use folder name when no git
 	tmpDir := filepath.Join(t.TempDir(), "testfolder")
 	testutil.RunCmd(t, "", "mkdir", "-p", tmpDir)
@@ -62,4 +73,171 @@ func TestDetectWithOverride(t *testing.T) {
 	// Test: Should use override
 	name := DetectProjectWithConfig("/my/path", cfg)
 	assert.Equal(t, "custom-project", name)
-}
\ No newline at end of file
+}
+
+func TestDetectID(t *testing.T) {
+	t.Setenv("DENV_HOME", t.TempDir())
+	tmpDir := t.TempDir()
+	testutil.RunCmd(t, tmpDir, "git", "init")
+
+	// Test: Without remotes the directory is the ID
+	id, err := DetectID(tmpDir)
+	require.NoError(t, err)
+	assert.Equal(t, tmpDir, id)
+
+	// Test: Any remote beats the directory, upstream beats it, origin beats all
+	testutil.RunCmd(t, tmpDir, "git", "remote", "add", "fork", "git@github.com:me/api.git")
+	id, _ = DetectID(tmpDir)
+	assert.Equal(t, "github.com/me/api", id)
+
+	testutil.RunCmd(t, tmpDir, "git", "remote", "add", "upstream", "https://github.com/acme/api.git")
+	id, _ = DetectID(tmpDir)
+	assert.Equal(t, "github.com/acme/api", id)
+
+	testutil.RunCmd(t, tmpDir, "git", "remote", "add", "origin", "https://gitlab.com/other/api.git")
+	id, _ = DetectID(tmpDir)
+	assert.Equal(t, "gitlab.com/other/api", id)
+}
+
+func TestCanonicalID(t *testing.T) {
+	tests := []struct {
+		remote   string
+		expected string
+	}{
+		{"https://github.com/acme/api.git", "github.com/acme/api"},
+		{"https://user@GitHub.com/acme/api/", "github.com/acme/api"},
+		{"git@github.com:acme/api.git", "github.com/acme/api"},
+		{"ssh://git@github.com:22/acme/api.git", "github.com/acme/api"},
+		{"gitlab.com:group/sub/api", "gitlab.com/group/sub/api"},
+		{"file:///srv/git/api.git", "/srv/git/api"},
+		{"/srv/git/api.git", "/srv/git/api"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, CanonicalID(tt.remote), tt.remote)
+	}
+}
+
+func TestDetectProject_SameNameDifferentOwners(t *testing.T) {
+	t.Setenv("DENV_HOME", t.TempDir())
+
+	acme := t.TempDir()
+	testutil.RunCmd(t, acme, "git", "init")
+	testutil.RunCmd(t, acme, "git", "remote", "add", "origin", "https://github.com/acme/api.git")
+	other := t.TempDir()
+	testutil.RunCmd(t, other, "git", "init")
+	testutil.RunCmd(t, other, "git", "remote", "add", "origin", "git@gitlab.com:other/api.git")
+
+	// Test: The first keeps the short name, the second is qualified
+	name, err := RegisterProject(acme)
+	require.NoError(t, err)
+	assert.Equal(t, "api", name)
+	name, err = RegisterProject(other)
+	require.NoError(t, err)
+	assert.Equal(t, "other-api", name)
+
+	// Test: Aliases are stable
+	name, _ = DetectProject(acme)
+	assert.Equal(t, "api", name)
+}
+
+func TestRegistry_Assign(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "projects.json")
+	r, err := LoadRegistry(path)
+	require.NoError(t, err)
+
+	a, err := r.Assign("github.com/acme/api")
+	require.NoError(t, err)
+	assert.Equal(t, "api", a.Alias)
+	assert.Empty(t, a.Taken)
+
+	// Test: Conflicts fall back to owner-repo, host-owner-repo, then a number
+	a, _ = r.Assign("gitlab.com/other/api")
+	assert.Equal(t, "other-api", a.Alias)
+	assert.Equal(t, "github.com/acme/api", a.Taken)
+	a, _ = r.Assign("github.com/other/api")
+	assert.Equal(t, "github.com-other-api", a.Alias)
+	r.Aliases["x"] = "example.com-other-api"
+	a, _ = r.Assign("example.com/other/api")
+	assert.Equal(t, "example.com-other-api-2", a.Alias)
+
+	// Test: The registry is persisted
+	loaded, err := LoadRegistry(path)
+	require.NoError(t, err)
+	alias, ok := loaded.Lookup("gitlab.com/other/api")
+	assert.True(t, ok)
+	assert.Equal(t, "other-api", alias)
+}
+
+// createLegacyEnvironment creates an environment directory the way denv
+// versions before the registry named it
+func createLegacyEnvironment(t *testing.T, home, project, env string) string {
+	envPath := filepath.Join(home, project+"-"+env)
+	require.NoError(t, os.MkdirAll(envPath, 0755))
+	require.NoError(t, environment.SaveRuntime(envPath, environment.NewRuntime(project, env)))
+	return envPath
+}
+
+func TestRegistry_MigratesLegacyDirectories(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("DENV_HOME", home)
+
+	acme := t.TempDir()
+	testutil.RunCmd(t, acme, "git", "init")
+	testutil.RunCmd(t, acme, "git", "remote", "add", "origin", "https://github.com/acme/api.git")
+	other := t.TempDir()
+	testutil.RunCmd(t, other, "git", "init")
+	testutil.RunCmd(t, other, "git", "remote", "add", "origin", "git@gitlab.com:other/api.git")
+
+	createLegacyEnvironment(t, home, "api", "default")
+	createLegacyEnvironment(t, home, "api", "test")
+	// Another project's directory sharing the prefix isn't api's
+	createLegacyEnvironment(t, home, "api-gateway", "default")
+
+	// Test: The first ID taking the name adopts its directories
+	name, err := RegisterProject(acme)
+	require.NoError(t, err)
+	assert.Equal(t, "api", name)
+
+	r, err := LoadRegistry(paths.ProjectsPath())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"api-default": "github.com/acme/api",
+		"api-test":    "github.com/acme/api",
+	}, r.Migrated)
+
+	// Test: A competing ID doesn't
+	name, err = RegisterProject(other)
+	require.NoError(t, err)
+	assert.Equal(t, "other-api", name)
+	r, err = LoadRegistry(paths.ProjectsPath())
+	require.NoError(t, err)
+	assert.Len(t, r.Migrated, 2)
+}
+
+func TestDetectProject_ReadOnly(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("DENV_HOME", home)
+	dir := t.TempDir()
+	testutil.RunCmd(t, dir, "git", "init")
+	testutil.RunCmd(t, dir, "git", "remote", "add", "origin", "https://github.com/acme/api.git")
+	createLegacyEnvironment(t, home, "api", "default")
+
+	// Test: Detecting an unregistered project names it without registering
+	name, err := DetectProject(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "api", name)
+	_, err = os.Stat(paths.ProjectsPath())
+	assert.True(t, os.IsNotExist(err))
+
+	// Test: Detecting a registered project doesn't rewrite the registry
+	_, err = RegisterProject(dir)
+	require.NoError(t, err)
+	past := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(paths.ProjectsPath(), past, past))
+	name, err = DetectProject(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "api", name)
+	info, err := os.Stat(paths.ProjectsPath())
+	require.NoError(t, err)
+	assert.True(t, info.ModTime().Equal(past))
+}
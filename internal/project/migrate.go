@@ -0,0 +1,34 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/caoer/denv/internal/environment"
+)
+
+// legacyEnvironments returns the environment directories in home whose
+// runtime.json names project as theirs. Once an alias is registered its
+// directories belong to its ID, so for an unregistered alias these are
+// left by denv versions before the registry (or by a name override).
+func legacyEnvironments(home, project string) []string {
+	entries, err := os.ReadDir(home)
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), project+"-") {
+			continue
+		}
+		runtime, _ := environment.LoadRuntime(filepath.Join(home, entry.Name()))
+		if runtime != nil && runtime.Project == project {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,193 @@
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/caoer/denv/internal/session"
+)
+
+// Registry assigns every canonical project ID the alias its directories in
+// DENV_HOME are named after, so same-named repositories don't share them
+type Registry struct {
+	path    string
+	Aliases map[string]string `json:"aliases"`
+	// Migrated records the directories left by denv versions before the
+	// registry and the ID each was matched to
+	Migrated map[string]string `json:"migrated,omitempty"`
+}
+
+// Assignment is the outcome of registering an ID
+type Assignment struct {
+	Alias string
+	// Taken is the ID holding the alias the ID would have had, if any
+	Taken string
+	// Adopted are the directories left under the alias by earlier versions
+	Adopted []string
+}
+
+// LoadRegistry reads the registry at path; a missing registry is empty
+func LoadRegistry(path string) (*Registry, error) {
+	r := &Registry{path: path, Aliases: make(map[string]string), Migrated: make(map[string]string)}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return r, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, err
+	}
+	if r.Aliases == nil {
+		r.Aliases = make(map[string]string)
+	}
+	if r.Migrated == nil {
+		r.Migrated = make(map[string]string)
+	}
+	return r, nil
+}
+
+// Lookup returns the alias of a registered id
+func (r *Registry) Lookup(id string) (string, bool) {
+	alias, ok := r.Aliases[id]
+	return alias, ok
+}
+
+// Owner returns the ID holding alias, or ""
+func (r *Registry) Owner(alias string) string {
+	for id, a := range r.Aliases {
+		if a == alias {
+			return id
+		}
+	}
+	return ""
+}
+
+// Register assigns id an alias in the registry at path, holding a lock so
+// concurrent shells registering the same ID agree
+func Register(path, id string) (Assignment, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return Assignment{}, err
+	}
+	lock, err := acquireRegistryLock(path + ".lock")
+	if err != nil {
+		return Assignment{}, err
+	}
+	defer lock.Release()
+
+	// Another process may have registered it since it was looked up
+	r, err := LoadRegistry(path)
+	if err != nil {
+		return Assignment{}, err
+	}
+	return r.Assign(id)
+}
+
+// acquireRegistryLock waits up to a second for the registry lock
+func acquireRegistryLock(path string) (*session.FileLock, error) {
+	var err error
+	for i := 0; i < 50; i++ {
+		var lock *session.FileLock
+		if lock, err = session.AcquireLock(path); err == nil {
+			return lock, nil
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return nil, fmt.Errorf("failed to lock project registry: %w", err)
+}
+
+// Assign returns the alias of id, registering one if id is new. A new ID
+// gets its repository name unless another ID holds it; on a conflict the
+// alias is qualified with the owner, then the host, and Taken reports the
+// ID holding the plain name. Directories left under the alias by denv
+// versions before the registry are migrated to the ID taking it.
+func (r *Registry) Assign(id string) (Assignment, error) {
+	if alias, ok := r.Aliases[id]; ok {
+		return Assignment{Alias: alias}, nil
+	}
+	a := r.assign(id)
+	r.Aliases[id] = a.Alias
+	a.Adopted = legacyEnvironments(filepath.Dir(r.path), a.Alias)
+	for _, name := range a.Adopted {
+		r.Migrated[name] = id
+	}
+	return a, r.save()
+}
+
+// Preview returns the alias registering the unregistered id would give it,
+// without registering it
+func (r *Registry) Preview(id string) string {
+	return r.assign(id).Alias
+}
+
+// assign picks the alias of a new id; see Assign
+func (r *Registry) assign(id string) Assignment {
+	var a Assignment
+	candidates := aliasCandidates(id)
+	for _, c := range candidates {
+		if r.Owner(c) == "" {
+			a.Alias = c
+			break
+		}
+	}
+	for n := 2; a.Alias == ""; n++ {
+		if c := fmt.Sprintf("%s-%d", candidates[len(candidates)-1], n); r.Owner(c) == "" {
+			a.Alias = c
+		}
+	}
+	if a.Alias != candidates[0] {
+		a.Taken = r.Owner(candidates[0])
+	}
+	return a
+}
+
+// aliasCandidates returns the aliases id may take, shortest first:
+// "repo", "owner-repo", then "host-owner-repo"
+func aliasCandidates(id string) []string {
+	segments := strings.FieldsFunc(id, func(r rune) bool { return r == '/' })
+	if len(segments) == 0 {
+		return []string{"unknown"}
+	}
+
+	var candidates []string
+	for n := 1; n <= len(segments) && n <= 3; n++ {
+		candidates = append(candidates, strings.Join(segments[len(segments)-n:], "-"))
+	}
+	if len(segments) > 3 {
+		candidates = append(candidates, strings.Join(segments, "-"))
+	}
+	return candidates
+}
+
+func (r *Registry) save() error {
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(r.path), filepath.Base(r.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), r.path)
+}
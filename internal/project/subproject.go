@@ -0,0 +1,104 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/caoer/denv/internal/config"
+)
+
+// Subproject is a directory below a repository's root with its own
+// environments, such as a service in a monorepo
+type Subproject struct {
+	// ID is the repository's ID followed by Path
+	ID string
+	// Parent is the name of the repository's project
+	Parent string
+	// Path is the directory relative to the repository root, with slashes
+	Path string
+	// Root is the repository's directory
+	Root string
+}
+
+// Alias returns the name the sub-project's directories use. The dots keep
+// it apart from the parent's "<project>-<env>" directories.
+func (s *Subproject) Alias() string {
+	return s.Parent + "." + strings.ReplaceAll(s.Path, "/", ".")
+}
+
+// DetectSubproject returns the sub-project containing dir: the nearest
+// directory holding one of the configured markers, walking up to but not
+// including the repository root. It returns nil at the root, outside a
+// repository or when detection is off. Whether the sub-project gets its own
+// environments depends on the mode.
+func DetectSubproject(dir string, cfg *config.Config) *Subproject {
+	if mode, _ := cfg.SubprojectMode(); mode == config.SubprojectsOff {
+		return nil
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil
+	}
+	root := repositoryRoot(dir)
+	if root == "" || root == dir {
+		return nil
+	}
+
+	markers := cfg.SubprojectMarkers()
+	for sub := dir; sub != root; sub = filepath.Dir(sub) {
+		if !hasMarker(sub, markers) {
+			continue
+		}
+		rel, err := filepath.Rel(root, sub)
+		if err != nil {
+			return nil
+		}
+		parentID, err := DetectID(root)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		return &Subproject{
+			ID:     parentID + "/" + rel,
+			Parent: DetectProjectWithConfig(root, cfg),
+			Path:   rel,
+			Root:   root,
+		}
+	}
+	return nil
+}
+
+// subprojectMode returns the sub-project mode of cfg, warning about an
+// unknown one and using the default instead
+func subprojectMode(cfg *config.Config) string {
+	mode, err := cfg.SubprojectMode()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v; using %s\n", err, mode)
+	}
+	return mode
+}
+
+// repositoryRoot returns the nearest directory containing .git, or ""
+func repositoryRoot(dir string) string {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+func hasMarker(dir string, markers []string) bool {
+	for _, marker := range markers {
+		if _, err := os.Stat(filepath.Join(dir, marker)); err == nil {
+			return true
+		}
+	}
+	return false
+}
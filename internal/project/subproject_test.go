@@ -0,0 +1,98 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/caoer/denv/internal/config"
+	"github.com/caoer/denv/internal/paths"
+	"github.com/caoer/denv/internal/testutil"
+)
+
+// setupMonorepo creates a repository with a Go service in services/billing
+// and returns its root
+func setupMonorepo(t *testing.T) string {
+	t.Setenv("DENV_HOME", t.TempDir())
+
+	root := filepath.Join(t.TempDir(), "monorepo")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "services", "billing", "cmd"), 0755))
+	testutil.RunCmd(t, root, "git", "init")
+	testutil.RunCmd(t, root, "git", "remote", "add", "origin", "https://github.com/acme/monorepo.git")
+	require.NoError(t, os.WriteFile(filepath.Join(root, "package.json"), []byte("{}"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "services", "billing", "go.mod"), []byte("module billing\n"), 0644))
+	return root
+}
+
+func TestDetectSubproject(t *testing.T) {
+	root := setupMonorepo(t)
+
+	// Test: The nearest marker below the root wins
+	sub := DetectSubproject(filepath.Join(root, "services", "billing", "cmd"), nil)
+	require.NotNil(t, sub)
+	assert.Equal(t, "github.com/acme/monorepo/services/billing", sub.ID)
+	assert.Equal(t, "monorepo", sub.Parent)
+	assert.Equal(t, "services/billing", sub.Path)
+	assert.Equal(t, "monorepo.services.billing", sub.Alias())
+
+	// Test: The root and directories without a marker below it aren't
+	assert.Nil(t, DetectSubproject(root, nil))
+	assert.Nil(t, DetectSubproject(filepath.Join(root, "services"), nil))
+}
+
+func TestDetectSubproject_Outside(t *testing.T) {
+	t.Setenv("DENV_HOME", t.TempDir())
+
+	// Test: Without a repository there's no root to be below
+	dir := filepath.Join(t.TempDir(), "app")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module app\n"), 0644))
+	assert.Nil(t, DetectSubproject(dir, nil))
+}
+
+func TestDetectSubproject_Markers(t *testing.T) {
+	root := setupMonorepo(t)
+	billing := filepath.Join(root, "services", "billing")
+
+	cfg := &config.Config{Subprojects: config.Subprojects{Markers: []string{"BUILD"}}}
+	assert.Nil(t, DetectSubproject(billing, cfg))
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, "services", "BUILD"), nil, 0644))
+	sub := DetectSubproject(billing, cfg)
+	require.NotNil(t, sub)
+	assert.Equal(t, "services", sub.Path)
+}
+
+func TestDetectProjectWithConfig_Subprojects(t *testing.T) {
+	root := setupMonorepo(t)
+	billing := filepath.Join(root, "services", "billing")
+
+	// Test: Sub-projects share the repository's name by default
+	assert.Equal(t, "monorepo", DetectProjectWithConfig(billing, &config.Config{}))
+	assert.Equal(t, "monorepo", DetectProjectWithConfig(billing, nil))
+
+	// Test: Separate sub-projects get their own name
+	separate := &config.Config{Subprojects: config.Subprojects{Mode: config.SubprojectsSeparate}}
+	assert.Equal(t, "monorepo.services.billing", DetectProjectWithConfig(billing, separate))
+	assert.Equal(t, "monorepo", DetectProjectWithConfig(root, separate))
+
+	// Test: Disabled detection and unknown modes use the repository's
+	off := &config.Config{Subprojects: config.Subprojects{Mode: config.SubprojectsOff}}
+	assert.Equal(t, "monorepo", DetectProjectWithConfig(billing, off))
+	bogus := &config.Config{Subprojects: config.Subprojects{Mode: "seperate"}}
+	assert.Equal(t, "monorepo", DetectProjectWithConfig(billing, bogus))
+
+	// Test: Registering a sub-project registers its repository
+	assert.Equal(t, "monorepo.services.billing", RegisterProjectWithConfig(billing, separate))
+	r, err := LoadRegistry(paths.ProjectsPath())
+	require.NoError(t, err)
+	alias, ok := r.Lookup("github.com/acme/monorepo")
+	assert.True(t, ok)
+	assert.Equal(t, "monorepo", alias)
+
+	// Test: The repository's override names its sub-projects too
+	separate.Projects = map[string]string{root: "shop"}
+	assert.Equal(t, "shop.services.billing", DetectProjectWithConfig(billing, separate))
+}
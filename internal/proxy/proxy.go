@@ -0,0 +1,114 @@
+package proxy
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+const dialTimeout = 5 * time.Second
+
+// Forwarder accepts TCP connections on a local address and pipes them to a
+// target address that can be switched while it runs
+type Forwarder struct {
+	ln     net.Listener
+	mu     sync.RWMutex
+	target string
+}
+
+// Listen starts listening on addr, forwarding to target
+func Listen(addr, target string) (*Forwarder, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewForwarder(ln, target), nil
+}
+
+// NewForwarder forwards connections accepted by ln to target, e.g. to
+// forward from a TLS listener
+func NewForwarder(ln net.Listener, target string) *Forwarder {
+	return &Forwarder{ln: ln, target: target}
+}
+
+// Addr returns the listening address
+func (f *Forwarder) Addr() net.Addr {
+	return f.ln.Addr()
+}
+
+// Target returns the current target address; "" refuses connections
+func (f *Forwarder) Target() string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.target
+}
+
+// SetTarget switches new connections to target. Established connections
+// keep their original target.
+func (f *Forwarder) SetTarget(target string) {
+	f.mu.Lock()
+	f.target = target
+	f.mu.Unlock()
+}
+
+// Serve accepts connections until the forwarder is closed
+func (f *Forwarder) Serve() error {
+	for {
+		conn, err := f.ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go f.handle(conn)
+	}
+}
+
+// Close stops accepting connections
+func (f *Forwarder) Close() error {
+	return f.ln.Close()
+}
+
+func (f *Forwarder) handle(conn net.Conn) {
+	defer conn.Close()
+
+	target := f.Target()
+	if target == "" {
+		return
+	}
+	upstream, err := net.DialTimeout("tcp", target, dialTimeout)
+	if err != nil {
+		return
+	}
+	defer upstream.Close()
+
+	Join(conn, upstream)
+}
+
+// Join pipes a and b to each other until both directions are done
+func Join(a, b net.Conn) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		pipe(b, a)
+	}()
+	go func() {
+		defer wg.Done()
+		pipe(a, b)
+	}()
+	wg.Wait()
+}
+
+// pipe copies src to dst and then half-closes dst so the peer sees EOF
+func pipe(dst, src net.Conn) {
+	_, _ = io.Copy(dst, src)
+	if cw, ok := dst.(interface{ CloseWrite() error }); ok {
+		_ = cw.CloseWrite()
+	} else {
+		_ = dst.Close()
+	}
+}
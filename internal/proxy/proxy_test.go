@@ -0,0 +1,108 @@
+package proxy
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// backend answers every connection with name
+func backend(t *testing.T, name string) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			_, _ = conn.Write([]byte(name + "\n"))
+			conn.Close()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func read(t *testing.T, addr string) string {
+	conn, err := net.DialTimeout("tcp", addr, time.Second)
+	require.NoError(t, err)
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	line, _ := bufio.NewReader(conn).ReadString('\n')
+	return line
+}
+
+func TestForwarderSwitch(t *testing.T) {
+	a := backend(t, "a")
+	b := backend(t, "b")
+
+	f, err := Listen("127.0.0.1:0", a)
+	require.NoError(t, err)
+	defer f.Close()
+	go func() { _ = f.Serve() }()
+
+	addr := f.Addr().String()
+	assert.Equal(t, "a\n", read(t, addr))
+
+	// Test: New connections follow the switch
+	f.SetTarget(b)
+	assert.Equal(t, "b\n", read(t, addr))
+
+	// Test: No target refuses by closing
+	f.SetTarget("")
+	assert.Equal(t, "", read(t, addr))
+}
+
+func TestForwarderEcho(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		_, _ = conn.Write([]byte("echo " + line))
+	}()
+
+	f, err := Listen("127.0.0.1:0", ln.Addr().String())
+	require.NoError(t, err)
+	defer f.Close()
+	go func() { _ = f.Serve() }()
+
+	conn, err := net.Dial("tcp", f.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("hello\n"))
+	require.NoError(t, err)
+
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "echo hello\n", line)
+}
+
+func TestServeReturnsOnClose(t *testing.T) {
+	f, err := Listen("127.0.0.1:0", "")
+	require.NoError(t, err)
+
+	done := make(chan error, 1)
+	go func() { done <- f.Serve() }()
+	require.NoError(t, f.Close())
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Serve did not return")
+	}
+}
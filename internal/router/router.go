@@ -0,0 +1,130 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Suffix is the domain environments are routed under
+const Suffix = ".localhost"
+
+// Routes maps an environment's routing name ("<env>.<project>") to its
+// original → mapped ports
+type Routes map[string]map[int]int
+
+// Hostname returns the host an environment is reachable at
+func Hostname(project, env string) string {
+	return strings.ToLower(env + "." + project + Suffix)
+}
+
+// Table is the routing table shared by all listeners. It is safe for
+// concurrent use.
+type Table struct {
+	mu     sync.RWMutex
+	routes Routes
+}
+
+// NewTable returns an empty table
+func NewTable() *Table {
+	return &Table{routes: make(Routes)}
+}
+
+// Set replaces the routing table. Keys are hostnames as returned by
+// Hostname.
+func (t *Table) Set(routes Routes) {
+	t.mu.Lock()
+	t.routes = routes
+	t.mu.Unlock()
+}
+
+// Lookup returns the mapped port for a request's Host on an original port
+func (t *Table) Lookup(host string, port int) (int, bool) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	mapped, ok := t.routes[host][port]
+	return mapped, ok
+}
+
+// Hosts returns the routed hostnames serving port, sorted
+func (t *Table) Hosts(port int) []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var hosts []string
+	for host, ports := range t.routes {
+		if _, ok := ports[port]; ok {
+			hosts = append(hosts, host)
+		}
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// Ports returns every original port in the table, sorted
+func (t *Table) Ports() []int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	seen := make(map[int]bool)
+	var ports []int
+	for _, routes := range t.routes {
+		for orig := range routes {
+			if !seen[orig] {
+				seen[orig] = true
+				ports = append(ports, orig)
+			}
+		}
+	}
+	sort.Ints(ports)
+	return ports
+}
+
+type targetKey struct{}
+
+// NewHandler returns an HTTP handler for the listener on original port
+// that forwards each request to the mapped port of the environment named
+// by its Host. WebSocket upgrades and streamed responses pass through.
+func NewHandler(table *Table, port int) http.Handler {
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(pr.In.Context().Value(targetKey{}).(*url.URL))
+			pr.SetXForwarded()
+			// Apps see the routed hostname, like they would without denv
+			pr.Out.Host = pr.In.Host
+		},
+		// Flush immediately so server-sent events and other streams work
+		FlushInterval: -1,
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mapped, ok := table.Lookup(r.Host, port)
+		if !ok {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, "denv router: no environment for %s on port %d\n", r.Host, port)
+			if hosts := table.Hosts(port); len(hosts) > 0 {
+				fmt.Fprintln(w, "\nAvailable:")
+				for _, host := range hosts {
+					fmt.Fprintf(w, "  http://%s:%d\n", host, port)
+				}
+			}
+			return
+		}
+
+		target := &url.URL{Scheme: "http", Host: "127.0.0.1:" + strconv.Itoa(mapped)}
+		proxy.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), targetKey{}, target)))
+	})
+}
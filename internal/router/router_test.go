@@ -0,0 +1,152 @@
+package router
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func backendPort(t *testing.T, srv *httptest.Server) int {
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(u.Port())
+	require.NoError(t, err)
+	return port
+}
+
+func get(t *testing.T, routerURL, host string) (int, string) {
+	req, err := http.NewRequest(http.MethodGet, routerURL+"/path", nil)
+	require.NoError(t, err)
+	req.Host = host
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestHostname(t *testing.T) {
+	assert.Equal(t, "feature.myapp.localhost", Hostname("MyApp", "feature"))
+}
+
+func TestRoutesByHost(t *testing.T) {
+	newBackend := func(name string) *httptest.Server {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "%s %s %s", name, r.Host, r.URL.Path)
+		}))
+		t.Cleanup(srv.Close)
+		return srv
+	}
+	main := newBackend("main")
+	feature := newBackend("feature")
+
+	table := NewTable()
+	table.Set(Routes{
+		"main.myapp.localhost":    {3000: backendPort(t, main)},
+		"feature.myapp.localhost": {3000: backendPort(t, feature)},
+	})
+	router := httptest.NewServer(NewHandler(table, 3000))
+	defer router.Close()
+
+	status, body := get(t, router.URL, "feature.myapp.localhost:3000")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "feature feature.myapp.localhost:3000 /path", body)
+
+	status, body = get(t, router.URL, "MAIN.myapp.localhost")
+	assert.Equal(t, http.StatusOK, status)
+	assert.True(t, strings.HasPrefix(body, "main "))
+
+	// Test: Unknown hosts list what is available
+	status, body = get(t, router.URL, "nope.myapp.localhost:3000")
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Contains(t, body, "http://feature.myapp.localhost:3000")
+
+	// Test: Refreshing the table drops removed environments
+	table.Set(Routes{"main.myapp.localhost": {3000: backendPort(t, main)}})
+	status, _ = get(t, router.URL, "feature.myapp.localhost:3000")
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.Equal(t, []int{3000}, table.Ports())
+}
+
+func TestStreaming(t *testing.T) {
+	release := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: first\n\n")
+		w.(http.Flusher).Flush()
+		<-release
+		fmt.Fprint(w, "data: second\n\n")
+	}))
+	defer backend.Close()
+	defer close(release)
+
+	table := NewTable()
+	table.Set(Routes{"dev.app.localhost": {8080: backendPort(t, backend)}})
+	router := httptest.NewServer(NewHandler(table, 8080))
+	defer router.Close()
+
+	req, err := http.NewRequest(http.MethodGet, router.URL, nil)
+	require.NoError(t, err)
+	req.Host = "dev.app.localhost"
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	// Test: The first event arrives before the response ends
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "data: first\n", line)
+}
+
+func TestWebSocketUpgrade(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "websocket" {
+			http.Error(w, "upgrade required", http.StatusBadRequest)
+			return
+		}
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fmt.Fprint(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		rw.Flush()
+		// Echo one line over the upgraded connection
+		line, _ := rw.ReadString('\n')
+		fmt.Fprint(rw, "echo "+line)
+		rw.Flush()
+	}))
+	defer backend.Close()
+
+	table := NewTable()
+	table.Set(Routes{"dev.app.localhost": {8080: backendPort(t, backend)}})
+	router := httptest.NewServer(NewHandler(table, 8080))
+	defer router.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(router.URL, "http://"))
+	require.NoError(t, err)
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	fmt.Fprint(conn, "GET /ws HTTP/1.1\r\nHost: dev.app.localhost:8080\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+	fmt.Fprint(conn, "hello\n")
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "echo hello\n", line)
+}
@@ -0,0 +1,30 @@
+package shell
+
+import "strings"
+
+// DirenvScript returns the direnv library defining use_denv, which loads an
+// environment in .envrc with `use denv [name]`. denvPath is the denv binary
+// to call.
+func DirenvScript(denvPath string) string {
+	return strings.ReplaceAll(direnvrc, "@DENV@", "\""+escapeShellValue(denvPath)+"\"")
+}
+
+const direnvrc = `# use denv [--no-ports] [name]
+# Loads a denv environment (default: "default") into .envrc, reloading when
+# its ports, the denv configuration or the repository's .denv.yaml change.
+use_denv() {
+  local exports
+  exports="$(@DENV@ export "$@")" || return
+  eval "$exports"
+
+  local home="${DENV_HOME:-$HOME/.denv}"
+  watch_file "$home/config.yaml" "$home/trust.json"
+  if [[ -n "${DENV_ENV:-}" ]]; then
+    watch_file "$DENV_ENV/runtime.json"
+  fi
+  local repo_config
+  if repo_config="$(find_up .denv.yaml)"; then
+    watch_file "$repo_config"
+  fi
+}
+`
@@ -0,0 +1,39 @@
+package shell
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirenvScript_UseDenv(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+
+	fake := filepath.Join(t.TempDir(), "denv")
+	require.NoError(t, os.WriteFile(fake, []byte("#!/bin/sh\necho \"export DENV_HOME=/h DENV_ENV=/h/app-$2 ARGS='$*'\"\n"), 0755))
+
+	// Stand-ins for direnv's stdlib
+	stdlib := `watch_file() { echo "watch $*"; }
+find_up() { echo /repo/.denv.yaml; }
+`
+
+	// Test: The environment is loaded and its files are watched
+	script := stdlib + DirenvScript(fake) + `use_denv staging; echo "$ARGS"`
+	out, err := exec.Command("bash", "--norc", "-c", script).CombinedOutput()
+	require.NoError(t, err, string(out))
+	assert.Equal(t, "watch /h/config.yaml /h/trust.json\n"+
+		"watch /h/app-staging/runtime.json\n"+
+		"watch /repo/.denv.yaml\n"+
+		"export staging\n", string(out))
+
+	// Test: A failing export fails use_denv
+	require.NoError(t, os.WriteFile(fake, []byte("#!/bin/sh\nexit 1\n"), 0755))
+	script = stdlib + DirenvScript(fake) + `use_denv`
+	assert.Error(t, exec.Command("bash", "--norc", "-c", script).Run())
+}
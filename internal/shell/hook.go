@@ -0,0 +1,165 @@
+package shell
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseShellName returns the shell type for a name like "zsh"
+func ParseShellName(name string) (ShellType, error) {
+	switch name {
+	case "bash":
+		return Bash, nil
+	case "zsh":
+		return Zsh, nil
+	case "fish":
+		return Fish, nil
+	case "nu", "nushell":
+		return Nu, nil
+	case "pwsh", "powershell":
+		return Pwsh, nil
+	case "xonsh":
+		return Xonsh, nil
+	}
+	return Bash, fmt.Errorf("unsupported shell '%s' (supported: bash, zsh, fish, nu, pwsh, xonsh)", name)
+}
+
+// ExportCommand returns the command setting an exported variable
+func ExportCommand(shellType ShellType, key, value string) string {
+	switch shellType {
+	case Fish:
+		return fmt.Sprintf("set -gx %s \"%s\";", key, escapeFishValue(value))
+	case Nu:
+		return fmt.Sprintf("$env.%s = %s", key, quoteNu(value))
+	case Pwsh:
+		return fmt.Sprintf("$env:%s = %s", key, quotePwsh(value))
+	case Xonsh:
+		return fmt.Sprintf("$%s = %s", key, quoteXonsh(value))
+	}
+	return fmt.Sprintf("export %s=\"%s\";", key, escapeShellValue(value))
+}
+
+// UnsetCommand returns the command removing a variable
+func UnsetCommand(shellType ShellType, key string) string {
+	switch shellType {
+	case Fish:
+		return fmt.Sprintf("set -e %s;", key)
+	case Nu:
+		return fmt.Sprintf("hide-env -i %s", key)
+	case Pwsh:
+		return fmt.Sprintf("Remove-Item -ErrorAction SilentlyContinue Env:%s", key)
+	case Xonsh:
+		return fmt.Sprintf("${...}.pop('%s', None)", key)
+	}
+	return fmt.Sprintf("unset %s;", key)
+}
+
+// HookScript returns the code that makes a shell call `denv hook-env`
+// before every prompt and when the directory changes, and release its
+// session when it exits. It also wraps denv so that switch, push and pop
+// change the shell itself. denvPath is the denv binary to call.
+func HookScript(shellType ShellType, denvPath string) (string, error) {
+	switch shellType {
+	case Bash:
+		return strings.ReplaceAll(bashHook, "@DENV@", "\""+escapeShellValue(denvPath)+"\""), nil
+	case Zsh:
+		return strings.ReplaceAll(zshHook, "@DENV@", "\""+escapeShellValue(denvPath)+"\""), nil
+	case Fish:
+		return strings.ReplaceAll(fishHook, "@DENV@", "\""+escapeFishValue(denvPath)+"\""), nil
+	}
+	return "", fmt.Errorf("no shell hook for %s (supported: bash, zsh, fish)", shellType)
+}
+
+const bashHook = `denv() {
+  case "${1:-}" in
+    switch|push|pop)
+      local commands ret
+      commands="$(@DENV@ "$1" --shell bash --pid $$ "${@:2}")"
+      ret=$?
+      eval "$commands"
+      return $ret
+      ;;
+    *)
+      @DENV@ "$@"
+      ;;
+  esac
+}
+_denv_hook() {
+  local previous_exit_status=$?
+  eval "$(@DENV@ hook-env bash $$)"
+  return $previous_exit_status
+}
+_denv_exit() {
+  local exit_status=$?
+  if [[ -n "${DENV_ACTIVATION:-}" ]]; then
+    @DENV@ hook-exit $$ >/dev/null 2>&1
+  fi
+  return $exit_status
+}
+if [[ ";${PROMPT_COMMAND[*]:-};" != *";_denv_hook;"* ]]; then
+  if [[ "$(declare -p PROMPT_COMMAND 2>&1)" == "declare -a"* ]]; then
+    PROMPT_COMMAND=(_denv_hook "${PROMPT_COMMAND[@]}")
+  else
+    PROMPT_COMMAND="_denv_hook${PROMPT_COMMAND:+;$PROMPT_COMMAND}"
+  fi
+fi
+if [[ "$(trap -p EXIT)" != *_denv_exit* ]]; then
+  # Run before an existing EXIT trap rather than replacing it
+  eval "_denv_exit_trap=($(trap -p EXIT))"
+  trap "_denv_exit${_denv_exit_trap[2]:+; ${_denv_exit_trap[2]}}" EXIT
+  unset _denv_exit_trap
+fi
+`
+
+const zshHook = `denv() {
+  case "${1:-}" in
+    switch|push|pop)
+      local commands ret
+      commands="$(@DENV@ "$1" --shell zsh --pid $$ "${@:2}")"
+      ret=$?
+      eval "$commands"
+      return $ret
+      ;;
+    *)
+      @DENV@ "$@"
+      ;;
+  esac
+}
+_denv_hook() {
+  eval "$(@DENV@ hook-env zsh $$)"
+}
+_denv_exit() {
+  if [[ -n "${DENV_ACTIVATION:-}" ]]; then
+    @DENV@ hook-exit $$ >/dev/null 2>&1
+  fi
+}
+typeset -ag precmd_functions chpwd_functions zshexit_functions
+if (( ! ${precmd_functions[(I)_denv_hook]} )); then
+  precmd_functions=(_denv_hook $precmd_functions)
+fi
+if (( ! ${chpwd_functions[(I)_denv_hook]} )); then
+  chpwd_functions=(_denv_hook $chpwd_functions)
+fi
+if (( ! ${zshexit_functions[(I)_denv_exit]} )); then
+  zshexit_functions+=(_denv_exit)
+fi
+`
+
+const fishHook = `function denv --wraps @DENV@
+    switch "$argv[1]"
+        case switch push pop
+            @DENV@ $argv[1] --shell fish --pid $fish_pid $argv[2..-1] | source
+            return $pipestatus[1]
+        case '*'
+            @DENV@ $argv
+    end
+end
+function __denv_hook --on-event fish_prompt --on-variable PWD
+    @DENV@ hook-env fish $fish_pid | source
+end
+function __denv_exit --on-event fish_exit
+    if set -q DENV_ACTIVATION
+        @DENV@ hook-exit $fish_pid >/dev/null 2>&1
+    end
+end
+`
@@ -0,0 +1,141 @@
+package shell
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseShellName(t *testing.T) {
+	st, err := ParseShellName("zsh")
+	require.NoError(t, err)
+	assert.Equal(t, Zsh, st)
+
+	st, err = ParseShellName("pwsh")
+	require.NoError(t, err)
+	assert.Equal(t, Pwsh, st)
+
+	_, err = ParseShellName("tcsh")
+	assert.Error(t, err)
+}
+
+func TestExportCommand(t *testing.T) {
+	assert.Equal(t, `export PORT="33000";`, ExportCommand(Bash, "PORT", "33000"))
+	assert.Equal(t, `set -gx PORT "33000";`, ExportCommand(Fish, "PORT", "33000"))
+	assert.Equal(t, `unset PORT;`, UnsetCommand(Zsh, "PORT"))
+	assert.Equal(t, `set -e PORT;`, UnsetCommand(Fish, "PORT"))
+}
+
+func TestExportCommand_RoundTripsInBash(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+
+	values := []string{
+		"plain",
+		"with spaces",
+		`double"quote`,
+		"single'quote",
+		"$HOME and `id` and $(id)",
+		`back\slash`,
+		"multi\nline",
+		"!bang",
+	}
+	for _, value := range values {
+		script := ExportCommand(Bash, "DENV_TEST_VALUE", value) + ` printf %s "$DENV_TEST_VALUE"`
+		out, err := exec.Command("bash", "-c", script).Output()
+		require.NoError(t, err)
+		assert.Equal(t, value, string(out))
+	}
+}
+
+func hookScript(t *testing.T, shellType ShellType, denvPath string) string {
+	script, err := HookScript(shellType, denvPath)
+	require.NoError(t, err)
+	return script
+}
+
+func TestHookScript(t *testing.T) {
+	bash := hookScript(t, Bash, "/usr/local/bin/denv")
+	assert.Contains(t, bash, `eval "$("/usr/local/bin/denv" hook-env bash $$)"`)
+	assert.Contains(t, bash, "PROMPT_COMMAND")
+	assert.Contains(t, bash, `"/usr/local/bin/denv" hook-exit $$`)
+	assert.Contains(t, bash, `"/usr/local/bin/denv" "$1" --shell bash --pid $$ "${@:2}"`)
+
+	zsh := hookScript(t, Zsh, "/usr/local/bin/denv")
+	assert.Contains(t, zsh, "chpwd_functions")
+	assert.Contains(t, zsh, "zshexit_functions")
+
+	fish := hookScript(t, Fish, "/usr/local/bin/denv")
+	assert.Contains(t, fish, `"/usr/local/bin/denv" hook-env fish $fish_pid | source`)
+	assert.Contains(t, fish, "--on-event fish_exit")
+	assert.Contains(t, fish, `"/usr/local/bin/denv" hook-exit $fish_pid`)
+	assert.Contains(t, fish, "--shell fish --pid $fish_pid")
+	assert.False(t, strings.Contains(fish, "@DENV@"))
+
+	// Test: Shells without a hook are refused
+	_, err := HookScript(Nu, "/usr/local/bin/denv")
+	assert.Error(t, err)
+}
+
+func TestHookScript_ParsesInBash(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+
+	// Test: Installing the hook twice registers it once
+	script := hookScript(t, Bash, "/bin/true") + hookScript(t, Bash, "/bin/true") + `echo "$PROMPT_COMMAND"`
+	out, err := exec.Command("bash", "--norc", "-c", script).CombinedOutput()
+	require.NoError(t, err, string(out))
+	assert.Equal(t, "_denv_hook\n", string(out))
+}
+
+func TestHookScript_ChainsExitTrapInBash(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+
+	dir := t.TempDir()
+	log := filepath.Join(dir, "log")
+	fake := filepath.Join(dir, "denv")
+	require.NoError(t, os.WriteFile(fake, []byte("#!/bin/sh\necho \"$1\" >> '"+log+"'\n"), 0755))
+
+	// Test: An existing EXIT trap still runs, after the sessions are released
+	script := `trap 'echo "user'\''s trap"' EXIT;` + hookScript(t, Bash, fake) + hookScript(t, Bash, fake) +
+		`DENV_ACTIVATION=x; exit 3`
+	cmd := exec.Command("bash", "--norc", "-c", script)
+	out, err := cmd.CombinedOutput()
+	assert.Equal(t, "user's trap\n", string(out))
+	var exitErr *exec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, 3, exitErr.ExitCode())
+
+	data, err := os.ReadFile(log)
+	require.NoError(t, err)
+	assert.Equal(t, "hook-exit\n", string(data))
+}
+
+func TestHookScript_EvaluatesSwitchInBash(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+
+	fake := filepath.Join(t.TempDir(), "denv")
+	require.NoError(t, os.WriteFile(fake, []byte("#!/bin/sh\necho \"export DENV_ARGS='$*';\"\nexit 3\n"), 0755))
+
+	// Test: switch output is evaluated in the shell, keeping the exit status
+	script := hookScript(t, Bash, fake) + `denv switch staging; echo "$? $DENV_ARGS"`
+	out, err := exec.Command("bash", "--norc", "-c", script).CombinedOutput()
+	require.NoError(t, err, string(out))
+	assert.Regexp(t, `^3 switch --shell bash --pid \d+ staging\n$`, string(out))
+
+	// Test: other commands run as usual
+	script = hookScript(t, Bash, fake) + `denv list; echo "$?"`
+	out, _ = exec.Command("bash", "--norc", "-c", script).CombinedOutput()
+	assert.Equal(t, "export DENV_ARGS='list';\n3\n", string(out))
+}
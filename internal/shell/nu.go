@@ -0,0 +1,78 @@
+package shell
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+func generateNuWrapper(env map[string]string) string {
+	var script strings.Builder
+
+	// Export environment variables (Nushell syntax)
+	script.WriteString("# Set environment variables\n")
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		script.WriteString(ExportCommand(Nu, key, env[key]) + "\n")
+	}
+	script.WriteString("\n")
+
+	// Nushell has no exit event and only sources files known when parsing,
+	// so in-shell hooks are not supported; denv still runs hooks/on-exit
+	// and removes the session lock once the shell exits
+
+	// Modify prompt with color
+	script.WriteString("# Modify prompt with color\n")
+	envName := env["DENV_ENV_NAME"]
+	if envName == "" {
+		envName = "denv"
+	}
+	script.WriteString(GenerateColoredPrompt(envName, Nu))
+	script.WriteString("\n")
+
+	return script.String()
+}
+
+// generateNuColoredPrompt wraps PROMPT_COMMAND, which may be a string or a
+// closure, so the user's prompt follows the environment name
+func generateNuColoredPrompt(envName, color string) string {
+	code := strings.TrimPrefix(color, "\033[")
+	return fmt.Sprintf(`let denv_previous_prompt = ($env.PROMPT_COMMAND? | default "")
+$env.PROMPT_COMMAND = {||
+    let previous = if ($denv_previous_prompt | describe | str starts-with "closure") { do $denv_previous_prompt } else { $denv_previous_prompt }
+    [(ansi --escape %s) %s (ansi reset) " " $previous] | str join
+}`, quoteNu(code), quoteNu("("+envName+")"))
+}
+
+// quoteNu returns value as a Nushell double-quoted string, which has
+// backslash escapes but no interpolation
+func quoteNu(value string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range value {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '"':
+			b.WriteString(`\"`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < ' ' || r == 0x7f {
+				fmt.Fprintf(&b, `\u{%x}`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
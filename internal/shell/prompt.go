@@ -33,6 +33,12 @@ func GenerateColoredPrompt(envName string, shellType ShellType) string {
 		return generateFishColoredPrompt(envName, color)
 	case Zsh:
 		return generateZshColoredPrompt(envName, color)
+	case Nu:
+		return generateNuColoredPrompt(envName, color)
+	case Pwsh:
+		return generatePwshColoredPrompt(envName, color)
+	case Xonsh:
+		return generateXonshColoredPrompt(envName, color)
 	default:
 		// Bash and Sh use ANSI color syntax with PS1
 		return generateBashStylePrompt(envName, color)
@@ -61,6 +67,12 @@ func GetColorForEnvironment(envName string) string {
 	return darkModeColors[index]
 }
 
+// GetColorIndex returns the 256-colour index of an environment's colour, for
+// prompts that take a colour number rather than an escape sequence
+func GetColorIndex(envName string) string {
+	return mapAnsiToZshColor(GetColorForEnvironment(envName))
+}
+
 func generateBashStylePrompt(envName, color string) string {
 	// Format: colored (envName) followed by original PS1
 	return fmt.Sprintf(`PS1="%s(%s)%s $PS1"`, color, envName, colorReset)
@@ -129,3 +129,9 @@ func TestColorConsistencyForEnvironment(t *testing.T) {
 	_ = differentColor // We won't assert they're different due to possible collision
 }
 
+
+func TestGetColorIndex(t *testing.T) {
+	// Test: The index matches the escape sequence used for the prompt
+	envName := "staging"
+	assert.Equal(t, "\033[38;5;"+GetColorIndex(envName)+"m", GetColorForEnvironment(envName))
+}
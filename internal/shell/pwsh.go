@@ -0,0 +1,76 @@
+package shell
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+func generatePwshWrapper(env map[string]string) string {
+	var script strings.Builder
+
+	// Export environment variables (PowerShell syntax)
+	script.WriteString("# Set environment variables\n")
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		script.WriteString(ExportCommand(Pwsh, key, env[key]) + "\n")
+	}
+	script.WriteString("\n")
+
+	// Define cleanup, run when the shell exits
+	script.WriteString("# Cleanup function\n")
+	script.WriteString("$null = Register-EngineEvent -SourceIdentifier PowerShell.Exiting -Action {\n")
+	script.WriteString("    # Run exit hook if exists\n")
+	script.WriteString("    $hook = Join-Path $env:DENV_PROJECT 'hooks/on-exit.ps1'\n")
+	script.WriteString("    if (Test-Path $hook) { . $hook }\n")
+	script.WriteString("    # Remove session lock\n")
+	script.WriteString("    Remove-Item -Force -ErrorAction SilentlyContinue (Join-Path $env:DENV_ENV \"sessions/$($env:DENV_SESSION).lock\")\n")
+	script.WriteString("}\n\n")
+
+	// Run enter hook
+	script.WriteString("# Run enter hook if exists\n")
+	script.WriteString("$__denvHook = Join-Path $env:DENV_PROJECT 'hooks/on-enter.ps1'\n")
+	script.WriteString("if (Test-Path $__denvHook) { . $__denvHook }\n")
+	script.WriteString("Remove-Variable __denvHook\n\n")
+
+	// Modify prompt with color
+	script.WriteString("# Modify prompt with color\n")
+	envName := env["DENV_ENV_NAME"]
+	if envName == "" {
+		envName = "denv"
+	}
+	script.WriteString(GenerateColoredPrompt(envName, Pwsh))
+	script.WriteString("\n")
+
+	return script.String()
+}
+
+// generatePwshColoredPrompt wraps the prompt function left by the user's
+// profile
+func generatePwshColoredPrompt(envName, color string) string {
+	code := strings.TrimPrefix(color, "\033[")
+	return fmt.Sprintf("$global:__denvPrompt = $function:prompt\n"+
+		"function global:prompt { \"`e[%s\" + %s + \"`e[0m \" + (& $global:__denvPrompt) }",
+		code, quotePwsh("("+envName+")"))
+}
+
+// quotePwsh returns value as a PowerShell single-quoted string, where only
+// quotes are special; PowerShell also takes typographic single quotes as
+// quotes, so those are doubled too
+func quotePwsh(value string) string {
+	var b strings.Builder
+	b.WriteByte('\'')
+	for _, r := range value {
+		switch r {
+		case '\'', '‘', '’', '‚', '‛':
+			b.WriteRune(r)
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
@@ -0,0 +1,96 @@
+package shell
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// quotingCases are values that must survive ExportCommand unchanged
+var quotingCases = []struct {
+	name  string
+	value string
+}{
+	{"plain", "33000"},
+	{"spaces", "with spaces"},
+	{"double quote", `say "hi"`},
+	{"single quote", "it's"},
+	{"typographic quotes", "it’s ‘quoted’"},
+	{"expansions", "$HOME $(id) `id` ${PATH}"},
+	{"backslashes", `C:\Users\dev\`},
+	{"newline and tab", "multi\nline\tvalue"},
+	{"braces and percent", "{name} %PATH% #not-a-comment;"},
+	{"unicode", "ünïcødé ✓"},
+	{"empty", ""},
+}
+
+func TestExportCommand_Golden(t *testing.T) {
+	for _, shellType := range []ShellType{Bash, Fish, Nu, Pwsh, Xonsh} {
+		t.Run(shellType.String(), func(t *testing.T) {
+			var got strings.Builder
+			for _, c := range quotingCases {
+				fmt.Fprintf(&got, "# %s\n%s\n", c.name, ExportCommand(shellType, "VALUE", c.value))
+			}
+			fmt.Fprintf(&got, "# unset\n%s\n", UnsetCommand(shellType, "VALUE"))
+
+			golden := filepath.Join("testdata", "export."+shellType.String()+".golden")
+			if *update {
+				require.NoError(t, os.WriteFile(golden, []byte(got.String()), 0644))
+			}
+			want, err := os.ReadFile(golden)
+			require.NoError(t, err)
+			assert.Equal(t, string(want), got.String())
+		})
+	}
+}
+
+// TestExportCommand_RoundTrips runs the assignments in every installed shell
+func TestExportCommand_RoundTrips(t *testing.T) {
+	shells := []struct {
+		shellType ShellType
+		command   func(assign string) []string
+	}{
+		{Zsh, func(assign string) []string {
+			return []string{"zsh", "-f", "-c", assign + ` printf %s "$VALUE"`}
+		}},
+		{Fish, func(assign string) []string {
+			return []string{"fish", "--no-config", "-c", assign + ` printf %s "$VALUE"`}
+		}},
+		{Nu, func(assign string) []string {
+			return []string{"nu", "--no-config-file", "-c", assign + "; print --no-newline $env.VALUE"}
+		}},
+		{Pwsh, func(assign string) []string {
+			return []string{"pwsh", "-NoProfile", "-Command", assign + "; [Console]::Out.Write($env:VALUE)"}
+		}},
+		{Xonsh, func(assign string) []string {
+			return []string{"xonsh", "--no-rc", "-c", assign + "\nimport sys\nsys.stdout.write($VALUE)"}
+		}},
+	}
+
+	for _, s := range shells {
+		t.Run(s.shellType.String(), func(t *testing.T) {
+			if _, err := exec.LookPath(s.shellType.String()); err != nil {
+				t.Skipf("%s not installed", s.shellType)
+			}
+			for _, c := range quotingCases {
+				if c.value == "" && s.shellType == Pwsh {
+					// Assigning '' removes the variable in PowerShell
+					continue
+				}
+				argv := s.command(ExportCommand(s.shellType, "VALUE", c.value))
+				out, err := exec.Command(argv[0], argv[1:]...).Output()
+				require.NoError(t, err, c.name)
+				assert.Equal(t, c.value, string(out), c.name)
+			}
+		})
+	}
+}
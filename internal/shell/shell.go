@@ -13,6 +13,9 @@ const (
 	Zsh
 	Fish
 	Sh
+	Nu
+	Pwsh
+	Xonsh
 )
 
 func (s ShellType) String() string {
@@ -25,18 +28,25 @@ func (s ShellType) String() string {
 		return "fish"
 	case Sh:
 		return "sh"
+	case Nu:
+		return "nu"
+	case Pwsh:
+		return "pwsh"
+	case Xonsh:
+		return "xonsh"
 	default:
 		return "unknown"
 	}
 }
 
-// DetectShell detects the shell type from the shell path
+// DetectShell detects the shell type from the shell path. Unknown shells
+// are treated as bash but keep their own name.
 func DetectShell(shellPath string) (ShellType, string) {
 	if shellPath == "" {
 		return Bash, "bash" // Default to bash
 	}
 
-	base := filepath.Base(shellPath)
+	base := strings.TrimSuffix(filepath.Base(shellPath), ".exe")
 
 	switch {
 	case strings.Contains(base, "zsh"):
@@ -47,33 +57,14 @@ func DetectShell(shellPath string) (ShellType, string) {
 		return Bash, "bash"
 	case base == "sh":
 		return Sh, "sh"
+	case base == "nu" || base == "nushell":
+		return Nu, "nu"
+	case base == "pwsh" || base == "powershell":
+		return Pwsh, "pwsh"
+	case strings.Contains(base, "xonsh"):
+		return Xonsh, "xonsh"
 	default:
-		return Bash, "bash" // Default to bash for unknown shells
-	}
-}
-
-// GetShellCommand returns the command and arguments to start a shell with the environment
-func GetShellCommand(shellType ShellType, envScript string) []string {
-	switch shellType {
-	case Bash:
-		// Bash supports --init-file to source a script on startup
-		return []string{"bash", "--init-file", envScript}
-
-	case Zsh:
-		// Zsh doesn't have --init-file, so we source and exec
-		return []string{"zsh", "-c", fmt.Sprintf("source %s && exec zsh", envScript)}
-
-	case Fish:
-		// Fish uses different syntax for sourcing
-		return []string{"fish", "-c", fmt.Sprintf("source %s; and exec fish", envScript)}
-
-	case Sh:
-		// Plain sh uses . instead of source
-		return []string{"sh", "-c", fmt.Sprintf(". %s && exec sh", envScript)}
-
-	default:
-		// Default to bash behavior
-		return []string{"bash", "--init-file", envScript}
+		return Bash, base // Default to bash for unknown shells
 	}
 }
 
@@ -82,6 +73,12 @@ func GenerateShellWrapper(shellType ShellType, env map[string]string) string {
 	switch shellType {
 	case Fish:
 		return generateFishWrapper(env)
+	case Nu:
+		return generateNuWrapper(env)
+	case Pwsh:
+		return generatePwshWrapper(env)
+	case Xonsh:
+		return generateXonshWrapper(env)
 	default:
 		// Bash, Zsh, and Sh use similar syntax
 		return GenerateWrapper(env)
@@ -101,9 +98,9 @@ func generateFishWrapper(env map[string]string) string {
 	}
 	script.WriteString("\n")
 
-	// Define cleanup function (Fish syntax)
+	// Define cleanup function, run when the shell exits (Fish has no trap)
 	script.WriteString("# Cleanup function\n")
-	script.WriteString("function cleanup\n")
+	script.WriteString("function cleanup --on-event fish_exit\n")
 	script.WriteString("    # Run exit hook if exists\n")
 	script.WriteString("    if test -f \"$DENV_PROJECT/hooks/on-exit.sh\"\n")
 	script.WriteString("        source \"$DENV_PROJECT/hooks/on-exit.sh\"\n")
@@ -112,12 +109,6 @@ func generateFishWrapper(env map[string]string) string {
 	script.WriteString("    rm -f \"$DENV_ENV/sessions/$DENV_SESSION.lock\"\n")
 	script.WriteString("end\n\n")
 
-	// Setup signal handlers (Fish syntax)
-	script.WriteString("# Setup signal handlers\n")
-	script.WriteString("trap cleanup EXIT\n")
-	script.WriteString("trap cleanup SIGINT\n")
-	script.WriteString("trap cleanup SIGTERM\n\n")
-
 	// Run enter hook
 	script.WriteString("# Run enter hook if exists\n")
 	script.WriteString("if test -f \"$DENV_PROJECT/hooks/on-enter.sh\"\n")
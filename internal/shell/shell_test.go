@@ -18,6 +18,11 @@ func TestDetectShell(t *testing.T) {
 		{"/usr/bin/zsh", Zsh, "zsh"},
 		{"/usr/local/bin/fish", Fish, "fish"},
 		{"/bin/sh", Sh, "sh"},
+		{"/usr/bin/nu", Nu, "nu"},
+		{"/opt/microsoft/powershell/7/pwsh", Pwsh, "pwsh"},
+		{"/usr/bin/pwsh.exe", Pwsh, "pwsh"},
+		{"/usr/local/bin/xonsh", Xonsh, "xonsh"},
+		{"/bin/tcsh", Bash, "tcsh"}, // Bash, keeping the name
 		{"", Bash, "bash"}, // Default to bash
 	}
 
@@ -30,39 +35,6 @@ func TestDetectShell(t *testing.T) {
 	}
 }
 
-func TestShellCommand(t *testing.T) {
-	envScript := "/tmp/test-env.sh"
-
-	tests := []struct {
-		shellType ShellType
-		expected  []string
-	}{
-		{
-			Bash,
-			[]string{"bash", "--init-file", envScript},
-		},
-		{
-			Zsh,
-			[]string{"zsh", "-c", "source /tmp/test-env.sh && exec zsh"},
-		},
-		{
-			Fish,
-			[]string{"fish", "-c", "source /tmp/test-env.sh; and exec fish"},
-		},
-		{
-			Sh,
-			[]string{"sh", "-c", ". /tmp/test-env.sh && exec sh"},
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.shellType.String(), func(t *testing.T) {
-			args := GetShellCommand(tt.shellType, envScript)
-			assert.Equal(t, tt.expected, args)
-		})
-	}
-}
-
 func TestGenerateShellWrapper(t *testing.T) {
 	env := map[string]string{
 		"TEST_VAR":  "value",
@@ -83,7 +55,19 @@ func TestGenerateShellWrapper(t *testing.T) {
 		},
 		{
 			Fish,
-			[]string{"set -x TEST_VAR", "function cleanup"},
+			[]string{"set -x TEST_VAR", "function cleanup --on-event fish_exit"},
+		},
+		{
+			Nu,
+			[]string{`$env.TEST_VAR = "value"`, "$env.PROMPT_COMMAND = {||"},
+		},
+		{
+			Pwsh,
+			[]string{"$env:TEST_VAR = 'value'", "PowerShell.Exiting", "on-enter.ps1", "function global:prompt"},
+		},
+		{
+			Xonsh,
+			[]string{`$TEST_VAR = "value"`, "@events.on_exit", "on-enter.xsh", "$PROMPT = lambda"},
 		},
 	}
 
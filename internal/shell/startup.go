@@ -0,0 +1,122 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ScriptExtension returns the file extension the shell expects for a
+// wrapper script; PowerShell only sources .ps1 files
+func ScriptExtension(shellType ShellType) string {
+	switch shellType {
+	case Fish:
+		return ".fish"
+	case Nu:
+		return ".nu"
+	case Pwsh:
+		return ".ps1"
+	case Xonsh:
+		return ".xsh"
+	default:
+		return ".sh"
+	}
+}
+
+// StartupCommand writes the files shellType needs to load the wrapper
+// script envScript when it starts interactively into dir, and returns the
+// command starting it along with variables to add to its environment. The
+// user's own startup files are loaded first so the wrapper's prompt and
+// traps are the last word. dir must outlive the shell.
+func StartupCommand(shellType ShellType, envScript, dir string) ([]string, []string, error) {
+	switch shellType {
+	case Zsh:
+		// zsh reads its startup files from ZDOTDIR; ours load the user's
+		// from where they would have been read, then the wrapper
+		zshenv := fmt.Sprintf(zshenvStartup, "\""+escapeShellValue(dir)+"\"")
+		zshrc := fmt.Sprintf(zshrcStartup, "\""+escapeShellValue(envScript)+"\"")
+		if err := os.WriteFile(filepath.Join(dir, ".zshenv"), []byte(zshenv), 0644); err != nil {
+			return nil, nil, err
+		}
+		if err := os.WriteFile(filepath.Join(dir, ".zshrc"), []byte(zshrc), 0644); err != nil {
+			return nil, nil, err
+		}
+		env := []string{"ZDOTDIR=" + dir}
+		if userDir := os.Getenv("ZDOTDIR"); userDir != "" {
+			env = append(env, "DENV_USER_ZDOTDIR="+userDir)
+		}
+		return []string{"zsh", "-i"}, env, nil
+
+	case Fish:
+		// --init-command runs after config.fish, before the first prompt
+		return []string{"fish", "-i", "--init-command", fmt.Sprintf("source \"%s\"", escapeFishValue(envScript))}, nil, nil
+
+	case Nu:
+		// -e runs commands before the REPL, after config.nu and env.nu
+		return []string{"nu", "-e", "source " + quoteNu(envScript)}, nil, nil
+
+	case Pwsh:
+		// Profiles load before -Command; -NoExit keeps the shell open
+		return []string{"pwsh", "-NoExit", "-Command", ". " + quotePwsh(envScript)}, nil, nil
+
+	case Xonsh:
+		// XONSHRC lists the run control files; ours comes after the user's
+		rcFiles := os.Getenv("XONSHRC")
+		if rcFiles == "" {
+			home, _ := os.UserHomeDir()
+			rcFiles = strings.Join([]string{
+				"/etc/xonsh/xonshrc",
+				filepath.Join(home, ".config", "xonsh", "rc.xsh"),
+				filepath.Join(home, ".xonshrc"),
+			}, string(os.PathListSeparator))
+		}
+		return []string{"xonsh", "-i"}, []string{"XONSHRC=" + rcFiles + string(os.PathListSeparator) + envScript}, nil
+
+	case Sh:
+		// Plain sh uses . instead of source
+		return []string{"sh", "-c", fmt.Sprintf(". %s && exec sh", envScript)}, nil, nil
+
+	default:
+		// --init-file replaces ~/.bashrc, so ours loads it first
+		bashrc := fmt.Sprintf(bashrcStartup, "\""+escapeShellValue(envScript)+"\"")
+		path := filepath.Join(dir, "bashrc")
+		if err := os.WriteFile(path, []byte(bashrc), 0644); err != nil {
+			return nil, nil, err
+		}
+		return []string{"bash", "--init-file", path, "-i"}, nil, nil
+	}
+}
+
+const bashrcStartup = `if [ -f ~/.bashrc ]; then
+  source ~/.bashrc
+fi
+source %s
+`
+
+// zshenvStartup points ZDOTDIR back at the user's directory to load their
+// .zshenv, which may move it, then back at ours so zsh reads our .zshrc
+const zshenvStartup = `if [[ -n "${DENV_USER_ZDOTDIR:-}" ]]; then
+  export ZDOTDIR="$DENV_USER_ZDOTDIR"
+else
+  unset ZDOTDIR
+fi
+unset DENV_USER_ZDOTDIR
+if [[ -f "${ZDOTDIR:-$HOME}/.zshenv" ]]; then
+  source "${ZDOTDIR:-$HOME}/.zshenv"
+fi
+_denv_user_zdotdir="${ZDOTDIR:-}"
+ZDOTDIR=%s
+`
+
+const zshrcStartup = `if [[ -n "$_denv_user_zdotdir" ]]; then
+  export ZDOTDIR="$_denv_user_zdotdir"
+else
+  unset ZDOTDIR
+fi
+unset _denv_user_zdotdir
+if [[ -f "${ZDOTDIR:-$HOME}/.zshrc" ]]; then
+  source "${ZDOTDIR:-$HOME}/.zshrc"
+fi
+source %s
+`
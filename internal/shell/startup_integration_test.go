@@ -0,0 +1,115 @@
+package shell
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startShell runs an interactive shell entered with the wrapper for env,
+// feeding it script on stdin, and returns its output
+func startShell(t *testing.T, shellType ShellType, home string, env map[string]string, script string) string {
+	if _, err := exec.LookPath(shellType.String()); err != nil {
+		t.Skipf("%s not installed", shellType)
+	}
+
+	dir := t.TempDir()
+	wrapperPath := filepath.Join(dir, "wrapper.sh")
+	require.NoError(t, os.WriteFile(wrapperPath, []byte(GenerateShellWrapper(shellType, env)), 0644))
+	args, shellEnv, err := StartupCommand(shellType, wrapperPath, dir)
+	require.NoError(t, err)
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Env = append(os.Environ(), "HOME="+home, "XDG_CONFIG_HOME="+filepath.Join(home, ".config"))
+	cmd.Env = append(cmd.Env, shellEnv...)
+	cmd.Stdin = strings.NewReader(script)
+	out, err := cmd.Output()
+	require.NoError(t, err, string(out))
+	return string(out)
+}
+
+// wrapperEnv returns the variables of an environment whose on-exit hook
+// creates a file, and the path of that file
+func wrapperEnv(t *testing.T, shellPath string) (map[string]string, string) {
+	project := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(project, "hooks"), 0755))
+	exited := filepath.Join(t.TempDir(), "exited")
+	require.NoError(t, os.WriteFile(filepath.Join(project, "hooks", "on-exit.sh"), []byte("touch \""+exited+"\"\n"), 0644))
+
+	return map[string]string{
+		"DENV_ENV_NAME":     "staging",
+		"DENV_PROJECT_NAME": "myproject",
+		"DENV_ENV":          t.TempDir(),
+		"DENV_PROJECT":      project,
+		"DENV_SESSION":      "test-session",
+		"SHELL":             shellPath,
+	}, exited
+}
+
+func TestStartup_Bash(t *testing.T) {
+	home := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".bashrc"), []byte("export RC_MARKER=loaded\nPS1='rc> '\n"), 0644))
+	env, exited := wrapperEnv(t, "/bin/bash")
+
+	out := startShell(t, Bash, home, env, "echo \"rc=$RC_MARKER env=$DENV_ENV_NAME\"\necho \"prompt=$PS1\"\nexit\n")
+
+	// Test: ~/.bashrc and the wrapper are both loaded, the prompt last
+	assert.Contains(t, out, "rc=loaded env=staging")
+	assert.Contains(t, out, "(staging)\033[0m rc> ")
+
+	// Test: The exit trap ran
+	assert.FileExists(t, exited)
+}
+
+func TestStartup_Zsh(t *testing.T) {
+	home := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".zshenv"), []byte("export ENV_MARKER=loaded\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".zshrc"), []byte("export RC_MARKER=loaded\nPROMPT='rc> '\n"), 0644))
+	env, exited := wrapperEnv(t, "/bin/zsh")
+	t.Setenv("ZDOTDIR", "")
+
+	out := startShell(t, Zsh, home, env, "echo \"env=$ENV_MARKER rc=$RC_MARKER name=$DENV_ENV_NAME zdotdir=${ZDOTDIR:-unset}\"\nprint -r -- \"prompt=$PROMPT\"\nexit\n")
+
+	// Test: The user's files are loaded and ZDOTDIR is theirs again
+	assert.Contains(t, out, "env=loaded rc=loaded name=staging zdotdir=unset")
+
+	// Test: The prompt change survives .zshrc and the exit trap runs
+	assert.Contains(t, out, "(staging)%f rc> ")
+	assert.FileExists(t, exited)
+}
+
+func TestStartup_ZshWithZdotdir(t *testing.T) {
+	home := t.TempDir()
+	zdotdir := filepath.Join(home, ".config", "zsh")
+	require.NoError(t, os.MkdirAll(zdotdir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(zdotdir, ".zshrc"), []byte("export RC_MARKER=loaded\n"), 0644))
+	env, _ := wrapperEnv(t, "/bin/zsh")
+	t.Setenv("ZDOTDIR", zdotdir)
+
+	out := startShell(t, Zsh, home, env, "echo \"rc=$RC_MARKER zdotdir=$ZDOTDIR\"\nexit\n")
+
+	// Test: .zshrc is read from the user's ZDOTDIR, which is restored
+	assert.Contains(t, out, "rc=loaded zdotdir="+zdotdir)
+}
+
+func TestStartup_Fish(t *testing.T) {
+	home := t.TempDir()
+	configDir := filepath.Join(home, ".config", "fish")
+	require.NoError(t, os.MkdirAll(configDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(configDir, "config.fish"), []byte("set -gx RC_MARKER loaded\n"), 0644))
+	env, exited := wrapperEnv(t, "/usr/bin/fish")
+
+	out := startShell(t, Fish, home, env, "echo \"rc=$RC_MARKER env=$DENV_ENV_NAME\"\nfunctions fish_prompt | string match -q '*(staging)*'; and echo prompt=set\nexit\n")
+
+	// Test: config.fish and the wrapper are both loaded
+	assert.Contains(t, out, "rc=loaded env=staging")
+	assert.Contains(t, out, "prompt=set")
+
+	// Test: The fish_exit handler ran the exit hook
+	assert.FileExists(t, exited)
+}
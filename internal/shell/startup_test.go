@@ -0,0 +1,79 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartupCommand(t *testing.T) {
+	envScript := "/tmp/test-env.sh"
+
+	t.Run("bash", func(t *testing.T) {
+		dir := t.TempDir()
+		args, env, err := StartupCommand(Bash, envScript, dir)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"bash", "--init-file", filepath.Join(dir, "bashrc"), "-i"}, args)
+		assert.Empty(t, env)
+
+		// Test: The init file loads ~/.bashrc before the wrapper
+		data, err := os.ReadFile(filepath.Join(dir, "bashrc"))
+		require.NoError(t, err)
+		assert.Regexp(t, `(?s)source ~/\.bashrc.*source "/tmp/test-env\.sh"`, string(data))
+	})
+
+	t.Run("zsh", func(t *testing.T) {
+		dir := t.TempDir()
+		t.Setenv("ZDOTDIR", "/home/user/.config/zsh")
+		args, env, err := StartupCommand(Zsh, envScript, dir)
+		require.NoError(t, err)
+
+		// Test: No exec, so the wrapper's traps and prompt survive
+		assert.Equal(t, []string{"zsh", "-i"}, args)
+		assert.Equal(t, []string{"ZDOTDIR=" + dir, "DENV_USER_ZDOTDIR=/home/user/.config/zsh"}, env)
+
+		zshenv, err := os.ReadFile(filepath.Join(dir, ".zshenv"))
+		require.NoError(t, err)
+		assert.Contains(t, string(zshenv), `source "${ZDOTDIR:-$HOME}/.zshenv"`)
+		assert.Contains(t, string(zshenv), `ZDOTDIR="`+dir+`"`)
+		zshrc, err := os.ReadFile(filepath.Join(dir, ".zshrc"))
+		require.NoError(t, err)
+		assert.Regexp(t, `(?s)source "\$\{ZDOTDIR:-\$HOME\}/\.zshrc".*source "/tmp/test-env\.sh"`, string(zshrc))
+	})
+
+	t.Run("fish", func(t *testing.T) {
+		args, env, err := StartupCommand(Fish, envScript, t.TempDir())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"fish", "-i", "--init-command", `source "/tmp/test-env.sh"`}, args)
+		assert.Empty(t, env)
+	})
+
+	t.Run("nu", func(t *testing.T) {
+		args, _, err := StartupCommand(Nu, "/tmp/test-env.nu", t.TempDir())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"nu", "-e", `source "/tmp/test-env.nu"`}, args)
+	})
+
+	t.Run("pwsh", func(t *testing.T) {
+		args, _, err := StartupCommand(Pwsh, "/tmp/test-env.ps1", t.TempDir())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"pwsh", "-NoExit", "-Command", ". '/tmp/test-env.ps1'"}, args)
+	})
+
+	t.Run("xonsh", func(t *testing.T) {
+		t.Setenv("XONSHRC", "/home/user/.xonshrc")
+		args, env, err := StartupCommand(Xonsh, "/tmp/test-env.xsh", t.TempDir())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"xonsh", "-i"}, args)
+		assert.Equal(t, []string{"XONSHRC=/home/user/.xonshrc" + string(os.PathListSeparator) + "/tmp/test-env.xsh"}, env)
+	})
+
+	t.Run("sh", func(t *testing.T) {
+		args, _, err := StartupCommand(Sh, envScript, t.TempDir())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"sh", "-c", ". /tmp/test-env.sh && exec sh"}, args)
+	})
+}
@@ -9,9 +9,8 @@ import (
 func GenerateWrapper(env map[string]string) string {
 	var script strings.Builder
 
-	// Add shebang and set options
-	script.WriteString("#!/bin/bash\n")
-	script.WriteString("set -e\n\n")
+	// Add shebang; no set -e, the script is sourced by the interactive shell
+	script.WriteString("#!/bin/bash\n\n")
 
 	// Export environment variables
 	script.WriteString("# Set environment variables\n")
@@ -0,0 +1,94 @@
+package shell
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func generateXonshWrapper(env map[string]string) string {
+	var script strings.Builder
+
+	// Export environment variables (xonsh syntax)
+	script.WriteString("# Set environment variables\n")
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		script.WriteString(ExportCommand(Xonsh, key, env[key]) + "\n")
+	}
+	script.WriteString("\n")
+
+	// Define cleanup, run when the shell exits
+	script.WriteString("# Cleanup function\n")
+	script.WriteString("@events.on_exit\n")
+	script.WriteString("def __denv_cleanup(**kwargs):\n")
+	script.WriteString("    import os\n")
+	script.WriteString("    # Run exit hook if exists\n")
+	script.WriteString("    hook = os.path.join($DENV_PROJECT, 'hooks', 'on-exit.xsh')\n")
+	script.WriteString("    if os.path.isfile(hook):\n")
+	script.WriteString("        source @(hook)\n")
+	script.WriteString("    # Remove session lock\n")
+	script.WriteString("    try:\n")
+	script.WriteString("        os.remove(os.path.join($DENV_ENV, 'sessions', $DENV_SESSION + '.lock'))\n")
+	script.WriteString("    except OSError:\n")
+	script.WriteString("        pass\n\n")
+
+	// Run enter hook
+	script.WriteString("# Run enter hook if exists\n")
+	script.WriteString("import os.path as __denv_path\n")
+	script.WriteString("if __denv_path.isfile(__denv_path.join($DENV_PROJECT, 'hooks', 'on-enter.xsh')):\n")
+	script.WriteString("    source @(__denv_path.join($DENV_PROJECT, 'hooks', 'on-enter.xsh'))\n\n")
+
+	// Modify prompt with color
+	script.WriteString("# Modify prompt with color\n")
+	envName := env["DENV_ENV_NAME"]
+	if envName == "" {
+		envName = "denv"
+	}
+	script.WriteString(GenerateColoredPrompt(envName, Xonsh))
+	script.WriteString("\n")
+
+	return script.String()
+}
+
+// generateXonshColoredPrompt wraps $PROMPT, which may be a format string or
+// a function
+func generateXonshColoredPrompt(envName, color string) string {
+	name := strings.NewReplacer("{", "{{", "}", "}}").Replace(envName)
+	prefix := fmt.Sprintf("{%s}(%s){RESET} ", mapAnsiToXonshColor(color), name)
+	return fmt.Sprintf("__denv_prompt = $PROMPT\n"+
+		"$PROMPT = lambda: %s + (__denv_prompt() if callable(__denv_prompt) else __denv_prompt)",
+		quoteXonsh(prefix))
+}
+
+// mapAnsiToXonshColor maps ANSI color codes to xonsh color names
+func mapAnsiToXonshColor(ansiColor string) string {
+	colorMap := map[string]string{
+		"\033[38;5;39m":  "BOLD_BLUE",
+		"\033[38;5;46m":  "BOLD_GREEN",
+		"\033[38;5;208m": "INTENSE_RED", // orange-ish
+		"\033[38;5;99m":  "PURPLE",
+		"\033[38;5;87m":  "BOLD_CYAN",
+		"\033[38;5;226m": "BOLD_YELLOW",
+		"\033[38;5;201m": "INTENSE_PURPLE",
+		"\033[38;5;51m":  "INTENSE_CYAN",
+		"\033[38;5;118m": "INTENSE_GREEN",
+		"\033[38;5;214m": "INTENSE_YELLOW",
+	}
+
+	if xonshColor, ok := colorMap[ansiColor]; ok {
+		return xonshColor
+	}
+	// Default to bright blue if color not found
+	return "BOLD_BLUE"
+}
+
+// quoteXonsh returns value as a Python string literal; xonsh doesn't expand
+// variables in Python mode strings. Go's escapes are a subset of Python's.
+func quoteXonsh(value string) string {
+	return strconv.Quote(value)
+}
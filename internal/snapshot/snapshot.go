@@ -0,0 +1,270 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// metadataName is the first entry of every archive; environment files
+// follow under envPrefix
+const (
+	metadataName = "snapshot.json"
+	envPrefix    = "env/"
+)
+
+// Metadata describes where a snapshot came from
+type Metadata struct {
+	Name        string    `json:"name"`
+	Project     string    `json:"project"`
+	Environment string    `json:"environment"`
+	EnvPath     string    `json:"env_path"`
+	DenvVersion string    `json:"denv_version"`
+	Created     time.Time `json:"created"`
+}
+
+// Create writes a gzip-compressed tar archive of envPath to archivePath.
+// Session locks and service pids are never included.
+func Create(archivePath, envPath string, meta Metadata) error {
+	if err := os.MkdirAll(filepath.Dir(archivePath), 0755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file so a failed snapshot never leaves a
+	// truncated archive behind
+	tmpPath := archivePath + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	if err := writeArchive(f, envPath, meta); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Rename(tmpPath, archivePath)
+}
+
+func writeArchive(w io.Writer, envPath string, meta Metadata) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = tw.WriteHeader(&tar.Header{
+		Name:    metadataName,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: meta.Created,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return err
+	}
+
+	err = filepath.Walk(envPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(envPath, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "sessions" || rel == "services" {
+			// Session locks and service pids describe live processes
+			return filepath.SkipDir
+		}
+
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		} else if !info.IsDir() && !info.Mode().IsRegular() {
+			// Sockets and pipes can't be archived
+			return nil
+		}
+
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = envPrefix + rel
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		if info.Mode().IsRegular() {
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(tw, f)
+			f.Close()
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// ReadMetadata reads the metadata of a snapshot without extracting it
+func ReadMetadata(archivePath string) (*Metadata, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("not a snapshot archive: %w", err)
+	}
+	defer gz.Close()
+
+	return readMetadata(tar.NewReader(gz))
+}
+
+func readMetadata(tr *tar.Reader) (*Metadata, error) {
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != metadataName {
+		return nil, fmt.Errorf("not a snapshot archive: missing %s", metadataName)
+	}
+
+	var meta Metadata
+	if err := json.NewDecoder(tr).Decode(&meta); err != nil {
+		return nil, fmt.Errorf("invalid snapshot metadata: %w", err)
+	}
+	return &meta, nil
+}
+
+// Extract unpacks the environment files of a snapshot into dest, which must
+// not exist yet, and returns the snapshot metadata
+func Extract(archivePath, dest string) (*Metadata, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("not a snapshot archive: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	meta, err := readMetadata(tr)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Mkdir(dest, 0755); err != nil {
+		return nil, err
+	}
+
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		target, err := entryPath(dest, hdr.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(target, os.FileMode(hdr.Mode).Perm())
+		case tar.TypeSymlink:
+			err = os.Symlink(hdr.Linkname, target)
+		case tar.TypeReg:
+			err = extractFile(tr, target, os.FileMode(hdr.Mode).Perm())
+		default:
+			// Ignore entry types we never write
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return meta, nil
+}
+
+// entryPath maps an archive entry name to a path inside dest, rejecting
+// entries that would escape it
+func entryPath(dest, name string) (string, error) {
+	if !strings.HasPrefix(name, envPrefix) {
+		return "", fmt.Errorf("unexpected entry in snapshot: %s", name)
+	}
+
+	rel := filepath.FromSlash(strings.TrimPrefix(name, envPrefix))
+	if rel == "" || filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("unsafe path in snapshot: %s", name)
+	}
+	target := filepath.Join(dest, rel)
+	if !strings.HasPrefix(target, dest+string(filepath.Separator)) {
+		return "", fmt.Errorf("unsafe path in snapshot: %s", name)
+	}
+
+	// Refuse to write through a symlink extracted earlier
+	for dir := filepath.Dir(target); dir != dest; dir = filepath.Dir(dir) {
+		if info, err := os.Lstat(dir); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("unsafe path in snapshot: %s", name)
+		}
+	}
+
+	return target, nil
+}
+
+func extractFile(r io.Reader, target string, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_EXCL, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,88 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateAndExtract(t *testing.T) {
+	envPath := filepath.Join(t.TempDir(), "myapp-default")
+	require.NoError(t, os.MkdirAll(filepath.Join(envPath, "data"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(envPath, "sessions"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(envPath, "runtime.json"), []byte(`{"project":"myapp"}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(envPath, "data", "db.sqlite"), []byte("rows"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(envPath, "sessions", "abc.lock"), nil, 0644))
+	require.NoError(t, os.Symlink("data/db.sqlite", filepath.Join(envPath, "current.db")))
+
+	archive := filepath.Join(t.TempDir(), "snaps", "first.tar.gz")
+	meta := Metadata{
+		Name:        "first",
+		Project:     "myapp",
+		Environment: "default",
+		EnvPath:     envPath,
+		DenvVersion: "1.2.3",
+		Created:     time.Now().Truncate(time.Second),
+	}
+	require.NoError(t, Create(archive, envPath, meta))
+
+	// Test: Metadata can be read without extracting
+	got, err := ReadMetadata(archive)
+	require.NoError(t, err)
+	assert.Equal(t, "myapp", got.Project)
+	assert.Equal(t, "1.2.3", got.DenvVersion)
+
+	// Test: Extract restores files, modes and symlinks but not sessions
+	dest := filepath.Join(t.TempDir(), "restored")
+	_, err = Extract(archive, dest)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(dest, "data", "db.sqlite"))
+	require.NoError(t, err)
+	assert.Equal(t, "rows", string(data))
+
+	info, err := os.Stat(filepath.Join(dest, "data", "db.sqlite"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	link, err := os.Readlink(filepath.Join(dest, "current.db"))
+	require.NoError(t, err)
+	assert.Equal(t, "data/db.sqlite", link)
+
+	assert.NoDirExists(t, filepath.Join(dest, "sessions"))
+}
+
+func TestExtractRejectsUnsafePaths(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "evil.tar.gz")
+	f, err := os.Create(archive)
+	require.NoError(t, err)
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	data, _ := json.Marshal(Metadata{Name: "evil"})
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: metadataName, Mode: 0644, Size: int64(len(data))}))
+	_, _ = tw.Write(data)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "env/../../escape", Mode: 0644, Typeflag: tar.TypeReg}))
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	require.NoError(t, f.Close())
+
+	_, err = Extract(archive, filepath.Join(t.TempDir(), "dest"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unsafe path")
+}
+
+func TestReadMetadataRejectsOtherArchives(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plain.tar.gz")
+	require.NoError(t, os.WriteFile(path, []byte("not gzip"), 0644))
+
+	_, err := ReadMetadata(path)
+	assert.Error(t, err)
+}
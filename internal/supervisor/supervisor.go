@@ -0,0 +1,147 @@
+package supervisor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/caoer/denv/internal/process"
+)
+
+// Restart policies
+const (
+	RestartNo        = "no"
+	RestartOnFailure = "on-failure"
+	RestartAlways    = "always"
+)
+
+// Restart backoff starts at initialBackoff and doubles up to maxBackoff. A
+// run longer than stableRun resets it.
+var (
+	initialBackoff = time.Second
+	maxBackoff     = 30 * time.Second
+	stableRun      = 10 * time.Second
+	stopGrace      = 10 * time.Second
+)
+
+// Spec describes a supervised service
+type Spec struct {
+	Name    string   `json:"name"`
+	Command string   `json:"command"`
+	Env     []string `json:"env"`
+	Dir     string   `json:"dir"`
+	Restart string   `json:"restart"`
+	LogPath string   `json:"log_path"`
+	PidPath string   `json:"pid_path"`
+	// Autostart marks services started with the environment's first
+	// session, which are stopped again with its last
+	Autostart bool `json:"autostart,omitempty"`
+}
+
+// WriteSpec saves spec as JSON at path
+func WriteSpec(path string, spec Spec) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(spec, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// ReadSpec loads a spec written by WriteSpec
+func ReadSpec(path string) (Spec, error) {
+	var spec Spec
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return spec, err
+	}
+	err = json.Unmarshal(data, &spec)
+	return spec, err
+}
+
+// Run runs the service, restarting it according to its policy, until it
+// exits for good or stop is signalled. Output goes to the spec's log file.
+func Run(spec Spec, stop <-chan os.Signal) error {
+	if err := os.MkdirAll(filepath.Dir(spec.LogPath), 0755); err != nil {
+		return err
+	}
+	logFile, err := os.OpenFile(spec.LogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+
+	if spec.PidPath != "" {
+		defer os.Remove(spec.PidPath)
+	}
+
+	backoff := initialBackoff
+	for {
+		started := time.Now()
+		cmd := process.Command(spec.Command)
+		cmd.Env = spec.Env
+		cmd.Dir = spec.Dir
+		cmd.Stdout = logFile
+		cmd.Stderr = logFile
+		process.NewGroup(cmd)
+
+		if err := cmd.Start(); err != nil {
+			logf(logFile, "failed to start %s: %v", spec.Name, err)
+			return err
+		}
+		logf(logFile, "started %s (pid %d)", spec.Name, cmd.Process.Pid)
+
+		done := make(chan error, 1)
+		go func() { done <- cmd.Wait() }()
+
+		var exitErr error
+		select {
+		case <-stop:
+			logf(logFile, "stopping %s", spec.Name)
+			_ = process.Stop(cmd.Process.Pid, stopGrace)
+			<-done
+			logf(logFile, "stopped %s", spec.Name)
+			return nil
+		case exitErr = <-done:
+		}
+
+		if exitErr != nil {
+			logf(logFile, "%s exited: %v", spec.Name, exitErr)
+		} else {
+			logf(logFile, "%s exited", spec.Name)
+		}
+
+		switch spec.Restart {
+		case RestartAlways:
+		case RestartOnFailure:
+			if exitErr == nil {
+				return nil
+			}
+		default:
+			return exitErr
+		}
+
+		if time.Since(started) > stableRun {
+			backoff = initialBackoff
+		}
+		logf(logFile, "restarting %s in %s", spec.Name, backoff)
+		select {
+		case <-stop:
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+func logf(w io.Writer, format string, args ...interface{}) {
+	fmt.Fprintf(w, "[denv %s] %s\n", time.Now().Format("15:04:05"), fmt.Sprintf(format, args...))
+}
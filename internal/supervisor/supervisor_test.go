@@ -0,0 +1,101 @@
+//go:build !windows
+
+package supervisor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func countLines(t *testing.T, path string) int {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0
+	}
+	require.NoError(t, err)
+	return strings.Count(string(data), "\n")
+}
+
+func TestRunRestartsOnFailure(t *testing.T) {
+	initialBackoff = 10 * time.Millisecond
+	defer func() { initialBackoff = time.Second }()
+
+	dir := t.TempDir()
+	runs := filepath.Join(dir, "runs")
+	spec := Spec{
+		Name: "flaky",
+		// Fails twice, then succeeds
+		Command: `echo run >> "` + runs + `"; [ $(wc -l < "` + runs + `") -ge 3 ]`,
+		Restart: RestartOnFailure,
+		LogPath: filepath.Join(dir, "logs", "flaky.log"),
+	}
+
+	require.NoError(t, Run(spec, make(chan os.Signal)))
+	assert.Equal(t, 3, countLines(t, runs))
+
+	log, err := os.ReadFile(spec.LogPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(log), "restarting flaky")
+}
+
+func TestRunNoRestart(t *testing.T) {
+	dir := t.TempDir()
+	runs := filepath.Join(dir, "runs")
+	spec := Spec{
+		Name:    "once",
+		Command: `echo run >> "` + runs + `"; exit 1`,
+		LogPath: filepath.Join(dir, "once.log"),
+	}
+
+	assert.Error(t, Run(spec, make(chan os.Signal)))
+	assert.Equal(t, 1, countLines(t, runs))
+}
+
+func TestRunStops(t *testing.T) {
+	dir := t.TempDir()
+	pidPath := filepath.Join(dir, "svc.pid")
+	require.NoError(t, os.WriteFile(pidPath, []byte("1"), 0644))
+	spec := Spec{
+		Name:    "server",
+		Command: "echo listening; sleep 30",
+		Restart: RestartAlways,
+		LogPath: filepath.Join(dir, "server.log"),
+		PidPath: pidPath,
+	}
+
+	stop := make(chan os.Signal, 1)
+	result := make(chan error, 1)
+	go func() { result <- Run(spec, stop) }()
+
+	time.Sleep(200 * time.Millisecond)
+	stop <- os.Interrupt
+
+	select {
+	case err := <-result:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("supervisor did not stop")
+	}
+
+	log, err := os.ReadFile(spec.LogPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(log), "listening")
+	assert.Contains(t, string(log), "stopped server")
+	assert.NoFileExists(t, pidPath)
+}
+
+func TestSpecRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "svc.json")
+	spec := Spec{Name: "db", Command: "postgres", Env: []string{"A=1"}, Restart: RestartAlways}
+	require.NoError(t, WriteSpec(path, spec))
+
+	loaded, err := ReadSpec(path)
+	require.NoError(t, err)
+	assert.Equal(t, spec, loaded)
+}
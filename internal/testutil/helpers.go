@@ -1,7 +1,9 @@
 package testutil
 
 import (
+	"os"
 	"os/exec"
+	"path/filepath"
 	"testing"
 )
 
@@ -12,4 +14,36 @@ func RunCmd(t *testing.T, dir string, command string, args ...string) {
 	if err != nil {
 		t.Fatalf("Command failed: %s %v\nOutput: %s\nError: %v", command, args, output, err)
 	}
-}
\ No newline at end of file
+}
+
+// SetupProject creates a git repository called name with a
+// github.com/user/<name> origin, changes into it and points DENV_HOME at an
+// empty directory, all undone when the test ends. It returns DENV_HOME and
+// the repository.
+func SetupProject(t *testing.T, name string) (string, string) {
+	t.Helper()
+	denvHome := t.TempDir()
+	project := filepath.Join(t.TempDir(), name)
+	if err := os.MkdirAll(project, 0755); err != nil {
+		t.Fatalf("Failed to create project: %v", err)
+	}
+
+	RunCmd(t, project, "git", "init")
+	RunCmd(t, project, "git", "remote", "add", "origin", "https://github.com/user/"+name+".git")
+
+	Chdir(t, project)
+	t.Setenv("DENV_HOME", denvHome)
+	t.Setenv("DENV_TEST_MODE", "1")
+	return denvHome, project
+}
+
+// Chdir changes the working directory until the test ends
+func Chdir(t *testing.T, dir string) {
+	t.Helper()
+	if cwd, err := os.Getwd(); err == nil {
+		t.Cleanup(func() { _ = os.Chdir(cwd) })
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+}
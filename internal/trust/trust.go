@@ -0,0 +1,109 @@
+package trust
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// Status describes whether a file's current content has been approved
+type Status int
+
+const (
+	// NotAllowed means the file was never approved (or was denied)
+	NotAllowed Status = iota
+	// Allowed means the file's current content was approved
+	Allowed
+	// Changed means the file was approved but has changed since
+	Changed
+)
+
+// Store records approved files by absolute path and content hash
+type Store struct {
+	path  string
+	Files map[string]string `json:"files"`
+}
+
+// Load reads the store at path; a missing store is empty
+func Load(path string) (*Store, error) {
+	s := &Store{path: path, Files: make(map[string]string)}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+	if s.Files == nil {
+		s.Files = make(map[string]string)
+	}
+	return s, nil
+}
+
+// Check reports whether data is the approved content of file
+func (s *Store) Check(file string, data []byte) Status {
+	hash, ok := s.Files[key(file)]
+	if !ok {
+		return NotAllowed
+	}
+	if hash != Hash(data) {
+		return Changed
+	}
+	return Allowed
+}
+
+// Allow approves the current content of file
+func (s *Store) Allow(file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	s.Files[key(file)] = Hash(data)
+	return s.save()
+}
+
+// Deny revokes any approval of file
+func (s *Store) Deny(file string) error {
+	delete(s.Files, key(file))
+	return s.save()
+}
+
+func (s *Store) save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, s.path)
+}
+
+// Hash returns the hex-encoded SHA-256 of data
+func Hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// key normalizes file to an absolute path with symlinks resolved, so the
+// same file is recognized however it is reached
+func key(file string) string {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return file
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		return resolved
+	}
+	return abs
+}
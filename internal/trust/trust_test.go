@@ -0,0 +1,53 @@
+package trust
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAllowDeny(t *testing.T) {
+	tmpDir := t.TempDir()
+	storePath := filepath.Join(tmpDir, "home", "trust.json")
+	file := filepath.Join(tmpDir, ".denv.yaml")
+	content := []byte("hooks:\n  on-create: [make setup]\n")
+	require.NoError(t, os.WriteFile(file, content, 0644))
+
+	store, err := Load(storePath)
+	require.NoError(t, err)
+	assert.Equal(t, NotAllowed, store.Check(file, content))
+
+	// Test: Approval persists
+	require.NoError(t, store.Allow(file))
+	store, err = Load(storePath)
+	require.NoError(t, err)
+	assert.Equal(t, Allowed, store.Check(file, content))
+
+	// Test: Changing the content invalidates the approval
+	assert.Equal(t, Changed, store.Check(file, []byte("hooks: {}\n")))
+
+	// Test: Deny revokes
+	require.NoError(t, store.Deny(file))
+	store, err = Load(storePath)
+	require.NoError(t, err)
+	assert.Equal(t, NotAllowed, store.Check(file, content))
+}
+
+func TestApprovalIsPerPath(t *testing.T) {
+	tmpDir := t.TempDir()
+	content := []byte("patterns: []\n")
+	a := filepath.Join(tmpDir, "a.yaml")
+	b := filepath.Join(tmpDir, "b.yaml")
+	require.NoError(t, os.WriteFile(a, content, 0644))
+	require.NoError(t, os.WriteFile(b, content, 0644))
+
+	store, err := Load(filepath.Join(tmpDir, "trust.json"))
+	require.NoError(t, err)
+	require.NoError(t, store.Allow(a))
+
+	assert.Equal(t, Allowed, store.Check(a, content))
+	assert.Equal(t, NotAllowed, store.Check(b, content))
+}
@@ -3,6 +3,7 @@ package ui
 import (
 	"fmt"
 	"hash/fnv"
+	"sort"
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
@@ -243,8 +244,23 @@ func RenderEnvironmentList(title string, environments map[string][]EnvInfo) stri
 			continue
 		}
 		
+		// The project's own environments come first, then each sub-project's
+		sort.SliceStable(envs, func(i, j int) bool {
+			return envs[i].Subproject < envs[j].Subproject
+		})
+
+		indent := "   "
+		subproject := ""
 		for _, env := range envs {
-			output.WriteString("   • ")
+			if env.Subproject != subproject {
+				subproject = env.Subproject
+				indent = "      "
+				output.WriteString("   ")
+				output.WriteString(projectStyle.Render("📁 " + subproject))
+				output.WriteString("\n")
+			}
+
+			output.WriteString(indent + "• ")
 			if env.Active {
 				output.WriteString(envStyle.Render(env.Name))
 				output.WriteString(": ")
@@ -276,4 +292,7 @@ type EnvInfo struct {
 	Active   bool
 	Sessions int
 	Ports    int
+	// Subproject is the path of the monorepo sub-project the environment
+	// belongs to, if any
+	Subproject string
 }
\ No newline at end of file
@@ -88,4 +88,22 @@ func TestPortColorConsistency(t *testing.T) {
 	// We can't guarantee they're different due to hash collisions, 
 	// but we can verify the function returns something
 	assert.NotEmpty(t, port3)
-}
\ No newline at end of file
+}
+
+func TestEnvironmentListGroupsSubprojects(t *testing.T) {
+	result := RenderEnvironmentList("Environments", map[string][]EnvInfo{
+		"monorepo": {
+			{Name: "local", Subproject: "services/billing"},
+			{Name: "dev"},
+			{Name: "local", Subproject: "services/api"},
+		},
+	})
+
+	// The project's own environments come first, then each sub-project's
+	dev := strings.Index(result, "dev")
+	api := strings.Index(result, "services/api")
+	billing := strings.Index(result, "services/billing")
+	assert.True(t, dev >= 0 && dev < api && api < billing, result)
+	assert.Equal(t, 1, strings.Count(result, "📦"))
+	assert.Contains(t, result, "      • local")
+}